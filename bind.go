@@ -0,0 +1,172 @@
+package cookoo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Bind copies values from a Getter into the tagged fields of a struct.
+//
+// Each field to be bound must have a `cookoo` tag naming its key:
+//
+// 	type Request struct {
+// 		Name    string `cookoo:"name"`
+// 		Retries int    `cookoo:"retries"`
+// 		Limit   *int   `cookoo:"limit"`
+// 	}
+//
+// 	req := &Request{}
+// 	if err := cookoo.Bind(params, req); err != nil {
+// 		return nil, &cookoo.FatalError{Message: err.Error()}
+// 	}
+//
+// A value is bound if its type can be assigned to the field, as with the
+// typed getters: an int will not be converted to an int64. A pointer field
+// is given a newly allocated value if the value can be assigned to the
+// pointed-to type. Fields without a tag, and fields whose keys are not in
+// the source (as reported by Has), are left alone.
+//
+// A tag may be followed by ",required", as in `cookoo:"name,required"`. If
+// any required keys are missing, a *MissingKeysError listing all of them is
+// returned.
+//
+// The target must be a pointer to a struct. If a value is the wrong type for
+// its field, an error naming the field and key is returned. It wraps a
+// *ValueTypeError.
+func Bind(source Getter, target interface{}) error {
+	return bindTagged(source, target, "cookoo", false)
+}
+
+// Bind copies params into the `param` tagged fields of a struct.
+//
+// This works like the Bind function, but it uses the `param` tag, and it
+// converts values to the type of their fields where it can:
+//
+// 	var opts struct {
+// 		Page  int           `param:"page"`
+// 		Wait  time.Duration `param:"wait"`
+// 		Debug bool          `param:"debug"`
+// 		Name  string        `param:"name,required"`
+// 	}
+// 	if err := p.Bind(&opts); err != nil {
+// 		return nil, &cookoo.FatalError{Message: err.Error()}
+// 	}
+//
+// Strings are parsed into numbers, bools, and time.Durations. Numbers are
+// converted to other kinds of numbers, as long as no information is lost.
+// As with GetDuration, an int or int64 is bound to a time.Duration as a
+// number of seconds. Slices are converted element by element.
+func (p *Params) Bind(target interface{}) error {
+	return bindTagged(p, target, "param", true)
+}
+
+// bindTagged does the work of Bind for a given struct tag. If convert is
+// true, values are converted to the type of their fields.
+func bindTagged(source Getter, target interface{}, tag string, convert bool) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cookoo: Bind target must be a pointer to a struct, not %T", target)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	missing := []string{}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		key, ok := field.Tag.Lookup(tag)
+		if !ok || !field.IsExported() {
+			continue
+		}
+		key, opts, _ := strings.Cut(key, ",")
+		val, ok := source.Has(key)
+		if !ok || val == nil {
+			if opts == "required" {
+				missing = append(missing, key)
+			}
+			continue
+		}
+
+		fv := rv.Field(i)
+		vv := reflect.ValueOf(val)
+		switch {
+		case vv.Type().AssignableTo(field.Type):
+			fv.Set(vv)
+		case field.Type.Kind() == reflect.Ptr && vv.Type().AssignableTo(field.Type.Elem()):
+			ptr := reflect.New(field.Type.Elem())
+			ptr.Elem().Set(vv)
+			fv.Set(ptr)
+		default:
+			if convert {
+				if cv, ok := bindValue(val, field.Type); ok {
+					fv.Set(cv)
+					continue
+				}
+			}
+			err := &ValueTypeError{Key: key, Actual: vv.Type(), Expected: field.Type}
+			return fmt.Errorf("cookoo: cannot bind field %s: %w", field.Name, err)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingKeysError{Keys: missing}
+	}
+	return nil
+}
+
+// bindValue converts a value to the type of a field for Params.Bind.
+//
+// Strings are parsed as they are for environment variables, and other
+// values are converted as they are by GetAs.
+func bindValue(v interface{}, t reflect.Type) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		return rv, true
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		ev, ok := bindValue(v, t.Elem())
+		if !ok {
+			return rv, false
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(ev)
+		return ptr, true
+	case reflect.Slice:
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return rv, false
+		}
+		out := reflect.MakeSlice(t, rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			ev, ok := bindValue(rv.Index(i).Interface(), t.Elem())
+			if !ok {
+				return rv, false
+			}
+			out.Index(i).Set(ev)
+		}
+		return out, true
+	}
+
+	if t == durationType {
+		// Numbers are seconds, as they are for GetDuration.
+		switch n := v.(type) {
+		case int:
+			return reflect.ValueOf(time.Duration(n) * time.Second), true
+		case int64:
+			return reflect.ValueOf(time.Duration(n) * time.Second), true
+		case string:
+		default:
+			return rv, false
+		}
+	}
+
+	if str, ok := v.(string); ok && t.Kind() != reflect.String {
+		out := reflect.New(t).Elem()
+		if err := setFromString(out, str); err != nil {
+			return rv, false
+		}
+		return out, true
+	}
+	return convertValue(v, t)
+}
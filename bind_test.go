@@ -0,0 +1,182 @@
+package cookoo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindInner struct {
+	Host string `cookoo:"host"`
+}
+
+type bindTarget struct {
+	Name     string     `cookoo:"name"`
+	Retries  int        `cookoo:"retries"`
+	Big      int64      `cookoo:"big"`
+	Small    int32      `cookoo:"small"`
+	Count    uint64     `cookoo:"count"`
+	Debug    bool       `cookoo:"debug"`
+	Ratio    float64    `cookoo:"ratio"`
+	Limit    *int       `cookoo:"limit"`
+	Inner    *bindInner `cookoo:"inner"`
+	Missing  string     `cookoo:"missing"`
+	Untagged string
+}
+
+func TestBind(t *testing.T) {
+	inner := &bindInner{Host: "example.com"}
+	p := NewParamsWithValues(map[string]interface{}{
+		"name":     "Matt",
+		"retries":  3,
+		"big":      int64(1) << 40,
+		"small":    int32(-7),
+		"count":    uint64(12),
+		"debug":    true,
+		"ratio":    0.5,
+		"limit":    10,
+		"inner":    inner,
+		"Untagged": "nope",
+	})
+
+	target := &bindTarget{Missing: "unchanged"}
+	if err := Bind(p, target); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+
+	if target.Name != "Matt" || target.Retries != 3 || target.Big != 1<<40 || target.Small != -7 {
+		t.Errorf("! Unexpected values: %+v", target)
+	}
+	if target.Count != 12 || !target.Debug || target.Ratio != 0.5 {
+		t.Errorf("! Unexpected values: %+v", target)
+	}
+	if target.Limit == nil || *target.Limit != 10 {
+		t.Errorf("! Expected limit to point to 10, got %v", target.Limit)
+	}
+	if target.Inner != inner {
+		t.Errorf("! Expected the inner pointer to be bound, got %v", target.Inner)
+	}
+	if target.Missing != "unchanged" {
+		t.Errorf("! Expected a missing key to leave the field alone, got %s", target.Missing)
+	}
+	if target.Untagged != "" {
+		t.Error("! Expected untagged fields to be ignored.")
+	}
+}
+
+func TestBindMissing(t *testing.T) {
+	target := &bindTarget{}
+	if err := Bind(NewParams(0), target); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if target.Limit != nil || target.Inner != nil || target.Name != "" {
+		t.Errorf("! Expected zero values, got %+v", target)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{"retries": "three"})
+	err := Bind(p, &bindTarget{})
+	if err == nil {
+		t.Fatal("! Expected a type mismatch to fail.")
+	}
+	if !strings.Contains(err.Error(), "Retries") || !strings.Contains(err.Error(), `"retries"`) {
+		t.Errorf("! Expected the error to name the field and key, got %s", err)
+	}
+	var te *ValueTypeError
+	if !errors.As(err, &te) {
+		t.Errorf("! Expected a ValueTypeError, got %T", err)
+	}
+
+	if err := Bind(p, bindTarget{}); err == nil {
+		t.Error("! Expected a non-pointer target to fail.")
+	}
+}
+
+type paramOpts struct {
+	Page  int           `param:"page"`
+	Wait  time.Duration `param:"wait"`
+	Debug bool          `param:"debug"`
+	Ratio float32       `param:"ratio"`
+	IDs   []int64       `param:"ids"`
+	Limit *uint         `param:"limit"`
+	Name  string        `param:"name,required"`
+	Email string        `param:"email,required"`
+}
+
+func TestParamsBind(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"page":  "2",
+		"wait":  "1m30s",
+		"debug": "true",
+		"ratio": 0.5,
+		"ids":   []interface{}{"1", 2, int64(3)},
+		"limit": 10,
+	})
+	opts := &paramOpts{}
+	err := p.Bind(opts)
+	mk, ok := err.(*MissingKeysError)
+	if !ok || len(mk.Keys) != 2 || mk.Keys[0] != "name" || mk.Keys[1] != "email" {
+		t.Fatalf("! Expected name and email to be missing, got %v", err)
+	}
+	if opts.Page != 2 || opts.Wait != 90*time.Second || !opts.Debug || opts.Ratio != 0.5 {
+		t.Errorf("! Unexpected values: %+v", opts)
+	}
+	if len(opts.IDs) != 3 || opts.IDs[0] != 1 || opts.IDs[2] != 3 {
+		t.Errorf("! Expected the IDs to be converted, got %v", opts.IDs)
+	}
+	if opts.Limit == nil || *opts.Limit != 10 {
+		t.Errorf("! Expected limit to point to 10, got %v", opts.Limit)
+	}
+
+	p = NewParamsWithValues(map[string]interface{}{"wait": 30, "name": "a", "email": "b"})
+	opts = &paramOpts{}
+	if err := p.Bind(opts); err != nil || opts.Wait != 30*time.Second || opts.Wait != GetDuration("wait", 0, p) {
+		t.Errorf("! Expected an int to bind as seconds, got %s: %v", opts.Wait, err)
+	}
+	p = NewParamsWithValues(map[string]interface{}{"wait": 1.5, "name": "a", "email": "b"})
+	if err := p.Bind(&paramOpts{}); err == nil || !strings.Contains(err.Error(), "Wait") {
+		t.Errorf("! Expected a float duration to fail, got %v", err)
+	}
+
+	p = NewParamsWithValues(map[string]interface{}{"page": 2.5, "name": "a", "email": "b"})
+	if err := p.Bind(&paramOpts{}); err == nil || !strings.Contains(err.Error(), "Page") {
+		t.Errorf("! Expected a lossy conversion to fail, got %v", err)
+	}
+	p = NewParamsWithValues(map[string]interface{}{"debug": "maybe", "name": "a", "email": "b"})
+	if err := p.Bind(&paramOpts{}); err == nil || !strings.Contains(err.Error(), "Debug") {
+		t.Errorf("! Expected a bad bool to fail, got %v", err)
+	}
+}
+
+type cxtOpts struct {
+	ID   int    `cxt:"user.ID,required"`
+	Name string `cxt:"user.Name"`
+}
+
+func TestContextBind(t *testing.T) {
+	base := NewContext()
+	base.Put("user.ID", "42")
+	base.Put("user.Name", "Matt")
+	child := base.NewChild()
+	child.Put("user.Name", "Angie")
+
+	for _, cxt := range []Context{base, SyncContext(base), ReadOnlyContext(base), child} {
+		opts := &cxtOpts{}
+		if err := cxt.Bind(opts); err != nil {
+			t.Errorf("! Unexpected error from %T: %s", cxt, err)
+		}
+		if opts.ID != 42 {
+			t.Errorf("! Expected ID 42 from %T, got %d", cxt, opts.ID)
+		}
+	}
+	opts := &cxtOpts{}
+	child.Bind(opts)
+	if opts.Name != "Angie" {
+		t.Errorf("! Expected the child's value, got %s", opts.Name)
+	}
+	if err := NewContext().Bind(opts); err == nil {
+		t.Error("! Expected a missing ID to fail.")
+	}
+}
@@ -0,0 +1,278 @@
+package cookoo
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore stores cached values.
+//
+// LRUCache is an in-memory CacheStore. Other stores, such as one backed by
+// Redis or memcached, can be used with CachingDatasource and CacheCommand by
+// implementing this interface. A store must be safe to use from many
+// goroutines, and should give values back as they were set.
+type CacheStore interface {
+	// Lookup gets a cached value, and whether there was one. Stores treat
+	// errors as misses.
+	Lookup(key string) (interface{}, bool)
+	// Set caches a value. If ttl is greater than zero, the value expires
+	// after that long.
+	Set(key string, value interface{}, ttl time.Duration) error
+	// Delete removes values from the cache.
+	Delete(keys ...string) error
+}
+
+// LRUCache is an in-memory CacheStore that holds a limited number of values.
+//
+// When the cache is full, the least recently used value is evicted to make
+// room for a new one.
+type LRUCache struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// NewLRUCache creates a new LRUCache that holds up to size values.
+//
+// If size is zero or less, the number of values is not limited.
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// Lookup gets a cached value, and whether there was one that has not expired.
+func (c *LRUCache) Lookup(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(e)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return entry.value, true
+}
+
+// Set caches a value, evicting the least recently used value if the cache is
+// full. If ttl is greater than zero, the value expires after that long.
+func (c *LRUCache) Set(key string, value interface{}, ttl time.Duration) error {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(e)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key, value, expires})
+	for c.size > 0 && c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Delete removes values from the cache.
+func (c *LRUCache) Delete(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if e, ok := c.entries[key]; ok {
+			c.remove(e)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of values in the cache, including any that have
+// expired but have not yet been removed.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRUCache) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*lruEntry).key)
+}
+
+// CachingDatasource caches the values of a KeyValueDatasource.
+//
+// It is useful in front of a datasource that is slow to read, such as one
+// backed by a database or a remote service. Values that are found are kept
+// in the Store for TTL. Missing (nil) values are not cached, so they are
+// looked up again each time.
+//
+// Example:
+//
+// 	settings := cookoo.NewCachingDatasource(db, cookoo.NewLRUCache(1000), time.Minute)
+// 	cxt.AddDatasource("settings", settings)
+//
+// 	reg.Route("GET /", "Home page").
+// 		Does(ShowHome, "home").
+// 			Using("motd").From("settings:motd")
+type CachingDatasource struct {
+	// Datasource is the datasource whose values are cached.
+	Datasource KeyValueDatasource
+	// Store holds the cached values.
+	Store CacheStore
+	// TTL is how long values are cached. If it is zero, values do not expire.
+	TTL time.Duration
+	// Prefix is added to the beginning of every key in the store, so several
+	// datasources can share one store.
+	Prefix string
+}
+
+// NewCachingDatasource creates a new CachingDatasource.
+func NewCachingDatasource(ds KeyValueDatasource, store CacheStore, ttl time.Duration) *CachingDatasource {
+	return &CachingDatasource{
+		Datasource: ds,
+		Store:      store,
+		TTL:        ttl,
+	}
+}
+
+// Value gets the value for a key, from the cache if it is there.
+func (d *CachingDatasource) Value(key string) interface{} {
+	if v, ok := d.Store.Lookup(d.Prefix + key); ok {
+		return v
+	}
+	v := d.Datasource.Value(key)
+	if v != nil {
+		d.Store.Set(d.Prefix+key, v, d.TTL)
+	}
+	return v
+}
+
+// Get gets the value for a key, or the default value. See Value.
+func (d *CachingDatasource) Get(key string, defaultVal interface{}) interface{} {
+	return DatasourceGet(d, key, defaultVal)
+}
+
+// Has gets the value for a key, and whether it is set. See Value.
+func (d *CachingDatasource) Has(key string) (interface{}, bool) {
+	return DatasourceHas(d, key)
+}
+
+// Invalidate removes keys from the cache, so that they are read from the
+// datasource the next time they are used.
+func (d *CachingDatasource) Invalidate(keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = d.Prefix + key
+	}
+	return d.Store.Delete(prefixed...)
+}
+
+// CacheCommand wraps a command so that its output is cached in a store.
+//
+// This is like Registry.CacheIn, but it wraps the command itself, so it can
+// be used wherever a Command is. The cache is keyed by the name of the
+// command's function and the values of keyParams. If ttl is zero, results do
+// not expire. Results are only cached when the command returns no interrupt.
+//
+// Since commands that share a store are told apart by function name,
+// closures made by the same function should not share a store.
+//
+// Example:
+//
+// 	users := cookoo.NewLRUCache(500)
+// 	reg.Route("GET /user", "Show a user").
+// 		Does(cookoo.CacheCommand(LoadUser, users, 5*time.Minute, "id"), "user").
+// 			Using("id").From("query:id")
+func CacheCommand(cmd Command, store CacheStore, ttl time.Duration, keyParams ...string) Command {
+	prefix := runtime.FuncForPC(reflect.ValueOf(cmd).Pointer()).Name()
+	return func(cxt Context, params *Params) (interface{}, Interrupt) {
+		key := prefix + "\x00" + paramsKey(params, keyParams)
+		if ret, ok := store.Lookup(key); ok {
+			return ret, nil
+		}
+		ret, irq := cmd(cxt, params)
+		if irq == nil {
+			if err := store.Set(key, ret, ttl); err != nil {
+				cxt.Logf("warn", "Could not cache a result: %s", err)
+			}
+		}
+		return ret, irq
+	}
+}
+
+// paramsKey builds a cache key from the values of the named params.
+func paramsKey(params *Params, names []string) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%#v", params.Get(name, nil))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// defaultCacheSize is how many results Registry.Cache keeps for a command.
+const defaultCacheSize = 1000
+
+// resultCache caches the results of a command in a store, keyed by param
+// values.
+//
+// It is attached to a command spec by Registry.Cache and Registry.CacheIn.
+type resultCache struct {
+	store     CacheStore
+	ttl       time.Duration
+	keyParams []string
+}
+
+func newResultCache(store CacheStore, ttl time.Duration, keyParams []string) *resultCache {
+	return &resultCache{
+		store:     store,
+		ttl:       ttl,
+		keyParams: keyParams,
+	}
+}
+
+// key builds a cache key from the route, the command name, and the values of
+// the key params, so that commands can share a store.
+func (c *resultCache) key(route, name string, params *Params) string {
+	return route + "\x00" + name + "\x00" + paramsKey(params, c.keyParams)
+}
+
+// get returns a cached value if there is one that has not expired.
+func (c *resultCache) get(key string) (interface{}, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	return c.store.Lookup(key)
+}
+
+// set caches a value. Nothing is cached if the ttl is zero or less.
+func (c *resultCache) set(key string, value interface{}) error {
+	if c.ttl <= 0 {
+		return nil
+	}
+	return c.store.Set(key, value, c.ttl)
+}
@@ -0,0 +1,208 @@
+package cookoo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	calls := 0
+	count := func(c Context, p *Params) (interface{}, Interrupt) {
+		calls++
+		return calls, nil
+	}
+
+	reg.Route("test", "Test caching.").
+		Does(count, "count").
+		Using("id").From("cxt:id").
+		Using("ignored").From("cxt:ignored").
+		Cache(time.Minute, "id").
+		Route("expires", "Test expiration.").
+		Does(count, "count").
+		Cache(-time.Second)
+	run := func(route string) {
+		if err := router.HandleRequest(route, cxt, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cxt.Put("id", 1)
+	cxt.Put("ignored", "a")
+	run("test")
+	cxt.Put("ignored", "b")
+	run("test")
+
+	if calls != 1 {
+		t.Errorf("! Expected the command to run once, ran %d times.", calls)
+	}
+	if v := cxt.Get("count", nil); v != 1 {
+		t.Errorf("! Expected the cached result 1, got %v", v)
+	}
+
+	cxt.Put("id", 2)
+	run("test")
+	if calls != 2 {
+		t.Errorf("! Expected the command to run again for a new id, ran %d times.", calls)
+	}
+	if v := cxt.Get("count", nil); v != 2 {
+		t.Errorf("! Expected 2, got %v", v)
+	}
+
+	// An expired result is not reused.
+	calls = 0
+	run("expires")
+	run("expires")
+	if calls != 2 {
+		t.Errorf("! Expected expired results to be recomputed, ran %d times.", calls)
+	}
+}
+
+func TestCacheIn(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	calls := 0
+	count := func(c Context, p *Params) (interface{}, Interrupt) {
+		calls++
+		return calls, nil
+	}
+	store := NewLRUCache(10)
+
+	reg.Route("a", "Test a shared store.").
+		Does(count, "count").
+		Using("id").From("cxt:id").
+		CacheIn(store, time.Minute, "id").
+		Route("b", "Test a shared store, again.").
+		Does(count, "count").
+		Using("id").From("cxt:id").
+		CacheIn(store, time.Minute, "id")
+
+	cxt.Put("id", 1)
+	for _, route := range []string{"a", "b", "a", "b"} {
+		if err := router.HandleRequest(route, cxt, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 || store.Len() != 2 {
+		t.Errorf("! Expected each route to run once and be cached, got %d calls and %d results", calls, store.Len())
+	}
+	if v := cxt.Get("count", nil); v != 2 {
+		t.Errorf("! Expected the cached result 2 for route b, got %v", v)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+	c.Lookup("a")
+	c.Set("c", 3, 0)
+
+	if _, ok := c.Lookup("b"); ok {
+		t.Error("! Expected the least recently used value to be evicted.")
+	}
+	if v, ok := c.Lookup("a"); !ok || v != 1 {
+		t.Errorf("! Expected a to be cached, got %v", v)
+	}
+	if c.Len() != 2 {
+		t.Errorf("! Expected 2 values, found %d", c.Len())
+	}
+
+	c.Set("d", 4, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok := c.Lookup("d"); ok {
+		t.Error("! Expected d to expire.")
+	}
+	c.Delete("a", "c")
+	if c.Len() != 0 {
+		t.Errorf("! Expected an empty cache, found %d", c.Len())
+	}
+}
+
+type countingDatasource struct {
+	values map[string]interface{}
+	reads  int
+}
+
+func (d *countingDatasource) Value(key string) interface{} {
+	d.reads++
+	return d.values[key]
+}
+
+func TestCachingDatasource(t *testing.T) {
+	src := &countingDatasource{values: map[string]interface{}{"motd": "Hello"}}
+	ds := NewCachingDatasource(src, NewLRUCache(10), time.Minute)
+
+	reg, router, cxt := Cookoo()
+	cxt.AddDatasource("settings", ds)
+	reg.Route("test", "Test a caching datasource.").
+		Does(AddToContext, "add").
+		Using("motd").From("settings:motd")
+
+	for i := 0; i < 2; i++ {
+		if err := router.HandleRequest("test", cxt, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if src.reads != 1 {
+		t.Errorf("! Expected one read, got %d", src.reads)
+	}
+	if v := cxt.Get("motd", nil); v != "Hello" {
+		t.Errorf("! Expected Hello, got %v", v)
+	}
+
+	if _, ok := ds.Has("missing"); ok {
+		t.Error("! Expected missing to be missing.")
+	}
+	ds.Has("missing")
+	if src.reads != 3 {
+		t.Errorf("! Expected missing values to be read again, got %d reads", src.reads)
+	}
+
+	src.values["motd"] = "Goodbye"
+	ds.Invalidate("motd")
+	if v := ds.Get("motd", nil); v != "Goodbye" {
+		t.Errorf("! Expected the new value after Invalidate, got %v", v)
+	}
+}
+
+func TestCacheCommand(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	calls := 0
+	count := func(c Context, p *Params) (interface{}, Interrupt) {
+		calls++
+		if p.Get("id", nil) == 0 {
+			return nil, &FatalError{"No id"}
+		}
+		return calls, nil
+	}
+	store := NewLRUCache(10)
+
+	reg.Route("test", "Test a cached command.").
+		Does(CacheCommand(count, store, time.Minute, "id"), "count").
+		Using("id").From("cxt:id")
+	run := func(fails bool) {
+		if err := router.HandleRequest("test", cxt, false); (err != nil) != fails {
+			t.Fatalf("! Unexpected error: %v", err)
+		}
+	}
+
+	cxt.Put("id", 1)
+	run(false)
+	run(false)
+	if calls != 1 || cxt.Get("count", nil) != 1 {
+		t.Errorf("! Expected one call and the cached result, got %d calls and %v", calls, cxt.Get("count", nil))
+	}
+
+	cxt.Put("id", 2)
+	run(false)
+	if calls != 2 || store.Len() != 2 {
+		t.Errorf("! Expected a second call and 2 cached results, got %d calls and %d results", calls, store.Len())
+	}
+
+	cxt.Put("id", 0)
+	run(true)
+	run(true)
+	if calls != 4 || store.Len() != 2 {
+		t.Errorf("! Expected failures not to be cached, got %d calls and %d results", calls, store.Len())
+	}
+}
@@ -0,0 +1,252 @@
+package cookoo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// NewChildContext creates a context that falls through to a parent context.
+//
+// This is useful for layering request-scoped values on top of an
+// application-scoped base context. Reads check the child first, and then the
+// parent. Writes only go to the child, so the parent is never modified. Unlike
+// Copy, the child is not a snapshot: values added to the parent after the
+// child is created are still visible through the child.
+//
+// Datasources fall through in the same way. RemoveDatasource only removes a
+// datasource from the child, so a datasource of the same name in the parent
+// will still be visible.
+//
+// Loggers are shared with the parent. So is the Go context, unless one is
+// set on the child with SetGoContext.
+//
+// Copy and DeepCopy flatten a child into a new standalone context, holding
+// the values of both the child and the parent.
+func NewChildContext(parent Context) Context {
+	local := new(ExecutionContext).Init()
+	// A nil Go context means the parent's is used.
+	local.goCxt = nil
+	return &childContext{parent: parent, local: local}
+}
+
+type childContext struct {
+	parent Context
+	local  *ExecutionContext
+}
+
+// Add is deprecated. Use Put instead.
+func (c *childContext) Add(key string, val ContextValue) {
+	c.Put(key, val)
+}
+
+// Put inserts a value into the child.
+func (c *childContext) Put(key string, val ContextValue) {
+	c.local.Put(key, val)
+}
+
+// AddWithTTL inserts an expiring value into the child.
+func (c *childContext) AddWithTTL(key string, val ContextValue, ttl time.Duration) {
+	c.local.AddWithTTL(key, val, ttl)
+}
+
+// Get returns a value from the child or, failing that, the parent.
+func (c *childContext) Get(key string, def interface{}) ContextValue {
+	if v, ok := c.Has(key); ok {
+		return v
+	}
+	return def
+}
+
+// Has checks the child and then the parent for a value.
+func (c *childContext) Has(key string) (ContextValue, bool) {
+	if v, ok := c.local.Has(key); ok {
+		return v, true
+	}
+	return c.parent.Has(key)
+}
+
+// Datasource returns a datasource from the child or, failing that, the parent.
+func (c *childContext) Datasource(key string) Datasource {
+	ds, _ := c.HasDatasource(key)
+	return ds
+}
+
+// Datasources returns a new map of the datasources of the child and the parent.
+func (c *childContext) Datasources() map[string]Datasource {
+	ds := map[string]Datasource{}
+	for k, v := range c.parent.Datasources() {
+		ds[k] = v
+	}
+	for k, v := range c.local.Datasources() {
+		ds[k] = v
+	}
+	return ds
+}
+
+// HasDatasource checks the child and then the parent for a datasource.
+func (c *childContext) HasDatasource(key string) (Datasource, bool) {
+	if ds, ok := c.local.HasDatasource(key); ok {
+		return ds, true
+	}
+	return c.parent.HasDatasource(key)
+}
+
+// AddDatasource adds a datasource to the child.
+func (c *childContext) AddDatasource(key string, ds Datasource) {
+	c.local.AddDatasource(key, ds)
+}
+
+// RemoveDatasource removes a datasource from the child.
+func (c *childContext) RemoveDatasource(key string) {
+	c.local.RemoveDatasource(key)
+}
+
+// Len returns the number of distinct values in the child and the parent.
+func (c *childContext) Len() int {
+	return len(c.AsMap())
+}
+
+// Keys returns the sorted names of the values in the child and the parent.
+func (c *childContext) Keys() []string {
+	vals := c.AsMap()
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MarshalJSON encodes the child and parent as JSON for debugging. See
+// DumpContext.
+func (c *childContext) MarshalJSON() ([]byte, error) {
+	return marshalContext(c)
+}
+
+// Copy flattens the child and parent into a shallow copy.
+func (c *childContext) Copy() Context {
+	return c.overlay(c.parent.Copy(), c.local.Copy().(*ExecutionContext))
+}
+
+// DeepCopy flattens the child and parent into a deep copy.
+func (c *childContext) DeepCopy() Context {
+	return c.overlay(c.parent.DeepCopy(), c.local.DeepCopy().(*ExecutionContext))
+}
+
+// Bind binds values into a struct, preferring the child's values to the
+// parent's.
+func (c *childContext) Bind(target interface{}) error {
+	return bindTagged(GettableCxt(c), target, "cxt", true)
+}
+
+// overlay adds a copy of the child's local store to a copy of the parent.
+func (c *childContext) overlay(cp Context, local *ExecutionContext) Context {
+	now := ContextClock()
+	for k, v := range local.values {
+		if t, ok := local.expires[k]; ok {
+			cp.AddWithTTL(k, v, t.Sub(now))
+		} else {
+			cp.Put(k, v)
+		}
+	}
+	for k, ds := range local.datasources {
+		cp.AddDatasource(k, ds)
+	}
+	if local.goCxt != nil {
+		cp.SetGoContext(local.goCxt)
+	}
+	return cp
+}
+
+// NewChild creates a child of this child.
+func (c *childContext) NewChild() Context {
+	return NewChildContext(c)
+}
+
+// AsMap returns a new map of the values of the child and the parent.
+func (c *childContext) AsMap() map[string]ContextValue {
+	vals := map[string]ContextValue{}
+	for k, v := range c.parent.AsMap() {
+		vals[k] = v
+	}
+	for k, v := range c.local.AsMap() {
+		vals[k] = v
+	}
+	return vals
+}
+
+// Logger gets a logger from the parent.
+func (c *childContext) Logger(name string) (io.Writer, bool) {
+	return c.parent.Logger(name)
+}
+
+// AddLogger adds a logger to the parent, since loggers are shared.
+func (c *childContext) AddLogger(name string, logger io.Writer) {
+	c.parent.AddLogger(name, logger)
+}
+
+// RemoveLogger removes a logger from the parent, since loggers are shared.
+func (c *childContext) RemoveLogger(name string) {
+	c.parent.RemoveLogger(name)
+}
+
+// LogHandler gets the parent's LogHandler.
+func (c *childContext) LogHandler() LogHandler {
+	return logHandler(c.parent)
+}
+
+// SetLogHandler sets the parent's LogHandler, since loggers are shared.
+func (c *childContext) SetLogHandler(h LogHandler) {
+	if lc, ok := c.parent.(LogHandlerContext); ok {
+		lc.SetLogHandler(h)
+	}
+}
+
+// skipped checks whether the parent ignores log messages with the prefix.
+func (c *childContext) skipped(prefix string) bool {
+	return logSkipped(c.parent, prefix)
+}
+
+// Log sends a message to the parent's loggers.
+//
+// If the parent has a LogHandler, the message is handled with the child, so
+// that the handler sees the child's values. Prefixes that the parent skips
+// are skipped either way.
+func (c *childContext) Log(prefix string, v ...interface{}) {
+	if c.skipped(prefix) {
+		return
+	}
+	if h := c.LogHandler(); h != nil {
+		h.Handle(c, prefix, fmt.Sprint(v...))
+		return
+	}
+	c.parent.Log(prefix, v...)
+}
+
+// Logf formats a message and sends it to the parent's loggers. See Log.
+func (c *childContext) Logf(prefix, format string, v ...interface{}) {
+	if c.skipped(prefix) {
+		return
+	}
+	if h := c.LogHandler(); h != nil {
+		h.Handle(c, prefix, fmt.Sprintf(format, v...))
+		return
+	}
+	c.parent.Logf(prefix, format, v...)
+}
+
+// GoContext returns the child's Go context, or the parent's if none is set.
+func (c *childContext) GoContext() context.Context {
+	if ctx := c.local.GoContext(); ctx != nil {
+		return ctx
+	}
+	return c.parent.GoContext()
+}
+
+// SetGoContext sets the Go context of the child, without changing the parent.
+func (c *childContext) SetGoContext(ctx context.Context) {
+	c.local.SetGoContext(ctx)
+}
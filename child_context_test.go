@@ -0,0 +1,61 @@
+package cookoo
+
+import (
+	"testing"
+)
+
+func TestChildContext(t *testing.T) {
+	parent := NewContext()
+	parent.Put("a", "parent a")
+	parent.Put("b", "parent b")
+	parent.AddDatasource("foo", new(ExampleDatasource))
+
+	child := parent.NewChild()
+	child.Put("b", "child b")
+	child.Put("c", "child c")
+	parent.Put("d", "parent d")
+
+	if v := child.Get("a", nil); v != "parent a" {
+		t.Errorf("! Expected the child to fall through to the parent, got %v", v)
+	}
+	if v := child.Get("b", nil); v != "child b" {
+		t.Errorf("! Expected the child to override the parent, got %v", v)
+	}
+	if v := child.Get("d", nil); v != "parent d" {
+		t.Errorf("! Expected a later parent value to be visible, got %v", v)
+	}
+	if v := parent.Get("b", nil); v != "parent b" {
+		t.Errorf("! Expected the parent to be unchanged, got %v", v)
+	}
+	if _, ok := parent.Has("c"); ok {
+		t.Error("! Child value leaked into the parent.")
+	}
+	if child.Len() != 4 || parent.Len() != 3 {
+		t.Errorf("! Expected lengths of 4 and 3, got %d and %d", child.Len(), parent.Len())
+	}
+
+	if _, ok := child.HasDatasource("foo"); !ok {
+		t.Error("! Expected the datasource to fall through.")
+	}
+	child.AddDatasource("bar", new(ExampleDatasource))
+	if _, ok := parent.HasDatasource("bar"); ok {
+		t.Error("! Child datasource leaked into the parent.")
+	}
+	if len(child.Datasources()) != 2 {
+		t.Errorf("! Expected two datasources, got %d", len(child.Datasources()))
+	}
+
+	grandchild := child.NewChild()
+	if v := grandchild.Get("c", nil); v != "child c" {
+		t.Errorf("! Expected the grandchild to see the child, got %v", v)
+	}
+
+	flat := child.Copy()
+	parent.Put("e", "parent e")
+	if _, ok := flat.Has("e"); ok {
+		t.Error("! Expected a copy of the child to be a snapshot.")
+	}
+	if v := flat.Get("b", nil); v != "child b" || flat.Len() != 4 {
+		t.Errorf("! Expected a flattened copy, got %v", flat.AsMap())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/Masterminds/cookoo"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 
 	"strings"
@@ -84,6 +85,10 @@ type Runner struct {
 
 	summary, usage string
 	flags *flag.FlagSet
+
+	// name and out are used by Dispatch.
+	name string
+	out  io.Writer
 }
 
 // Help sets the help text and support flags for the app.
@@ -0,0 +1,388 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// Dispatch runs routes as subcommands, with flags derived from their params.
+//
+// The first argument that is not a global flag names the route to run, and
+// the arguments after it are parsed with the route's own flags (see
+// FlagsFor). Every route whose name does not start with "@" can be run this
+// way:
+//
+// 	reg.Route("migrate", "Migrate the database.").
+// 		Does(Migrate, "migrate").Help("Apply pending migrations.").
+// 			Using("steps").WithDefault(0).From("cxt:steps").
+// 			Help("The number of migrations to apply. 0 applies all of them.")
+// 	reg.Route("serve", "Run the server.").
+// 		Does(Serve, "server").
+// 			Using("addr").WithDefault(":8080").From("cxt:addr")
+//
+// 	if err := cli.New(reg, router, cxt).Help(Summary, Usage, nil).Dispatch(os.Args[1:]); err != nil {
+// 		fmt.Fprintln(os.Stderr, err)
+// 		os.Exit(1)
+// 	}
+//
+// Now the program can be run like this:
+//
+// 	$ mytool migrate --steps 2
+// 	$ mytool serve -addr :9000
+// 	$ mytool serve --help       # Help for the serve command
+// 	$ mytool help serve         # The same
+// 	$ mytool -h                 # Help for the program, listing the commands
+// 	$ mytool completion bash    # A bash completion script
+//
+// Global flags are put into the context as strings, as with Run. The
+// subcommand's flags are put into the context with their own types, but only
+// if they are given on the command line, so that the params otherwise get
+// their defaults. Arguments left over after the flags are put into the context
+// as "subcommand.Args".
+//
+// The `help` and `completion` subcommands are built in, unless there are
+// routes with those names. Completion scripts can be generated for bash, zsh,
+// and fish.
+func (r *Runner) Dispatch(args []string) error {
+	out := r.output()
+	if r.flags == nil {
+		r.flags = flag.NewFlagSet("globalFlags", flag.ContinueOnError)
+		r.flags.Bool("h", false, "Show this help text.")
+		r.flags.Bool("help", false, "Show this help text.")
+	}
+	if err := r.flags.Parse(args); err != nil {
+		return err
+	}
+	addFlagsToContext(r.flags, r.cxt)
+
+	rest := r.flags.Args()
+	if len(rest) == 0 || wantsHelp(r.flags, map[string]bool{"h": true, "help": true}) {
+		r.appHelp(out)
+		return nil
+	}
+
+	name := rest[0]
+	if _, ok := r.reg.RouteSpec(name); !ok || strings.HasPrefix(name, "@") {
+		switch name {
+		case "help":
+			if len(rest) > 1 {
+				return r.commandHelp(rest[1], out)
+			}
+			r.appHelp(out)
+			return nil
+		case "completion":
+			shell := "bash"
+			if len(rest) > 1 {
+				shell = rest[1]
+			}
+			return r.Completion(shell, out)
+		}
+		return &cookoo.RouteError{Message: fmt.Sprintf("Unknown command %s. Run '%s help' for a list of commands.", name, r.progName())}
+	}
+
+	flags := FlagsFor(r.reg, name)
+	builtin := addHelpFlags(flags)
+	flags.SetOutput(out)
+	if err := flags.Parse(rest[1:]); err != nil {
+		return err
+	}
+	if wantsHelp(flags, builtin) {
+		return r.commandHelp(name, out)
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		if builtin[f.Name] {
+			return
+		}
+		if g, ok := f.Value.(flag.Getter); ok {
+			r.cxt.Put(f.Name, g.Get())
+		} else {
+			r.cxt.Put(f.Name, f.Value.String())
+		}
+	})
+	r.cxt.Put("subcommand.Args", flags.Args())
+	return r.router.HandleRequest(name, r.cxt, true)
+}
+
+// Named sets the name of the program, as it is shown in help text and
+// completion scripts. The default is the base name of os.Args[0].
+func (r *Runner) Named(name string) *Runner {
+	r.name = name
+	return r
+}
+
+// Output sets where help text and completion scripts are written. The
+// default is os.Stdout.
+func (r *Runner) Output(w io.Writer) *Runner {
+	r.out = w
+	return r
+}
+
+// FlagsFor builds a flag set for a route from the params that it declares.
+//
+// Each param that can be read from the context, as in `From("cxt:steps")`,
+// becomes a flag with the name of the context value (here, "steps"). The
+// param's default and help text become the flag's default and usage. The type
+// of the flag follows the type of the default: bool, int, int64, uint,
+// uint64, float64, time.Duration, or string. A param with no default is a
+// string flag, and a param with a default of any other type is not made into
+// a flag.
+//
+// If several params read the same context value, the first one defines the
+// flag. If the route does not exist, the flag set is empty.
+func FlagsFor(reg *cookoo.Registry, route string) *flag.FlagSet {
+	fs := flag.NewFlagSet(route, flag.ContinueOnError)
+	spec, ok := reg.RouteSpec(route)
+	if !ok {
+		return fs
+	}
+	for _, cmd := range cookoo.RouteDetails(spec).Commands() {
+		for _, p := range cmd.Params {
+			for _, from := range p.From {
+				if !strings.HasPrefix(from, "cxt:") {
+					continue
+				}
+				name := strings.TrimPrefix(from, "cxt:")
+				if name == "" || fs.Lookup(name) != nil {
+					continue
+				}
+				usage := p.Help
+				if usage == "" {
+					usage = fmt.Sprintf("The %s param of %s.", p.Name, cmd.Name)
+				}
+				defineFlag(fs, name, p.Default, usage)
+			}
+		}
+	}
+	return fs
+}
+
+// Completion writes a shell completion script for the program's subcommands
+// and their flags. The shell is "bash", "zsh", or "fish".
+//
+// For example, in ~/.bashrc:
+//
+// 	source <(mytool completion bash)
+func (r *Runner) Completion(shell string, w io.Writer) error {
+	prog := r.progName()
+	names := r.commandNames()
+
+	switch shell {
+	case "bash", "zsh":
+		fn := "_" + strings.Map(func(c rune) rune {
+			if c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+				return c
+			}
+			return '_'
+		}, prog) + "_complete"
+
+		if shell == "zsh" {
+			fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit")
+		}
+		fmt.Fprintf(w, "%s() {\n", fn)
+		fmt.Fprintln(w, "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\"")
+		fmt.Fprintln(w, "\tif [ \"$COMP_CWORD\" -eq 1 ]; then")
+		fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+		fmt.Fprintln(w, "\t\treturn")
+		fmt.Fprintln(w, "\tfi")
+		fmt.Fprintln(w, "\tcase \"${COMP_WORDS[1]}\" in")
+		for _, name := range names {
+			if _, ok := r.reg.RouteSpec(name); !ok {
+				continue
+			}
+			fmt.Fprintf(w, "\t%s)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\t;;\n", name, strings.Join(flagNames(r.reg, name), " "))
+		}
+		fmt.Fprintln(w, "\tesac")
+		fmt.Fprintln(w, "}")
+		fmt.Fprintf(w, "complete -F %s %s\n", fn, prog)
+	case "fish":
+		fmt.Fprintf(w, "complete -c %s -f\n", prog)
+		for _, name := range names {
+			fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n", prog, name, fishQuote(r.commandSummary(name)))
+		}
+		for _, name := range names {
+			if _, ok := r.reg.RouteSpec(name); !ok {
+				continue
+			}
+			flags := FlagsFor(r.reg, name)
+			addHelpFlags(flags)
+			flags.VisitAll(func(f *flag.Flag) {
+				fmt.Fprintf(w, "complete -c %s -n '__fish_seen_subcommand_from %s' -l %s -d %s\n", prog, name, f.Name, fishQuote(f.Usage))
+			})
+		}
+	default:
+		return fmt.Errorf("No completion for shell %s. Use bash, zsh, or fish.", shell)
+	}
+	return nil
+}
+
+// appHelp writes the help text for the program.
+func (r *Runner) appHelp(out io.Writer) {
+	lines := []string{}
+	for _, name := range r.commandNames() {
+		lines = append(lines, fmt.Sprintf("\t%s: %s", name, r.commandSummary(name)))
+	}
+	help := map[string]interface{}{
+		"summary":     r.summary,
+		"usage":       r.usage,
+		"subcommands": strings.Join(lines, "\n"),
+	}
+	if help["usage"] == "" {
+		help["usage"] = fmt.Sprintf("%s [flags] COMMAND [command flags] [args]", r.progName())
+	}
+	if r.flags != nil {
+		help["flags"] = r.flags
+	}
+	displayHelp([]string{"summary", "usage", "subcommands"}, help, out)
+}
+
+// commandHelp writes the help text for a subcommand.
+//
+// The summary is the route's description, and the description is made of the
+// help text of the route's commands.
+func (r *Runner) commandHelp(name string, out io.Writer) error {
+	spec, ok := r.reg.RouteSpec(name)
+	if !ok || strings.HasPrefix(name, "@") {
+		return &cookoo.RouteError{Message: fmt.Sprintf("Unknown command %s.", name)}
+	}
+	details := cookoo.RouteDetails(spec)
+
+	lines := []string{}
+	for _, cmd := range details.Commands() {
+		if cmd.Help != "" {
+			lines = append(lines, cmd.Help)
+		}
+	}
+	flags := FlagsFor(r.reg, name)
+	addHelpFlags(flags)
+	help := map[string]interface{}{
+		"summary":     details.Description(),
+		"usage":       fmt.Sprintf("%s %s [flags] [args]", r.progName(), name),
+		"description": strings.Join(lines, "\n"),
+		"flags":       flags,
+	}
+	displayHelp([]string{"summary", "usage", "description"}, help, out)
+	return nil
+}
+
+// commandNames lists the subcommands, including the built-in ones.
+//
+// Routes whose names start with "@" or contain spaces cannot be run as
+// subcommands, so they are left out.
+func (r *Runner) commandNames() []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range r.reg.RouteNames() {
+		if strings.HasPrefix(name, "@") || strings.ContainsAny(name, " \t") {
+			continue
+		}
+		names = append(names, name)
+		seen[name] = true
+	}
+	for _, name := range []string{"help", "completion"} {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// commandSummary returns the one-line summary of a subcommand.
+func (r *Runner) commandSummary(name string) string {
+	if spec, ok := r.reg.RouteSpec(name); ok {
+		return cookoo.RouteDetails(spec).Description()
+	}
+	switch name {
+	case "help":
+		return "Show help for a command."
+	case "completion":
+		return "Print a shell completion script for bash, zsh, or fish."
+	}
+	return ""
+}
+
+func (r *Runner) progName() string {
+	if r.name != "" {
+		return r.name
+	}
+	return filepath.Base(os.Args[0])
+}
+
+func (r *Runner) output() io.Writer {
+	if r.out != nil {
+		return r.out
+	}
+	return os.Stdout
+}
+
+// defineFlag adds a flag whose type follows the type of its default.
+func defineFlag(fs *flag.FlagSet, name string, def interface{}, usage string) {
+	switch d := def.(type) {
+	case nil:
+		fs.String(name, "", usage)
+	case string:
+		fs.String(name, d, usage)
+	case bool:
+		fs.Bool(name, d, usage)
+	case int:
+		fs.Int(name, d, usage)
+	case int64:
+		fs.Int64(name, d, usage)
+	case uint:
+		fs.Uint(name, d, usage)
+	case uint64:
+		fs.Uint64(name, d, usage)
+	case float64:
+		fs.Float64(name, d, usage)
+	case time.Duration:
+		fs.Duration(name, d, usage)
+	}
+}
+
+// addHelpFlags adds -h and -help to a flag set, unless the route already
+// uses those names. It returns the flags that were added.
+func addHelpFlags(fs *flag.FlagSet) map[string]bool {
+	added := map[string]bool{}
+	for _, name := range []string{"h", "help"} {
+		if fs.Lookup(name) == nil {
+			fs.Bool(name, false, "Show help for this command.")
+			added[name] = true
+		}
+	}
+	return added
+}
+
+// wantsHelp returns true if one of the named help flags was given.
+func wantsHelp(fs *flag.FlagSet, names map[string]bool) bool {
+	for name := range names {
+		if f := fs.Lookup(name); f != nil && f.Value.String() == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// flagNames lists a route's flags as they are typed, e.g. "--steps".
+func flagNames(reg *cookoo.Registry, route string) []string {
+	flags := FlagsFor(reg, route)
+	addHelpFlags(flags)
+	names := []string{}
+	flags.VisitAll(func(f *flag.Flag) {
+		names = append(names, "--"+f.Name)
+	})
+	sort.Strings(names)
+	return names
+}
+
+// fishQuote quotes a string for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+func captureParams(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	return params.AsMap(), nil
+}
+
+func dispatchApp() (*Runner, cookoo.Context, *bytes.Buffer) {
+	reg, router, cxt := cookoo.Cookoo()
+	reg.Route("migrate", "Migrate the database.").
+		Does(captureParams, "migrate").Help("Apply pending migrations.").
+		Using("steps").WithDefault(0).From("cxt:steps").Help("How many migrations to apply.").
+		Using("dry").WithDefault(false).From("cxt:dry").
+		Using("wait").WithDefault(time.Second).From("cxt:wait")
+	reg.Route("serve", "Run the server.").
+		Does(captureParams, "serve").
+		Using("addr").WithDefault(":8080").From("cxt:addr")
+	reg.Route("@internal", "Not a command.")
+
+	var out bytes.Buffer
+	app := New(reg, router, cxt).Help("A test tool.", "", nil).Named("mytool").Output(&out)
+	return app, cxt, &out
+}
+
+func TestDispatch(t *testing.T) {
+	app, cxt, _ := dispatchApp()
+	if err := app.Dispatch([]string{"migrate", "--steps", "2", "-wait=5s", "extra"}); err != nil {
+		t.Fatal(err)
+	}
+	if v := cxt.Get("steps", nil); v != 2 {
+		t.Errorf("! Expected steps to be int 2, got %#v", v)
+	}
+	if v := cxt.Get("wait", nil); v != 5*time.Second {
+		t.Errorf("! Expected wait to be 5s, got %#v", v)
+	}
+	if _, ok := cxt.Has("dry"); ok {
+		t.Error("! Expected flags that were not given to be left out.")
+	}
+	if m := cxt.Get("migrate", nil).(map[string]interface{}); m["dry"] != false || m["steps"] != 2 {
+		t.Errorf("! Unexpected params: %v", m)
+	}
+	if args := cxt.Get("subcommand.Args", nil).([]string); len(args) != 1 || args[0] != "extra" {
+		t.Errorf("! Expected the remaining args, got %v", args)
+	}
+
+	app, _, _ = dispatchApp()
+	if err := app.Dispatch([]string{"nope"}); err == nil {
+		t.Error("! Expected an error for an unknown command.")
+	}
+	if err := app.Dispatch([]string{"@internal"}); err == nil {
+		t.Error("! Expected internal routes not to be commands.")
+	}
+}
+
+func TestDispatchHelp(t *testing.T) {
+	app, _, out := dispatchApp()
+	app.Dispatch([]string{"-h"})
+	msg := out.String()
+	if !strings.Contains(msg, "A test tool.") || !strings.Contains(msg, "migrate: Migrate the database.") ||
+		!strings.Contains(msg, "completion:") || strings.Contains(msg, "@internal") {
+		t.Errorf("! Unexpected app help: %s", msg)
+	}
+
+	for _, args := range [][]string{{"migrate", "--help"}, {"help", "migrate"}} {
+		app, cxt, out := dispatchApp()
+		if err := app.Dispatch(args); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cxt.Has("migrate"); ok {
+			t.Errorf("! Expected %v not to run the route.", args)
+		}
+		msg := out.String()
+		for _, want := range []string{"Migrate the database.", "mytool migrate [flags]", "Apply pending migrations.", "-steps: How many migrations to apply. (Default: '0')", "-addr"} {
+			if strings.Contains(msg, want) == (want == "-addr") {
+				t.Errorf("! Help for %v: expected %q to be present: %v\n%s", args, want, want != "-addr", msg)
+			}
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	app, _, out := dispatchApp()
+	if err := app.Dispatch([]string{"completion", "bash"}); err != nil {
+		t.Fatal(err)
+	}
+	msg := out.String()
+	if !strings.Contains(msg, `compgen -W "migrate serve help completion"`) ||
+		!strings.Contains(msg, `"--dry --h --help --steps --wait"`) ||
+		!strings.Contains(msg, "complete -F _mytool_complete mytool") {
+		t.Errorf("! Unexpected bash completion:\n%s", msg)
+	}
+
+	out.Reset()
+	app.Completion("fish", out)
+	if !strings.Contains(msg, "serve") || !strings.Contains(out.String(), "-n '__fish_seen_subcommand_from serve' -l addr") {
+		t.Errorf("! Unexpected fish completion:\n%s", out.String())
+	}
+	if app.Completion("tcsh", out) == nil {
+		t.Error("! Expected an error for an unknown shell.")
+	}
+}
@@ -1,5 +1,12 @@
 package cookoo
 
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
 // LogMessage prints a message to the log.
 //
 // Params
@@ -54,3 +61,265 @@ func ForwardTo(cxt Context, params *Params) (interface{}, Interrupt) {
 
 	return nil, &Reroute{route}
 }
+
+// AssertRange verifies that a numeric value falls within a range.
+//
+// The value may be an int, int32, int64, uint64, float32, or float64. It is
+// converted to a float64 before it is compared. The range is inclusive.
+//
+// Params
+//
+// 	- value: The value to check. This is required, and is typically set
+// 	  with From("cxt:somekey").
+// 	- min: The minimum allowed value. If omitted, there is no lower bound.
+// 	- max: The maximum allowed value. If omitted, there is no upper bound.
+//
+// Returns
+//
+// 	- The value as a float64.
+//
+// If the value is missing, non-numeric, or out of range, a FatalError is
+// returned.
+func AssertRange(cxt Context, params *Params) (interface{}, Interrupt) {
+	raw, ok := params.Has("value")
+	if !ok {
+		return nil, &FatalError{"Expected a 'value'"}
+	}
+	val, ok := toFloat64(raw)
+	if !ok {
+		return nil, &FatalError{fmt.Sprintf("Expected a numeric value, got %T", raw)}
+	}
+
+	if rawMin, ok := params.Has("min"); ok {
+		min, ok := toFloat64(rawMin)
+		if !ok {
+			return nil, &FatalError{fmt.Sprintf("Expected a numeric min, got %T", rawMin)}
+		}
+		if val < min {
+			return nil, &FatalError{fmt.Sprintf("Value %v is less than minimum %v", val, min)}
+		}
+	}
+
+	if rawMax, ok := params.Has("max"); ok {
+		max, ok := toFloat64(rawMax)
+		if !ok {
+			return nil, &FatalError{fmt.Sprintf("Expected a numeric max, got %T", rawMax)}
+		}
+		if val > max {
+			return nil, &FatalError{fmt.Sprintf("Value %v is greater than maximum %v", val, max)}
+		}
+	}
+
+	return val, nil
+}
+
+// toFloat64 converts any of the core numeric types to a float64.
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// Spread copies selected entries from a map into the context.
+//
+// This is useful for taking a decoded payload and putting its contents into
+// the context so that later commands can use them with From("cxt:...").
+//
+// Params
+//
+// 	- source: A map[string]interface{} to read values from. This is required.
+// 	- mapping: A map[string]string of source keys to context names. Each
+// 	  source key found in the map is put into the context under its
+// 	  corresponding name. This is required.
+// 	- strict: If true, a missing source key will cause a FatalError. By
+// 	  default, missing keys are skipped.
+//
+// Returns
+//
+// 	- A []string of the context names that were set.
+func Spread(cxt Context, params *Params) (interface{}, Interrupt) {
+	ok, missing := params.Requires("source", "mapping")
+	if !ok {
+		return nil, &FatalError{"Missing params: " + strings.Join(missing, ", ")}
+	}
+
+	src, ok := params.Get("source", nil).(map[string]interface{})
+	if !ok {
+		return nil, &FatalError{"Expected 'source' to be a map[string]interface{}"}
+	}
+	mapping, ok := params.Get("mapping", nil).(map[string]string)
+	if !ok {
+		return nil, &FatalError{"Expected 'mapping' to be a map[string]string"}
+	}
+	strict := GetBool("strict", false, params)
+
+	set := make([]string, 0, len(mapping))
+	for from, to := range mapping {
+		v, ok := src[from]
+		if !ok {
+			if strict {
+				return set, &FatalError{fmt.Sprintf("Key '%s' not found in source", from)}
+			}
+			continue
+		}
+		cxt.Put(to, v)
+		set = append(set, to)
+	}
+	return set, nil
+}
+
+// regexpCache holds recently compiled regular expressions keyed by pattern.
+var regexpCache = NewLRUCache(256)
+
+// CompileRegexp compiles a regular expression.
+//
+// Compiled expressions are cached by pattern string and shared by all routes,
+// so a pattern that is used often is only compiled once. The cache holds the
+// 256 most recently used patterns, so patterns from client input cannot make
+// it grow without limit.
+//
+// Params
+//
+// 	- pattern (string): The regular expression. This is required.
+//
+// Returns
+//
+// 	- A *regexp.Regexp.
+//
+// A pattern that does not compile will cause a FatalError.
+func CompileRegexp(cxt Context, params *Params) (interface{}, Interrupt) {
+	pattern, ok := HasString("pattern", params)
+	if !ok {
+		return nil, &FatalError{"Expected a 'pattern'"}
+	}
+
+	if re, ok := regexpCache.Lookup(pattern); ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, &FatalError{fmt.Sprintf("Could not compile pattern: %s", err)}
+	}
+	regexpCache.Set(pattern, re, 0)
+	return re, nil
+}
+
+// Paginate returns one page of a slice.
+//
+// Pages are numbered starting at 1. A page that is out of range returns an
+// empty slice, not an error.
+//
+// Because page numbers and sizes often come from query parameters, both
+// may be given as either integers or strings.
+//
+// Params
+//
+// 	- list ([]interface{}): The slice to paginate. This is required.
+// 	- page (int): The page number. Default: 1
+// 	- size (int): The number of items per page. Default: 10
+// 	- totalKey (string): The context name to store the total number of
+// 	  items in. Default: "pagination.Total"
+// 	- pagesKey (string): The context name to store the total number of
+// 	  pages in. Default: "pagination.Pages"
+//
+// Returns
+//
+// 	- A []interface{} containing the items on the requested page.
+func Paginate(cxt Context, params *Params) (interface{}, Interrupt) {
+	list, ok := params.Get("list", nil).([]interface{})
+	if !ok {
+		return nil, &FatalError{"Expected 'list' to be a []interface{}"}
+	}
+
+	page, err := intParam("page", 1, params)
+	if err != nil {
+		return nil, &FatalError{err.Error()}
+	}
+	size, err := intParam("size", 10, params)
+	if err != nil {
+		return nil, &FatalError{err.Error()}
+	}
+	if size < 1 {
+		return nil, &FatalError{fmt.Sprintf("Page size must be positive, got %d", size)}
+	}
+
+	total := len(list)
+	pages := (total + size - 1) / size
+	cxt.Put(GetString("totalKey", "pagination.Total", params), total)
+	cxt.Put(GetString("pagesKey", "pagination.Pages", params), pages)
+
+	if page < 1 || page > pages {
+		return []interface{}{}, nil
+	}
+
+	start := (page - 1) * size
+	end := start + size
+	if end > total {
+		end = total
+	}
+	return list[start:end], nil
+}
+
+// intParam gets an int from a param that may be either an int or a string.
+func intParam(name string, defaultVal int, params *Params) (int, error) {
+	switch v := params.Get(name, defaultVal).(type) {
+	case int:
+		return v, nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("Expected '%s' to be an integer, got %q", name, v)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("Expected '%s' to be an integer, got %T", name, v)
+	}
+}
+
+// Concat joins several slices in the context into one.
+//
+// This is useful for gathering results after several independent steps.
+// The slices are read from the context in the order given. Names that are
+// not in the context are skipped.
+//
+// Params
+//
+// 	- sources ([]string): The context names of the slices to join. Each value
+// 	  must be a []interface{}. This is required.
+//
+// Returns
+//
+// 	- A []interface{} with the items of every source slice, in order.
+func Concat(cxt Context, params *Params) (interface{}, Interrupt) {
+	sources, ok := params.Get("sources", nil).([]string)
+	if !ok {
+		return nil, &FatalError{"Expected 'sources' to be a []string"}
+	}
+
+	res := []interface{}{}
+	for _, name := range sources {
+		v, ok := cxt.Has(name)
+		if !ok || v == nil {
+			continue
+		}
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, &FatalError{fmt.Sprintf("Expected '%s' to be a []interface{}, got %T", name, v)}
+		}
+		res = append(res, items...)
+	}
+	return res, nil
+}
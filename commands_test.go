@@ -4,6 +4,7 @@ package cookoo
 
 import (
 	"bytes"
+	"fmt"
 	"regexp"
 	"testing"
 )
@@ -115,3 +116,200 @@ func TestForwardTo(t *testing.T) {
 		t.Error("! Expected test3 route to forward to test to adding bar to the context.")
 	}
 }
+
+func TestAssertRange(t *testing.T) {
+	registry, router, context := Cookoo()
+
+	registry.Route("test", "Testing.").
+		Does(AssertRange, "inrange").
+		Using("value").From("cxt:value").
+		Using("min").WithDefault(1).
+		Using("max").WithDefault(10.5)
+
+	context.Put("value", int64(5))
+	if e := router.HandleRequest("test", context, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	if v := context.Get("inrange", nil); v != float64(5) {
+		t.Errorf("! Expected 5, got %v", v)
+	}
+
+	context.Put("value", 0)
+	e := router.HandleRequest("test", context, false)
+	if e == nil {
+		t.Error("! Expected error for value below minimum.")
+	} else if _, ok := e.(*FatalError); !ok {
+		t.Errorf("! Expected a FatalError, got %T", e)
+	}
+
+	context.Put("value", 10.6)
+	if e := router.HandleRequest("test", context, false); e == nil {
+		t.Error("! Expected error for value above maximum.")
+	}
+
+	context.Put("value", "5")
+	if e := router.HandleRequest("test", context, false); e == nil {
+		t.Error("! Expected error for non-numeric value.")
+	}
+}
+
+func TestSpread(t *testing.T) {
+	registry, router, context := Cookoo()
+
+	payload := map[string]interface{}{
+		"name":  "Matt",
+		"email": "matt@example.com",
+		"extra": true,
+	}
+	context.Put("payload", payload)
+
+	registry.Route("test", "Testing.").
+		Does(Spread, "spread").
+		Using("source").From("cxt:payload").
+		Using("mapping").WithDefault(map[string]string{
+		"name":    "user.Name",
+		"email":   "user.Email",
+		"missing": "user.Missing",
+	}).
+		Route("strict", "Testing strict.").
+		Does(Spread, "spread").
+		Using("source").From("cxt:payload").
+		Using("mapping").WithDefault(map[string]string{"missing": "user.Missing"}).
+		Using("strict").WithDefault(true)
+
+	if e := router.HandleRequest("test", context, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+
+	if v := context.Get("user.Name", nil); v != "Matt" {
+		t.Errorf("! Expected Matt, got %v", v)
+	}
+	if v := context.Get("user.Email", nil); v != "matt@example.com" {
+		t.Errorf("! Expected matt@example.com, got %v", v)
+	}
+	if _, ok := context.Has("user.Missing"); ok {
+		t.Error("! Expected missing key to be skipped.")
+	}
+	if _, ok := context.Has("extra"); ok {
+		t.Error("! Unmapped keys should not be copied.")
+	}
+
+	if e := router.HandleRequest("strict", context, false); e == nil {
+		t.Error("! Expected strict mode to fail on a missing key.")
+	}
+}
+
+func TestCompileRegexp(t *testing.T) {
+	registry, router, context := Cookoo()
+
+	registry.Route("test", "Testing.").
+		Does(CompileRegexp, "re").
+		Using("pattern").WithDefault("^a+b$").
+		Route("bad", "Testing a bad pattern.").
+		Does(CompileRegexp, "re").
+		Using("pattern").WithDefault("a(b")
+
+	if e := router.HandleRequest("test", context, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	re, ok := context.Get("re", nil).(*regexp.Regexp)
+	if !ok {
+		t.Fatal("! Expected a *regexp.Regexp")
+	}
+	if !re.MatchString("aaab") {
+		t.Error("! Expected pattern to match aaab")
+	}
+
+	// The second run should come out of the cache.
+	if e := router.HandleRequest("test", context, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	if re2 := context.Get("re", nil).(*regexp.Regexp); re2 != re {
+		t.Error("! Expected the cached regexp to be reused.")
+	}
+
+	if e := router.HandleRequest("bad", context, false); e == nil {
+		t.Error("! Expected bad pattern to fail.")
+	} else if _, ok := e.(*FatalError); !ok {
+		t.Errorf("! Expected a FatalError, got %T", e)
+	}
+
+	// The cache does not grow without limit.
+	for i := 0; i < 300; i++ {
+		CompileRegexp(context, NewParamsWithValues(map[string]interface{}{"pattern": fmt.Sprintf("^%d$", i)}))
+	}
+	if n := regexpCache.Len(); n > 256 {
+		t.Errorf("! Expected at most 256 cached patterns, found %d", n)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	registry, router, context := Cookoo()
+
+	list := []interface{}{1, 2, 3, 4, 5, 6, 7}
+	context.Put("list", list)
+
+	registry.Route("test", "Testing.").
+		Does(Paginate, "results").
+		Using("list").From("cxt:list").
+		Using("page").From("cxt:page").
+		Using("size").WithDefault("3")
+
+	tests := map[int][]interface{}{
+		1: {1, 2, 3},
+		2: {4, 5, 6},
+		3: {7},
+		4: {},
+		0: {},
+	}
+	for page, expects := range tests {
+		context.Put("page", page)
+		if e := router.HandleRequest("test", context, false); e != nil {
+			t.Errorf("! Unexpected error: %s", e)
+		}
+		res := context.Get("results", nil).([]interface{})
+		if len(res) != len(expects) {
+			t.Errorf("! Expected page %d to have %d items, got %d", page, len(expects), len(res))
+			continue
+		}
+		for i, v := range expects {
+			if res[i] != v {
+				t.Errorf("! Expected %v at position %d of page %d, got %v", v, i, page, res[i])
+			}
+		}
+	}
+
+	if v := context.Get("pagination.Total", nil); v != 7 {
+		t.Errorf("! Expected a total of 7, got %v", v)
+	}
+	if v := context.Get("pagination.Pages", nil); v != 3 {
+		t.Errorf("! Expected 3 pages, got %v", v)
+	}
+}
+
+func TestConcat(t *testing.T) {
+	registry, router, context := Cookoo()
+
+	context.Put("a", []interface{}{1, 2})
+	context.Put("c", []interface{}{"three"})
+
+	registry.Route("test", "Testing.").
+		Does(Concat, "all").
+		Using("sources").WithDefault([]string{"a", "b", "c"})
+
+	if e := router.HandleRequest("test", context, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+
+	all, ok := context.Get("all", nil).([]interface{})
+	if !ok {
+		t.Fatal("! Expected a []interface{}")
+	}
+	expects := []interface{}{1, 2, "three"}
+	equal(t, expects, all)
+
+	context.Put("b", "not a slice")
+	if e := router.HandleRequest("test", context, false); e == nil {
+		t.Error("! Expected a non-slice source to fail.")
+	}
+}
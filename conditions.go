@@ -0,0 +1,48 @@
+package cookoo
+
+import (
+	"reflect"
+)
+
+// Predicate decides whether a conditional command should run.
+//
+// It is given the context and the command's resolved params. See
+// Registry.When.
+type Predicate func(cxt Context, params *Params) bool
+
+// IsSet returns a predicate that is true if the context has a value with the
+// given name.
+func IsSet(name string) Predicate {
+	return func(cxt Context, params *Params) bool {
+		_, ok := cxt.Has(name)
+		return ok
+	}
+}
+
+// Equals returns a predicate that is true if a context value equals the
+// given value.
+//
+// Numbers are compared by value, so Equals("count", 1) matches an int64 or a
+// float64 of 1, as may come from a decoded payload. Other values are
+// compared with reflect.DeepEqual.
+func Equals(name string, value interface{}) Predicate {
+	return func(cxt Context, params *Params) bool {
+		v, ok := cxt.Has(name)
+		if !ok {
+			return false
+		}
+		if value != nil && v != nil {
+			if cv, ok := convertValue(v, reflect.TypeOf(value)); ok {
+				return reflect.DeepEqual(cv.Interface(), value)
+			}
+		}
+		return reflect.DeepEqual(v, value)
+	}
+}
+
+// Not returns a predicate that is true when p is false.
+func Not(p Predicate) Predicate {
+	return func(cxt Context, params *Params) bool {
+		return !p(cxt, params)
+	}
+}
@@ -0,0 +1,99 @@
+package cookoo
+
+import (
+	"testing"
+)
+
+func TestConditionalCommands(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	positive := func(c Context, p *Params) bool {
+		return p.Get("n", 0).(int) > 0
+	}
+	reg.Route("branch", "Branching route.").
+		Does(AddToContext, "always").Using("ran").WithDefault(true).
+		DoesIf(IsSet("user"), AddToContext, "greet").Using("greeted").WithDefault(true).
+		Does(AddToContext, "guest").Using("guest").WithDefault(true).
+		Unless(IsSet("user")).
+		Does(AddToContext, "pro").Using("pro").WithDefault(true).
+		When(IsSet("user")).When(Equals("plan", "pro")).
+		Does(AddToContext, "count").Using("n").From("cxt:count").
+		When(positive).
+		Does(AddToContext, "a").Using("a").WithDefault(true).InParallel().When(IsSet("user")).
+		Does(AddToContext, "b").Using("b").WithDefault(true).InParallel()
+
+	if err := router.HandleRequest("branch", cxt, false); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"ran": true, "greeted": false, "guest": true, "pro": false, "n": false, "a": false, "b": true} {
+		if _, ok := cxt.Has(name); ok != want {
+			t.Errorf("! Without a user, expected %s set to be %v", name, want)
+		}
+	}
+	if _, ok := cxt.Has("greet"); ok {
+		t.Error("! Expected nothing to be stored for a skipped command.")
+	}
+
+	cxt = NewContext()
+	cxt.Put("user", "matt")
+	cxt.Put("plan", "pro")
+	cxt.Put("count", 2)
+	if err := router.HandleRequest("branch", cxt, false); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"greeted": true, "guest": false, "pro": true, "n": true, "a": true} {
+		if _, ok := cxt.Has(name); ok != want {
+			t.Errorf("! With a user, expected %s set to be %v", name, want)
+		}
+	}
+
+	spec, _ := reg.RouteSpec("branch")
+	if cmds := spec.Commands(); cmds[0].Conditional || !cmds[1].Conditional {
+		t.Errorf("! Expected only conditional commands to be marked, got %+v", cmds[:2])
+	}
+}
+
+func TestConditionalParamsResolvedOnce(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	ds := &countingDatasource{values: map[string]interface{}{"n": 1}}
+	cxt.AddDatasource("counter", ds)
+	always := func(c Context, p *Params) bool { return p.Len() == 1 }
+	reg.Route("test", "Conditional commands with a datasource.").
+		Does(AddToContext, "addSeq").Using("seq").From("counter:n").When(always).
+		Does(AddToContext, "addPar").Using("par").From("counter:n").InParallel().When(always).
+		Does(AddToContext, "other").Using("other").WithDefault(true).InParallel()
+
+	if err := router.HandleRequest("test", cxt, false); err != nil {
+		t.Fatal(err)
+	}
+	if cxt.Get("seq", nil) != 1 || cxt.Get("par", nil) != 1 {
+		t.Errorf("! Expected both commands to run, got %v", cxt.AsMap())
+	}
+	if ds.reads != 2 {
+		t.Errorf("! Expected one read per command, got %d", ds.reads)
+	}
+}
+
+func TestEquals(t *testing.T) {
+	cxt := NewContext()
+	cxt.Put("count", float64(1))
+	cxt.Put("half", 1.5)
+	cxt.Put("tags", []string{"a"})
+
+	tests := []struct {
+		pred Predicate
+		want bool
+	}{
+		{Equals("count", 1), true},
+		{Equals("count", int64(1)), true},
+		{Equals("half", 1), false},
+		{Equals("tags", []string{"a"}), true},
+		{Equals("count", "1"), false},
+		{Equals("missing", nil), false},
+		{Not(Equals("count", 2)), true},
+	}
+	for i, tt := range tests {
+		if got := tt.pred(cxt, NewParams(0)); got != tt.want {
+			t.Errorf("! Test %d: expected %v, got %v", i, tt.want, got)
+		}
+	}
+}
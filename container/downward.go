@@ -0,0 +1,84 @@
+// Package container provides datasources for apps running in containers.
+package container
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DownwardDatasource provides access to a Kubernetes downward API file.
+//
+// The downward API can expose a pod's labels and annotations as files inside
+// the container. Each line of such a file has the form `key="value"`:
+//
+// 	app="cookoo"
+// 	tier="frontend"
+//
+// This datasource is both a KeyValueDatasource and a Getter, so it can be
+// used in From() clauses and with the cookoo.Get* functions:
+//
+// 	cxt.AddDatasource("labels", container.NewDownwardDatasource("/etc/podinfo/labels"))
+//
+// 	reg.Route("info", "Show info").
+// 		Does(ShowInfo, "info").
+// 			Using("tier").WithDefault("none").From("labels:tier")
+//
+// The file is read once, when the datasource is created. If the file does not
+// exist or cannot be read, the datasource is empty. This allows the same app to
+// run outside of a container.
+type DownwardDatasource struct {
+	values map[string]string
+}
+
+// NewDownwardDatasource reads a downward API file into a new datasource.
+func NewDownwardDatasource(path string) *DownwardDatasource {
+	d := &DownwardDatasource{values: map[string]string{}}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return d
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		val := strings.TrimSpace(parts[1])
+		if unquoted, err := strconv.Unquote(val); err == nil {
+			val = unquoted
+		}
+		d.values[key] = val
+	}
+	return d
+}
+
+// Value returns the value for a key, or nil if the key is not present.
+func (d *DownwardDatasource) Value(key string) interface{} {
+	if v, ok := d.values[key]; ok {
+		return v
+	}
+	return nil
+}
+
+// Get returns the value for a key, or the default value if it is not present.
+func (d *DownwardDatasource) Get(key string, defaultVal interface{}) interface{} {
+	if v, ok := d.values[key]; ok {
+		return v
+	}
+	return defaultVal
+}
+
+// Has returns the value for a key, and a flag indicating whether it was found.
+func (d *DownwardDatasource) Has(key string) (interface{}, bool) {
+	v, ok := d.values[key]
+	if !ok {
+		return nil, false
+	}
+	return v, true
+}
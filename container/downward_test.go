@@ -0,0 +1,54 @@
+package container
+
+import (
+	"github.com/Masterminds/cookoo"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownwardDatasource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookoo-downward")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	labels := `app="cookoo"
+tier="front end"
+kubernetes.io/escaped="say \"hi\""
+unquoted=plain
+not a label
+`
+	file := filepath.Join(dir, "labels")
+	if err := ioutil.WriteFile(file, []byte(labels), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ds := NewDownwardDatasource(file)
+
+	expects := map[string]string{
+		"app":                   "cookoo",
+		"tier":                  "front end",
+		"kubernetes.io/escaped": `say "hi"`,
+		"unquoted":              "plain",
+	}
+	for k, v := range expects {
+		if got := cookoo.GetString(k, "", ds); got != v {
+			t.Errorf("! Expected %s to be %q, got %q", k, v, got)
+		}
+	}
+
+	if _, ok := ds.Has("not a label"); ok {
+		t.Error("! Expected malformed lines to be skipped.")
+	}
+	if v := ds.Value("missing"); v != nil {
+		t.Errorf("! Expected nil for a missing key, got %v", v)
+	}
+
+	empty := NewDownwardDatasource(filepath.Join(dir, "no-such-file"))
+	if v := empty.Get("app", "default"); v != "default" {
+		t.Errorf("! Expected a missing file to give an empty datasource, got %v", v)
+	}
+}
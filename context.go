@@ -3,9 +3,15 @@ package cookoo
 // Copyright 2013, 1014 Masterminds
 
 import (
+	"context"
+	"fmt"
+
 	cio "github.com/Masterminds/cookoo/io"
 	"io"
 	"log"
+	"sort"
+	"sync"
+	"time"
 )
 
 // A Context is a collection of data that is associated with the current
@@ -102,8 +108,18 @@ type Context interface {
 	// Get the length of the context. This is the number of context values.
 	// Datsources are not counted.
 	Len() int
+	// Get the sorted names of the context values.
+	Keys() []string
+	// Put a value that expires after the given duration.
+	AddWithTTL(name string, value ContextValue, ttl time.Duration)
 	// Make a shallow copy of the context.
 	Copy() Context
+	// Make a deep copy of the context values.
+	DeepCopy() Context
+	// Copy context values into the `cxt` tagged fields of a struct.
+	Bind(target interface{}) error
+	// Make a child context that falls through to this one.
+	NewChild() Context
 	// Get the content (no datasources) as a map.
 	AsMap() map[string]ContextValue
 	// Get a logger.
@@ -116,6 +132,10 @@ type Context interface {
 	Log(prefix string, v ...interface{})
 	// Send a log and formatting string with a prefix.
 	Logf(prefix string, format string, v ...interface{})
+	// Get the Go context, for cancellation and deadlines.
+	GoContext() context.Context
+	// Set the Go context.
+	SetGoContext(ctx context.Context)
 }
 
 // ContextValue is an empty interface defining a context value.
@@ -129,15 +149,26 @@ type Datasource interface{}
 // ExecutionContext is the core implementation of a Context.
 //
 // An ExecutionContext is an unordered map-based context.
+//
+// An ExecutionContext is safe for concurrent use, so commands may pass it to
+// goroutines. Note that the maps returned by AsMap, GetAll, and Datasources
+// are not protected, and must not be used concurrently with writes.
 type ExecutionContext struct {
+	mutex sync.RWMutex
+
 	datasources map[string]Datasource // Datasources are things like MySQL connections.
 
 	// The Context values.
 	values map[string]ContextValue
+	// Expiration times of values added with AddWithTTL.
+	expires map[string]time.Time
 
 	loggers          io.Writer
 	loggerRegistered bool
 	skiplist         map[string]bool
+	logHandler       LogHandler
+
+	goCxt context.Context
 }
 
 // KeyValueDatasource is a datasource that can retrieve values by (string) keys.
@@ -148,6 +179,11 @@ type KeyValueDatasource interface {
 	Value(key string) interface{}
 }
 
+// ContextClock is the clock used to expire values added with AddWithTTL.
+//
+// By default, this is `time.Now`. Tests may replace it with a fake clock.
+var ContextClock = time.Now
+
 // NewContext creates a new empty cookoo.ExecutionContext and calls its Init() method.
 func NewContext() Context {
 	cxt := new(ExecutionContext).Init()
@@ -155,6 +191,24 @@ func NewContext() Context {
 }
 
 
+// WithGoContext creates a new empty cookoo.ExecutionContext that carries the
+// given Go context.
+//
+// Commands can use the Go context to notice when a request has been
+// cancelled or its deadline has passed:
+//
+// 	select {
+// 	case <-cxt.GoContext().Done():
+// 		return nil, &cookoo.FatalError{Message: "Cancelled"}
+// 	case res := <-results:
+// 		return res, nil
+// 	}
+func WithGoContext(ctx context.Context) Context {
+	cxt := new(ExecutionContext).Init()
+	cxt.goCxt = ctx
+	return cxt
+}
+
 // Init initializes a context.
 //
 // If an existing context is re-initialized, all of its associated
@@ -162,9 +216,11 @@ func NewContext() Context {
 func (cxt *ExecutionContext) Init() *ExecutionContext {
 	cxt.datasources = make(map[string]Datasource)
 	cxt.values = make(map[string]ContextValue)
+	cxt.expires = make(map[string]time.Time)
 	cxt.loggers = cio.NewMultiWriter()
 	cxt.loggerRegistered = false
 	cxt.skiplist = map[string]bool{}
+	cxt.goCxt = context.Background()
 	return cxt
 }
 
@@ -177,19 +233,56 @@ func (cxt *ExecutionContext) Add(name string, value ContextValue) {
 
 // Put inserts a value into the context.
 func (cxt *ExecutionContext) Put(name string, value ContextValue) {
+	cxt.mutex.Lock()
+	defer cxt.mutex.Unlock()
+	cxt.values[name] = value
+	delete(cxt.expires, name)
+}
+
+// AddWithTTL inserts a value into the context that expires after ttl.
+//
+// Once the value has expired, the context behaves as if it was never added.
+// Expiration is checked when values are read, using ContextClock. Putting a
+// new value under the same name removes the expiration.
+func (cxt *ExecutionContext) AddWithTTL(name string, value ContextValue, ttl time.Duration) {
+	cxt.mutex.Lock()
+	defer cxt.mutex.Unlock()
 	cxt.values[name] = value
+	cxt.expires[name] = ContextClock().Add(ttl)
+}
+
+// expired checks whether the named value has expired. The caller must hold
+// the lock.
+func (cxt *ExecutionContext) expired(name string, now time.Time) bool {
+	t, ok := cxt.expires[name]
+	return ok && !now.Before(t)
 }
 
 // AsMap returns the values of the context as a map keyed by a string.
+//
+// Expired values are removed before the map is returned.
 func (cxt *ExecutionContext) AsMap() map[string]ContextValue {
+	cxt.mutex.Lock()
+	defer cxt.mutex.Unlock()
+	if len(cxt.expires) > 0 {
+		now := ContextClock()
+		for name := range cxt.expires {
+			if cxt.expired(name, now) {
+				delete(cxt.values, name)
+				delete(cxt.expires, name)
+			}
+		}
+	}
 	return cxt.values
 }
 
 // Get retrieves a value from the context given a name. If a value does not
 // exist on the context the default is returned.
 func (cxt *ExecutionContext) Get(name string, defaultValue interface{}) ContextValue {
+	cxt.mutex.RLock()
+	defer cxt.mutex.RUnlock()
 	val, ok := cxt.values[name]
-	if !ok {
+	if !ok || cxt.expired(name, ContextClock()) {
 		return defaultValue
 	}
 	return val
@@ -197,14 +290,19 @@ func (cxt *ExecutionContext) Get(name string, defaultValue interface{}) ContextV
 
 // GetAll gets a map of all name/value pairs in the present context.
 func (cxt *ExecutionContext) GetAll() map[string]ContextValue {
-	return cxt.values
+	return cxt.AsMap()
 }
 
 // Has is a special form of Get that also returns a flag indicating if the value
 // is found. This fetches the value and also returns a flag indicating if the
 // value was found. This is useful in cases where the value may legitimately be 0.
 func (cxt *ExecutionContext) Has(name string) (value ContextValue, found bool) {
+	cxt.mutex.RLock()
+	defer cxt.mutex.RUnlock()
 	value, found = cxt.values[name]
+	if found && cxt.expired(name, ContextClock()) {
+		return nil, false
+	}
 	return
 }
 
@@ -214,6 +312,8 @@ func (cxt *ExecutionContext) Has(name string) (value ContextValue, found bool) {
 // of the variable foo that is a struct of type Foo.
 // foo = cxt.Datasource("foo").(*Foo)
 func (cxt *ExecutionContext) Datasource(name string) Datasource {
+	cxt.mutex.RLock()
+	defer cxt.mutex.RUnlock()
 	return cxt.datasources[name]
 }
 
@@ -224,6 +324,8 @@ func (cxt *ExecutionContext) Datasources() map[string]Datasource {
 
 // HasDatasource checks whether the named datasource exists, and return it if it does.
 func (cxt *ExecutionContext) HasDatasource(name string) (Datasource, bool) {
+	cxt.mutex.RLock()
+	defer cxt.mutex.RUnlock()
 	value, found := cxt.datasources[name]
 	return value, found
 }
@@ -234,11 +336,15 @@ func (cxt *ExecutionContext) HasDatasource(name string) (Datasource, bool) {
 // to the map just add it with a name. e.g. cxt.AddDatasource("mysql", foo) where
 // foo is the struct for the datasource.
 func (cxt *ExecutionContext) AddDatasource(name string, ds Datasource) {
+	cxt.mutex.Lock()
+	defer cxt.mutex.Unlock()
 	cxt.datasources[name] = ds
 }
 
 // RemoveDatasource removes a datasouce from the map of datasources.
 func (cxt *ExecutionContext) RemoveDatasource(name string) {
+	cxt.mutex.Lock()
+	defer cxt.mutex.Unlock()
 	delete(cxt.datasources, name)
 }
 
@@ -254,6 +360,9 @@ func (cxt *ExecutionContext) Logger(name string) (io.Writer, bool) {
 func (cxt *ExecutionContext) AddLogger(name string, logger io.Writer) {
 	cxt.loggers.(*cio.MultiWriter).AddWriter(name, logger)
 
+	cxt.mutex.Lock()
+	defer cxt.mutex.Unlock()
+
 	// Waiting until the first logger is attached before telling the Go log
 	// system what the output is.
 	if cxt.loggerRegistered == false {
@@ -280,15 +389,47 @@ func (cxt *ExecutionContext) RemoveLogger(name string) {
 //
 // In the above case, the subsequent call to `Logf()` is ignored.
 func (cxt *ExecutionContext) SkipLogPrefix(prefixes ...string) {
-	cxt.skiplist = make(map[string]bool, len(prefixes))
+	skiplist := make(map[string]bool, len(prefixes))
 	for _, pre := range prefixes {
-		cxt.skiplist[pre] = true
+		skiplist[pre] = true
 	}
+	cxt.mutex.Lock()
+	cxt.skiplist = skiplist
+	cxt.mutex.Unlock()
+}
+
+// skipped checks whether log messages with the given prefix are ignored.
+func (cxt *ExecutionContext) skipped(prefix string) bool {
+	cxt.mutex.RLock()
+	defer cxt.mutex.RUnlock()
+	return cxt.skiplist[prefix]
+}
+
+// LogHandler returns the context's LogHandler, or nil if it has none.
+func (cxt *ExecutionContext) LogHandler() LogHandler {
+	cxt.mutex.RLock()
+	defer cxt.mutex.RUnlock()
+	return cxt.logHandler
+}
+
+// SetLogHandler sends the context's log messages to a LogHandler, such as a
+// JSONLogger, instead of to its loggers. Setting nil goes back to the
+// loggers.
+//
+// Prefixes skipped with SkipLogPrefix are still skipped.
+func (cxt *ExecutionContext) SetLogHandler(h LogHandler) {
+	cxt.mutex.Lock()
+	defer cxt.mutex.Unlock()
+	cxt.logHandler = h
 }
 
 // Log logs a message to one of more loggers.
 func (cxt *ExecutionContext) Log(prefix string, v ...interface{}) {
-	if _, ok := cxt.skiplist[prefix]; ok {
+	if cxt.skipped(prefix) {
+		return
+	}
+	if h := cxt.LogHandler(); h != nil {
+		h.Handle(cxt, prefix, fmt.Sprint(v...))
 		return
 	}
 	tmpPrefix := log.Prefix()
@@ -299,7 +440,11 @@ func (cxt *ExecutionContext) Log(prefix string, v ...interface{}) {
 
 // Logf logs a message to one or more loggers and uses a format string.
 func (cxt *ExecutionContext) Logf(prefix string, format string, v ...interface{}) {
-	if _, ok := cxt.skiplist[prefix]; ok {
+	if cxt.skipped(prefix) {
+		return
+	}
+	if h := cxt.LogHandler(); h != nil {
+		h.Handle(cxt, prefix, fmt.Sprintf(format, v...))
 		return
 	}
 	tmpPrefix := log.Prefix()
@@ -310,27 +455,134 @@ func (cxt *ExecutionContext) Logf(prefix string, format string, v ...interface{}
 
 // Len returns the length of the context as in the length of the values stores.
 func (cxt *ExecutionContext) Len() int {
-	return len(cxt.values)
+	cxt.mutex.RLock()
+	defer cxt.mutex.RUnlock()
+	n := len(cxt.values)
+	if len(cxt.expires) > 0 {
+		now := ContextClock()
+		for name := range cxt.expires {
+			if cxt.expired(name, now) {
+				n--
+			}
+		}
+	}
+	return n
+}
+
+// NewChild creates a child of this context. See NewChildContext.
+func (cxt *ExecutionContext) NewChild() Context {
+	return NewChildContext(cxt)
+}
+
+// GoContext returns the Go context that this context carries.
+//
+// Unless the context was created with WithGoContext, this is
+// context.Background(). Copies of a context carry the same Go context.
+func (cxt *ExecutionContext) GoContext() context.Context {
+	cxt.mutex.RLock()
+	defer cxt.mutex.RUnlock()
+	return cxt.goCxt
+}
+
+// SetGoContext sets the Go context that this context carries.
+//
+// The router checks the Go context before running each command. Once it has
+// been cancelled, or its deadline has passed, no more commands are run.
+func (cxt *ExecutionContext) SetGoContext(ctx context.Context) {
+	cxt.mutex.Lock()
+	defer cxt.mutex.Unlock()
+	cxt.goCxt = ctx
+}
+
+// Keys returns the names of the values in the context, in sorted order.
+func (cxt *ExecutionContext) Keys() []string {
+	cxt.mutex.RLock()
+	now := ContextClock()
+	keys := make([]string, 0, len(cxt.values))
+	for k := range cxt.values {
+		if !cxt.expired(k, now) {
+			keys = append(keys, k)
+		}
+	}
+	cxt.mutex.RUnlock()
+
+	sort.Strings(keys)
+	return keys
+}
+
+// MarshalJSON encodes the context as JSON for debugging. See DumpContext.
+func (cxt *ExecutionContext) MarshalJSON() ([]byte, error) {
+	return marshalContext(cxt)
 }
 
 // Copy the context into a new context.
+//
+// The context is read-locked while it is copied. Values added with
+// AddWithTTL expire in the copy at the same time as in the original.
 func (cxt *ExecutionContext) Copy() Context {
-	newCxt := NewContext()
-	vals := cxt.GetAll()
-	ds := cxt.Datasources()
+	return cxt.copyValues(func(v interface{}) interface{} { return v })
+}
 
-	for k, v := range vals {
-		newCxt.Put(k, v)
+// copyValues copies the context, passing each value through clone.
+func (cxt *ExecutionContext) copyValues(clone func(interface{}) interface{}) Context {
+	newEC := new(ExecutionContext).Init()
+
+	cxt.mutex.RLock()
+	defer cxt.mutex.RUnlock()
+
+	now := ContextClock()
+	for k, v := range cxt.values {
+		if cxt.expired(k, now) {
+			continue
+		}
+		newEC.values[k] = clone(v)
+		if t, ok := cxt.expires[k]; ok {
+			newEC.expires[k] = t
+		}
 	}
 
-	for k, datasource := range ds {
-		newCxt.AddDatasource(k, datasource)
+	for k, datasource := range cxt.datasources {
+		newEC.datasources[k] = datasource
 	}
 
-	newEC := newCxt.(*ExecutionContext)
 	newEC.loggers = cxt.loggers 
 	newEC.skiplist = cxt.skiplist
 	newEC.loggerRegistered = cxt.loggerRegistered
+	newEC.goCxt = cxt.goCxt
+	newEC.logHandler = cxt.logHandler
+
+	return newEC
+}
 
-	return newCxt
+// DeepCopy copies the context into a new context, recursively cloning values.
+//
+// Maps, slices, arrays, pointers, and the exported fields of structs are
+// copied, so changes made to values in one context are not seen in the
+// other. Unexported struct fields are copied shallowly, as they would be by
+// assignment. Values that cannot be cloned, such as channels and functions,
+// are shared by reference.
+//
+// Values that implement Cloneable are copied by calling their Clone method.
+// This gives types with unexported state a way to copy themselves properly.
+//
+// Datasources and loggers are not cloned. They are shared, as they are by
+// Copy.
+func (cxt *ExecutionContext) DeepCopy() Context {
+	return cxt.copyValues(deepCopyValue)
+}
+
+// Bind copies context values into the `cxt` tagged fields of a struct.
+//
+// This works like Params.Bind, including type conversion and required
+// fields, but it reads from the context:
+//
+// 	var user struct {
+// 		ID   int    `cxt:"user.ID,required"`
+// 		Name string `cxt:"user.Name"`
+// 	}
+// 	if err := cxt.Bind(&user); err != nil {
+// 		return nil, &cookoo.FatalError{Message: err.Error()}
+// 	}
+func (cxt *ExecutionContext) Bind(target interface{}) error {
+	return bindTagged(GettableCxt(cxt), target, "cxt", true)
 }
@@ -5,11 +5,15 @@ package cookoo
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"log"
 	"reflect"
 	"regexp"
 	"runtime"
+	"sync"
 	"testing"
+	"time"
 )
 
 // An example datasource as can add to our store.
@@ -129,6 +133,223 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+type DeepStruct struct {
+	Stuff  []string
+	Counts map[string]int
+	Next   *DeepStruct
+	Events chan string
+}
+
+func TestDeepCopy(t *testing.T) {
+	deep := &DeepStruct{
+		Stuff:  []string{"O", "Hai"},
+		Counts: map[string]int{"a": 1},
+		Events: make(chan string),
+	}
+	deep.Next = deep
+	c := NewContext()
+	c.Put("a", deep)
+	c.Put("b", []interface{}{"This", "is", []int{1, 2}})
+
+	foo := new(ExampleDatasource)
+	foo.name = "bar"
+	c.AddDatasource("foo", foo)
+
+	c2 := c.DeepCopy()
+
+	c.Put("c", 1234)
+	if c2.Len() != 2 {
+		t.Error("! c2 should be 2.")
+	}
+
+	deep.Stuff[1] = "Noes"
+	deep.Counts["a"] = 2
+	c.Get("b", nil).([]interface{})[2].([]int)[0] = 99
+
+	v1 := c2.Get("a", nil).(*DeepStruct)
+	if v1 == deep {
+		t.Fatal("! Expected the pointer to be copied.")
+	}
+	if v1.Stuff[1] != "Hai" {
+		t.Error("! Expected deep copy of slice. Got ", v1.Stuff)
+	}
+	if v1.Counts["a"] != 1 {
+		t.Error("! Expected deep copy of map. Got ", v1.Counts)
+	}
+	if v1.Next != v1 {
+		t.Error("! Expected the cycle to point to the copy.")
+	}
+	if v1.Events != deep.Events {
+		t.Error("! Expected the channel to be shared.")
+	}
+	if v := c2.Get("b", nil).([]interface{})[2].([]int)[0]; v != 1 {
+		t.Errorf("! Expected nested slice to be copied, got %d", v)
+	}
+
+	if ds, _ := c2.HasDatasource("foo"); ds != foo {
+		t.Error("! Expected datasources to be shared.")
+	}
+	if _, ok := SyncContext(c).DeepCopy().Get("a", nil).(*DeepStruct); !ok {
+		t.Error("! Expected SyncContext to deep copy.")
+	}
+}
+
+type cloneableList struct {
+	items  []string
+	Clones *int
+}
+
+func (c cloneableList) Clone() interface{} {
+	*c.Clones++
+	return cloneableList{append([]string{}, c.items...), c.Clones}
+}
+
+func TestDeepCopyCloneable(t *testing.T) {
+	clones := 0
+	list := cloneableList{[]string{"a", "b"}, &clones}
+	c := NewContext()
+	c.Put("list", list)
+	c.Put("nested", map[string]interface{}{"list": list})
+
+	c2 := c.DeepCopy()
+	list.items[0] = "z"
+
+	if clones != 2 {
+		t.Errorf("! Expected Clone to be called twice, got %d", clones)
+	}
+	if v := c2.Get("list", nil).(cloneableList).items[0]; v != "a" {
+		t.Errorf("! Expected the clone to keep its own items, got %s", v)
+	}
+	if v := c2.Get("nested", nil).(map[string]interface{})["list"].(cloneableList).items[0]; v != "a" {
+		t.Errorf("! Expected the nested clone to keep its own items, got %s", v)
+	}
+}
+
+func TestAddWithTTL(t *testing.T) {
+	now := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+	ContextClock = func() time.Time { return now }
+	defer func() { ContextClock = time.Now }()
+
+	c := NewContext()
+	c.AddWithTTL("token", "abc123", time.Minute)
+	c.Put("forever", true)
+	c2 := c.Copy()
+	c3 := c.DeepCopy()
+
+	now = now.Add(30 * time.Second)
+	if v := c.Get("token", nil); v != "abc123" {
+		t.Errorf("! Expected the token before expiry, got %v", v)
+	}
+	if _, ok := c2.Has("token"); !ok {
+		t.Error("! Expected the copy to have the token before expiry.")
+	}
+	if c.Len() != 2 {
+		t.Errorf("! Expected 2 values, got %d", c.Len())
+	}
+
+	now = now.Add(30 * time.Second)
+	if v, ok := c.Has("token"); ok || v != nil {
+		t.Errorf("! Expected the token to have expired, got %v", v)
+	}
+	if v := c.Get("token", "default"); v != "default" {
+		t.Errorf("! Expected the default after expiry, got %v", v)
+	}
+	v, src := GetFromFirst("token", "default", GettableCxt(c))
+	if _, ok := src.(*DefaultGetter); !ok || v != "default" {
+		t.Errorf("! Expected GetFromFirst to use the default, got %v from %T", v, src)
+	}
+	for _, cp := range []Context{c2, c3} {
+		if _, ok := cp.Has("token"); ok {
+			t.Error("! Expected the token to expire in the copy at the same time.")
+		}
+	}
+	if c.Len() != 1 || !reflect.DeepEqual(c.Keys(), []string{"forever"}) {
+		t.Errorf("! Expected only 'forever' to remain, got %v", c.Keys())
+	}
+	if _, ok := c.AsMap()["token"]; ok {
+		t.Error("! Expected AsMap to drop expired values.")
+	}
+
+	// Put removes the expiration.
+	c.AddWithTTL("token", "def456", time.Second)
+	c.Put("token", "ghi789")
+	now = now.Add(time.Hour)
+	if v := c.Get("token", nil); v != "ghi789" {
+		t.Errorf("! Expected Put to clear the TTL, got %v", v)
+	}
+}
+
+func TestConcurrentContext(t *testing.T) {
+	c := NewContext()
+	c.AddDatasource("foo", new(ExampleDatasource))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key%d", i)
+			for j := 0; j < 100; j++ {
+				c.Put(key, j)
+				c.Put("shared", i)
+				c.Get("shared", nil)
+				c.Has(key)
+				c.Len()
+				c.Datasource("foo")
+				c.AddDatasource(key, new(ExampleDatasource))
+				c.RemoveDatasource(key)
+				c.Copy()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if c.Len() != 51 {
+		t.Errorf("! Expected 51 values, got %d", c.Len())
+	}
+	if v := c.Get("key7", nil); v != 99 {
+		t.Errorf("! Expected key7 to be 99, got %v", v)
+	}
+}
+
+func TestGoContext(t *testing.T) {
+	if NewContext().GoContext() != context.Background() {
+		t.Error("! Expected a background context by default.")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cxt := WithGoContext(ctx)
+	if cxt.Copy().GoContext() != ctx || cxt.DeepCopy().GoContext() != ctx {
+		t.Error("! Expected copies to carry the Go context.")
+	}
+	if SyncContext(cxt).GoContext() != ctx || ReadOnlyContext(cxt).GoContext() != ctx {
+		t.Error("! Expected wrappers to pass through the Go context.")
+	}
+
+	reg, router, _ := Cookoo()
+	reg.Route("loop", "Loop until cancelled.").
+		Does(Command(func(c Context, p *Params) (interface{}, Interrupt) {
+			for i := 0; i < 1000; i++ {
+				select {
+				case <-c.GoContext().Done():
+					return i, nil
+				default:
+				}
+				if i == 10 {
+					cancel()
+				}
+			}
+			return -1, nil
+		}), "iterations")
+
+	if e := router.HandleRequest("loop", cxt, false); e != nil {
+		t.Fatalf("! Unexpected error: %s", e)
+	}
+	if v := cxt.Get("iterations", nil); v != 11 {
+		t.Errorf("! Expected the command to stop after cancellation, got %v", v)
+	}
+}
+
 func TestLogging(t *testing.T) {
 	logger := new(bytes.Buffer)
 	c := NewContext()
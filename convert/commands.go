@@ -8,7 +8,9 @@ This package provides conversions that can be used in chains of commands.
 */
 
 import (
+	"fmt"
 	"github.com/Masterminds/cookoo"
+	"math"
 	"strconv"
 )
 
@@ -23,3 +25,109 @@ func Atoi(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
 	src := p.Get("str", "0").(string)
 	return strconv.Atoi(src)
 }
+
+// Cast converts a value to another type.
+//
+// Supported types are "int", "int64", "float64", "bool", and "string". The
+// source value may be a string (which will be parsed), or any of the
+// supported types. As with cookoo.GetAs, numbers are only converted if no
+// information is lost, so 2.0 can be cast to an int, but 2.7 cannot.
+//
+// This makes type conversions explicit in a route:
+//
+// 	reg.Route("GET /page", "Show a page").
+// 		Does(convert.Cast, "page").
+// 			Using("value").From("query:page").
+// 			Using("type").WithDefault("int")
+//
+// Params:
+// 	- value: The value to convert. This is required.
+// 	- type (string): The type to convert to. This is required.
+//
+// Returns:
+// 	- The converted value.
+//
+// If the value cannot be converted, a FatalError is returned.
+func Cast(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	val, ok := p.Has("value")
+	if !ok {
+		return nil, &cookoo.FatalError{Message: "Expected a 'value'"}
+	}
+	to, ok := cookoo.HasString("type", p)
+	if !ok {
+		return nil, &cookoo.FatalError{Message: "Expected a 'type'"}
+	}
+
+	res, err := cast(val, to)
+	if err != nil {
+		return nil, &cookoo.FatalError{Message: err.Error()}
+	}
+	return res, nil
+}
+
+func cast(val interface{}, to string) (interface{}, error) {
+	// Strings are parsed.
+	if str, ok := val.(string); ok {
+		switch to {
+		case "int":
+			return strconv.Atoi(str)
+		case "int64":
+			return strconv.ParseInt(str, 10, 64)
+		case "float64":
+			return strconv.ParseFloat(str, 64)
+		case "bool":
+			return strconv.ParseBool(str)
+		case "string":
+			return str, nil
+		}
+		return nil, fmt.Errorf("Cannot cast to unknown type '%s'", to)
+	}
+
+	switch v := val.(type) {
+	case int:
+		return castInt(int64(v), to)
+	case int64:
+		return castInt(v, to)
+	case float64:
+		switch to {
+		case "int", "int64":
+			// 2^63 is the first float64 too large for an int64.
+			if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+				return nil, fmt.Errorf("Cannot cast %v to %s without losing information", v, to)
+			}
+			return castInt(int64(v), to)
+		case "float64":
+			return v, nil
+		case "string":
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+	case bool:
+		switch to {
+		case "bool":
+			return v, nil
+		case "string":
+			return strconv.FormatBool(v), nil
+		}
+	}
+	return nil, fmt.Errorf("Cannot cast %T to %s", val, to)
+}
+
+func castInt(v int64, to string) (interface{}, error) {
+	switch to {
+	case "int":
+		if int64(int(v)) != v {
+			return nil, fmt.Errorf("Cannot cast %d to an int without losing information", v)
+		}
+		return int(v), nil
+	case "int64":
+		return v, nil
+	case "float64":
+		if f := float64(v); f < math.MaxInt64 && int64(f) == v {
+			return f, nil
+		}
+		return nil, fmt.Errorf("Cannot cast %d to a float64 without losing information", v)
+	case "string":
+		return strconv.FormatInt(v, 10), nil
+	}
+	return nil, fmt.Errorf("Cannot cast an integer to %s", to)
+}
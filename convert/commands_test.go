@@ -2,6 +2,7 @@ package convert
 
 import (
 	"github.com/Masterminds/cookoo"
+	"math"
 	"testing"
 )
 
@@ -24,3 +25,53 @@ func TestAtoi(t *testing.T) {
 		t.Errorf("! Expected '100' to be converted to 100. Got %d", i)
 	}
 }
+
+func TestCast(t *testing.T) {
+	reg, router, c := cookoo.Cookoo()
+	reg.Route("test", "Test cast.").
+		Does(Cast, "i").Using("value").From("cxt:a").Using("type").WithDefault("int").
+		Does(Cast, "s").Using("value").From("cxt:b").Using("type").WithDefault("string").
+		Does(Cast, "f").Using("value").From("cxt:b").Using("type").WithDefault("float64").
+		Route("fail", "Test a failing cast.").
+		Does(Cast, "x").Using("value").From("cxt:c").Using("type").WithDefault("int")
+
+	c.Put("a", "100")
+	c.Put("b", 42)
+	c.Put("c", "abc")
+	if e := router.HandleRequest("test", c, false); e != nil {
+		t.Errorf("! Failed during HandleRequest: %s", e)
+		return
+	}
+
+	if i, ok := c.Get("i", nil).(int); !ok || i != 100 {
+		t.Errorf("! Expected '100' to be converted to 100. Got %v", c.Get("i", nil))
+	}
+	if s, ok := c.Get("s", nil).(string); !ok || s != "42" {
+		t.Errorf("! Expected 42 to be converted to '42'. Got %v", c.Get("s", nil))
+	}
+	if f, ok := c.Get("f", nil).(float64); !ok || f != 42.0 {
+		t.Errorf("! Expected 42 to be converted to 42.0. Got %v", c.Get("f", nil))
+	}
+
+	e := router.HandleRequest("fail", c, false)
+	if e == nil {
+		t.Error("! Expected 'abc' to fail to convert to an int.")
+	} else if _, ok := e.(*cookoo.FatalError); !ok {
+		t.Errorf("! Expected a FatalError, got %T", e)
+	}
+
+	// Only lossless numeric conversions are allowed.
+	c.Put("c", 2.0)
+	if e := router.HandleRequest("fail", c, false); e != nil || c.Get("x", nil) != 2 {
+		t.Errorf("! Expected 2.0 to be converted to 2. Got %v (%v)", c.Get("x", nil), e)
+	}
+	for _, v := range []interface{}{2.7, math.Inf(1), 1e19} {
+		c.Put("c", v)
+		if e := router.HandleRequest("fail", c, false); e == nil {
+			t.Errorf("! Expected %v to fail to convert to an int.", v)
+		}
+	}
+	if _, err := cast(int64(1<<53+1), "float64"); err == nil {
+		t.Error("! Expected 2^53+1 to fail to convert to a float64.")
+	}
+}
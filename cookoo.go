@@ -53,17 +53,20 @@
 //
 // Interrupts
 //
-// There are four types of interrupts that you may wish to return:
+// There are five types of interrupts that you may wish to return:
 //
 // 	1. FatalError: This will stop the route immediately.
 // 	2. RecoverableError: This will allow the route to continue moving.
 // 	3. Stop: This will stop the current request, but not as an error.
 // 	4. Reroute: This will stop executing the current route, and switch to executing another route.
+// 	5. Retry: This will run the current command again, after a delay.
 //
 // To learn how to write Cookoo applications, you may wish to examine
 // the small Skunk application: https://github.com/technosophos/skunk.
 package cookoo
 
+import "time"
+
 // VERSION provides the current version of Cookoo.
 const VERSION = "1.3.0"
 
@@ -99,6 +102,7 @@ type Command func(cxt Context, params *Params) (interface{}, Interrupt)
 // - A FatalError, which will stop processing.
 // - A RecoverableError, which will continue the chain.
 // - A Reroute, which will cause a different route to be run.
+// - A Retry, which will cause the command to be run again.
 type Interrupt interface{}
 
 // Creates a new Reroute.
@@ -130,6 +134,59 @@ func (rr *Reroute) RouteTo() string {
 // given route. However, it will not emit an error, either.
 type Stop struct{}
 
+// Retry tells the router to run the current command again.
+//
+// When Cookoo encounters a `Retry`, it waits for the delay and then runs the
+// command again. The command's params are resolved again for each attempt,
+// so a param that comes `From` the context sees the context's current value.
+// If the command has already been retried Max times, the route fails with a
+// FatalError instead.
+//
+// While a command runs, `command.Attempt` holds the number of its current
+// attempt, starting at 1, so a command is being retried if the number is
+// greater than 1. Once a command that asked to be retried finishes, the
+// number of attempts it took is stored under the command's name followed by
+// ".Attempts" (for example, `fetch.Attempts`).
+//
+// 	func Fetch(c Context, p *Params) (interface{}, Interrupt) {
+// 		res, err := http.Get(p.Get("url", "").(string))
+// 		if err != nil {
+// 			return nil, &Retry{Message: err.Error(), Max: 3, Delay: time.Second, Exponential: true}
+// 		}
+// 		return res, nil
+// 	}
+type Retry struct {
+	// Message says why the command should be retried.
+	Message string
+	// Max is the most times to retry the command.
+	Max int
+	// Delay is how long to wait before retrying.
+	Delay time.Duration
+	// Exponential doubles the delay after each retry.
+	Exponential bool
+	// MaxDelay, if set, is the longest time to wait between retries.
+	MaxDelay time.Duration
+}
+
+// Error returns the reason for the retry.
+func (r *Retry) Error() string {
+	return r.Message
+}
+
+// delay returns how long to wait before the given retry, starting at 1.
+func (r *Retry) delay(retry int) time.Duration {
+	d := r.Delay
+	if r.Exponential {
+		for i := 1; i < retry && d*2 > d; i++ {
+			d *= 2
+		}
+	}
+	if r.MaxDelay > 0 && d > r.MaxDelay {
+		d = r.MaxDelay
+	}
+	return d
+}
+
 // RecoverableError is an error that should not cause the router to stop processing.
 //
 // When Cookoo encounters a `RecoverableError`, it will log the error as a
@@ -0,0 +1,235 @@
+/* Package cookootest provides helpers for testing Cookoo routes and commands.
+
+A command can be tested on its own with RunCommand:
+
+	cxt, log := cookootest.NewContext(map[string]interface{}{"name": "Matt"}, nil)
+	res, irq := cookootest.RunCommand(Greet, cxt, map[string]interface{}{"greeting": "Hi"})
+	if irq != nil || res != "Hi, Matt" {
+		t.Errorf("Unexpected result %v: %v", res, irq)
+	}
+	if !log.Contains("info", "Greeted Matt") {
+		t.Errorf("Expected a log message, got %v", log.Messages("info"))
+	}
+
+A whole route can be run against a registry with RunRoute, which returns the
+final context along with every interrupt the route's commands returned:
+
+	users := cookootest.NewMockDatasource(map[string]interface{}{"1": user})
+	cxt, _ := cookootest.NewContext(nil, map[string]cookoo.Datasource{"users": users})
+	result := cookootest.RunRoute(reg, "GET /user", cxt)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if result.Context.Get("user", nil) != user {
+		t.Error("Expected the user to be loaded.")
+	}
+*/
+package cookootest
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// NewContext creates a context for a test.
+//
+// The context is filled with the given values and datasources, either of
+// which may be nil. Its log messages are recorded by the returned Logger
+// instead of being written out.
+func NewContext(values map[string]interface{}, datasources map[string]cookoo.Datasource) (cookoo.Context, *Logger) {
+	cxt := cookoo.NewContext()
+	for k, v := range values {
+		cxt.Put(k, v)
+	}
+	for name, ds := range datasources {
+		cxt.AddDatasource(name, ds)
+	}
+	log := NewLogger()
+	cxt.(cookoo.LogHandlerContext).SetLogHandler(log)
+	return cxt, log
+}
+
+// LogEntry is a log message recorded by a Logger.
+type LogEntry struct {
+	Level   string
+	Message string
+}
+
+// Logger is a cookoo.LogHandler that records log messages.
+type Logger struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// NewLogger creates a new Logger.
+//
+// Use it with a context's SetLogHandler, or with cookoo.LogWith. NewContext
+// does this for you.
+func NewLogger() *Logger {
+	return &Logger{}
+}
+
+// Handle records a log message.
+func (l *Logger) Handle(cxt cookoo.Context, level, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, LogEntry{level, msg})
+}
+
+// Entries returns the recorded log messages, in the order they were logged.
+func (l *Logger) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LogEntry(nil), l.entries...)
+}
+
+// Messages returns the recorded messages with the given level.
+func (l *Logger) Messages(level string) []string {
+	msgs := []string{}
+	for _, e := range l.Entries() {
+		if e.Level == level {
+			msgs = append(msgs, e.Message)
+		}
+	}
+	return msgs
+}
+
+// Contains checks whether a message with the given level contains substr.
+func (l *Logger) Contains(level, substr string) bool {
+	for _, msg := range l.Messages(level) {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset forgets the recorded log messages.
+func (l *Logger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+// MockDatasource is a KeyValueDatasource that holds its values in memory, and
+// records the keys that are read from it.
+//
+// It is also a cookoo.Getter, so the Get* and Has* helpers work with it.
+type MockDatasource struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+	reads  []string
+}
+
+// NewMockDatasource creates a new MockDatasource with the given values.
+func NewMockDatasource(values map[string]interface{}) *MockDatasource {
+	m := &MockDatasource{values: map[string]interface{}{}}
+	for k, v := range values {
+		m.values[k] = v
+	}
+	return m
+}
+
+// Value gets the value for a key, or nil if it is not set.
+func (m *MockDatasource) Value(key string) interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads = append(m.reads, key)
+	return m.values[key]
+}
+
+// Get gets the value for a key, or the default value.
+func (m *MockDatasource) Get(key string, defaultVal interface{}) interface{} {
+	return cookoo.DatasourceGet(m, key, defaultVal)
+}
+
+// Has gets the value for a key, and whether it is set.
+func (m *MockDatasource) Has(key string) (interface{}, bool) {
+	return cookoo.DatasourceHas(m, key)
+}
+
+// Set sets the value for a key.
+func (m *MockDatasource) Set(key string, value interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+}
+
+// Reads returns the keys that have been read, in order.
+func (m *MockDatasource) Reads() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.reads...)
+}
+
+// Interruption is an interrupt returned by a command.
+type Interruption struct {
+	Route     string
+	Command   string
+	Interrupt cookoo.Interrupt
+}
+
+// Result is the outcome of RunRoute.
+type Result struct {
+	// Context is the context after the route has run.
+	Context cookoo.Context
+	// Err is the error returned by HandleRequest.
+	Err error
+	// Interrupts holds every interrupt returned by a command, in order. A
+	// command that is retried may appear more than once.
+	Interrupts []Interruption
+}
+
+// Interrupt returns the last interrupt returned by the named command, or nil
+// if it returned none.
+func (r *Result) Interrupt(command string) cookoo.Interrupt {
+	for i := len(r.Interrupts) - 1; i >= 0; i-- {
+		if r.Interrupts[i].Command == command {
+			return r.Interrupts[i].Interrupt
+		}
+	}
+	return nil
+}
+
+// RunRoute runs a route from a registry, and returns the result.
+//
+// The route is run by a new router, so hooks and middleware added to other
+// routers are not run. If cxt is nil, a context from NewContext is used.
+func RunRoute(reg *cookoo.Registry, route string, cxt cookoo.Context) *Result {
+	if cxt == nil {
+		cxt, _ = NewContext(nil, nil)
+	}
+	result := &Result{Context: cxt}
+
+	var mu sync.Mutex
+	router := cookoo.NewRouter(reg)
+	router.UseCommand(func(route, name string, next cookoo.Command) cookoo.Command {
+		return func(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			res, irq := next(cxt, params)
+			if irq != nil {
+				mu.Lock()
+				result.Interrupts = append(result.Interrupts, Interruption{route, name, irq})
+				mu.Unlock()
+			}
+			return res, irq
+		}
+	})
+	result.Err = router.HandleRequest(route, cxt, false)
+	return result
+}
+
+// RunCommand runs a single command with the given params.
+//
+// The params are passed to the command as they are, without any defaults or
+// From sources. If cxt is nil, a context from NewContext is used.
+func RunCommand(cmd cookoo.Command, cxt cookoo.Context, params map[string]interface{}) (interface{}, cookoo.Interrupt) {
+	if cxt == nil {
+		cxt, _ = NewContext(nil, nil)
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return cmd(cxt, cookoo.NewParamsWithValues(params))
+}
@@ -0,0 +1,75 @@
+package cookootest
+
+import (
+	"testing"
+
+	"github.com/Masterminds/cookoo"
+)
+
+func greet(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	name := cxt.Get("name", "nobody").(string)
+	cxt.Logf("info", "Greeted %s", name)
+	return params.Get("greeting", "Hello").(string) + ", " + name, nil
+}
+
+func TestRunCommand(t *testing.T) {
+	cxt, log := NewContext(map[string]interface{}{"name": "Matt"}, nil)
+	res, irq := RunCommand(greet, cxt, map[string]interface{}{"greeting": "Hi"})
+	if irq != nil || res != "Hi, Matt" {
+		t.Errorf("! Unexpected result %v: %v", res, irq)
+	}
+	if !log.Contains("info", "Greeted Matt") || len(log.Entries()) != 1 {
+		t.Errorf("! Expected the log message to be recorded, got %v", log.Entries())
+	}
+	log.Reset()
+	if len(log.Messages("info")) != 0 {
+		t.Error("! Expected Reset to clear the log.")
+	}
+
+	if res, _ := RunCommand(greet, nil, nil); res != "Hello, nobody" {
+		t.Errorf("! Expected defaults without a context, got %v", res)
+	}
+}
+
+func TestRunRoute(t *testing.T) {
+	users := NewMockDatasource(map[string]interface{}{"name": "Matt"})
+	cxt, _ := NewContext(nil, map[string]cookoo.Datasource{"users": users})
+
+	reg := cookoo.NewRegistry()
+	reg.Route("greet", "Greet a user.").
+		Does(cookoo.AddToContext, "add").
+		Using("name").From("users:name").
+		Does(greet, "greeting").
+		Does(cookoo.Command(func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			return nil, &cookoo.RecoverableError{Message: "Not important"}
+		}), "flaky").
+		Does(cookoo.Command(func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			return nil, &cookoo.FatalError{Message: "Broken"}
+		}), "broken").
+		Route("hello", "Greet nobody.").
+		Does(greet, "greeting")
+
+	result := RunRoute(reg, "greet", cxt)
+	if result.Err == nil {
+		t.Error("! Expected the route to fail.")
+	}
+	if v := result.Context.Get("greeting", nil); v != "Hello, Matt" {
+		t.Errorf("! Expected a greeting, got %v", v)
+	}
+	if len(result.Interrupts) != 2 || result.Interrupts[0].Command != "flaky" || result.Interrupts[1].Route != "greet" {
+		t.Errorf("! Unexpected interrupts: %+v", result.Interrupts)
+	}
+	if _, ok := result.Interrupt("broken").(*cookoo.FatalError); !ok {
+		t.Errorf("! Expected a fatal error from broken, got %v", result.Interrupt("broken"))
+	}
+	if result.Interrupt("greeting") != nil {
+		t.Error("! Expected no interrupt from greeting.")
+	}
+	if reads := users.Reads(); len(reads) != 1 || reads[0] != "name" {
+		t.Errorf("! Unexpected reads: %v", reads)
+	}
+
+	if result := RunRoute(reg, "hello", nil); result.Context.Get("greeting", nil) != "Hello, nobody" {
+		t.Errorf("! Expected a fresh context, got %v", result.Context.Get("greeting", nil))
+	}
+}
@@ -0,0 +1,291 @@
+// Redis datasource for Cookoo.
+//
+// This provides a KeyValueDatasource backed by Redis, so that values such as
+// sessions and cached results can be shared by several instances of an
+// application. It speaks the Redis protocol directly, and has no
+// dependencies outside of the standard library.
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// ErrClosed is returned when a closed Datasource is used.
+var ErrClosed = errors.New("redis: datasource is closed")
+
+// Datasource is a Redis-backed KeyValueDatasource.
+//
+// It is also a cookoo.Getter, so the Get* and Has* helpers work with it.
+// Values are stored in Redis as strings, and come back out as strings.
+//
+// Every key is prefixed with Prefix, so several applications can share a
+// Redis database. Connections are pooled, and are safe to use from many
+// goroutines.
+//
+// Example:
+//
+// 	ds := redis.NewDatasource("localhost:6379", "myapp:")
+// 	cxt.AddDatasource("redis", ds)
+//
+// 	reg.Route("GET /", "Home page").
+// 		Does(ShowHome, "home").
+// 			Using("motd").WithDefault("Welcome!").From("redis:motd")
+type Datasource struct {
+	// Addr is the host:port of the Redis server.
+	Addr string
+	// Prefix is added to the beginning of every key.
+	Prefix string
+	// Password, if set, is sent with AUTH on each new connection.
+	Password string
+	// DB, if set, is selected on each new connection.
+	DB int
+	// Timeout limits how long to wait when connecting, reading, or writing.
+	Timeout time.Duration
+	// MaxIdle is the most connections to keep open while they are not in use.
+	MaxIdle int
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+// NewDatasource creates a new Redis datasource.
+//
+// No connection is made until the datasource is used.
+func NewDatasource(addr, prefix string) *Datasource {
+	return &Datasource{
+		Addr:    addr,
+		Prefix:  prefix,
+		Timeout: 5 * time.Second,
+		MaxIdle: 10,
+	}
+}
+
+// Value gets the value for a key, or nil if it is not set.
+//
+// Errors talking to Redis also return nil. Use Lookup to see them.
+func (d *Datasource) Value(key string) interface{} {
+	v, ok, err := d.Lookup(key)
+	if err != nil || !ok {
+		return nil
+	}
+	return v
+}
+
+// Get gets the value for a key, or the default value. See Value.
+func (d *Datasource) Get(key string, defaultVal interface{}) interface{} {
+	return cookoo.DatasourceGet(d, key, defaultVal)
+}
+
+// Has gets the value for a key, and whether it is set. See Value.
+func (d *Datasource) Has(key string) (interface{}, bool) {
+	return cookoo.DatasourceHas(d, key)
+}
+
+// Lookup gets the value for a key, and whether it is set.
+func (d *Datasource) Lookup(key string) (string, bool, error) {
+	res, err := d.do("GET", d.Prefix+key)
+	if err != nil || res == nil {
+		return "", false, err
+	}
+	return res.(string), true, nil
+}
+
+// Set sets the value for a key.
+//
+// Strings and byte slices are stored as they are. Other values are
+// formatted with fmt.Sprint. If ttl is greater than zero, the key expires
+// after that long. Redis keeps TTLs to the millisecond.
+func (d *Datasource) Set(key string, value interface{}, ttl time.Duration) error {
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		str = fmt.Sprint(v)
+	}
+
+	args := []string{"SET", d.Prefix + key, str}
+	if ttl > 0 {
+		ms := int64(ttl / time.Millisecond)
+		if ms < 1 {
+			ms = 1
+		}
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	_, err := d.do(args...)
+	return err
+}
+
+// Delete removes keys.
+func (d *Datasource) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := []string{"DEL"}
+	for _, k := range keys {
+		args = append(args, d.Prefix+k)
+	}
+	_, err := d.do(args...)
+	return err
+}
+
+// Close closes all idle connections. The datasource cannot be used after it
+// is closed.
+func (d *Datasource) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	for _, c := range d.idle {
+		c.Close()
+	}
+	d.idle = nil
+	return nil
+}
+
+// do runs a command on a pooled connection.
+func (d *Datasource) do(args ...string) (interface{}, error) {
+	c, err := d.get()
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.do(d.Timeout, args...)
+	d.put(c, err)
+	return res, err
+}
+
+// get takes an idle connection from the pool, or opens a new one.
+func (d *Datasource) get() (*conn, error) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if n := len(d.idle); n > 0 {
+		c := d.idle[n-1]
+		d.idle = d.idle[:n-1]
+		d.mu.Unlock()
+		return c, nil
+	}
+	d.mu.Unlock()
+
+	nc, err := net.DialTimeout("tcp", d.Addr, d.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{Conn: nc, r: bufio.NewReader(nc)}
+	if d.Password != "" {
+		if _, err := c.do(d.Timeout, "AUTH", d.Password); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if d.DB != 0 {
+		if _, err := c.do(d.Timeout, "SELECT", strconv.Itoa(d.DB)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// put returns a connection to the pool. Connections that failed, other than
+// with an error reply from Redis, are closed instead.
+func (d *Datasource) put(c *conn, err error) {
+	_, isReply := err.(Error)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if (err != nil && !isReply) || d.closed || len(d.idle) >= d.MaxIdle {
+		c.Close()
+		return
+	}
+	d.idle = append(d.idle, c)
+}
+
+// Error is an error reply from Redis.
+type Error string
+
+// Error returns the message from Redis.
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// conn is a single connection to Redis.
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// do sends a command and reads its reply.
+func (c *conn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	if timeout > 0 {
+		c.SetDeadline(time.Now().Add(timeout))
+	}
+
+	buf := []byte(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, a := range args {
+		buf = append(buf, fmt.Sprintf("$%d\r\n%s\r\n", len(a), a)...)
+	}
+	if _, err := c.Write(buf); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read reads a reply. Bulk strings are returned as strings, integers as
+// int64, arrays as []interface{}, and nil replies as nil.
+func (c *conn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, Error(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				if _, ok := err.(Error); !ok {
+					return nil, err
+				}
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
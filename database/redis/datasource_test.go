@@ -0,0 +1,209 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// fakeRedis is a tiny Redis server that supports GET, SET, DEL, and AUTH.
+type fakeRedis struct {
+	net.Listener
+	mu    sync.Mutex
+	data  map[string]string
+	ttls  map[string]string
+	conns int
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen: %s", err)
+	}
+	f := &fakeRedis{Listener: l, data: map[string]string{}, ttls: map[string]string{}}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns++
+			f.mu.Unlock()
+			go f.serve(c)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] == "secret" {
+				reply = "+OK\r\n"
+			} else {
+				reply = "-ERR invalid password\r\n"
+			}
+		case "GET":
+			if v, ok := f.data[args[1]]; ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case "SET":
+			f.data[args[1]] = args[2]
+			if len(args) == 5 {
+				f.ttls[args[1]] = args[3] + " " + args[4]
+			}
+			reply = "+OK\r\n"
+		case "DEL":
+			n := 0
+			for _, k := range args[1:] {
+				if _, ok := f.data[k]; ok {
+					delete(f.data, k)
+					n++
+				}
+			}
+			reply = fmt.Sprintf(":%d\r\n", n)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+		c.Write([]byte(reply))
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestDatasource(t *testing.T) {
+	f := newFakeRedis(t)
+	defer f.Close()
+
+	ds := NewDatasource(f.Addr().String(), "test:")
+	defer ds.Close()
+
+	if err := ds.Set("name", "Matt", 0); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if err := ds.Set("count", 3, 1500*time.Millisecond); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if f.data["test:name"] != "Matt" || f.data["test:count"] != "3" {
+		t.Errorf("! Expected prefixed keys, got %v", f.data)
+	}
+	if f.ttls["test:count"] != "PX 1500" {
+		t.Errorf("! Expected a TTL of 1500ms, got %q", f.ttls["test:count"])
+	}
+
+	if v := ds.Value("name"); v != "Matt" {
+		t.Errorf("! Expected Matt, got %v", v)
+	}
+	if v := ds.Value("nope"); v != nil {
+		t.Errorf("! Expected nil, got %v", v)
+	}
+	if v := cookoo.GetString("name", "", ds); v != "Matt" {
+		t.Errorf("! Expected Matt from GetString, got %s", v)
+	}
+	if _, ok := cookoo.HasString("nope", ds); ok {
+		t.Error("! Expected nope to be missing.")
+	}
+
+	if err := ds.Delete("name"); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if _, ok, err := ds.Lookup("name"); ok || err != nil {
+		t.Errorf("! Expected name to be deleted, got %v", err)
+	}
+
+	if f.conns != 1 {
+		t.Errorf("! Expected a single pooled connection, got %d", f.conns)
+	}
+}
+
+func TestDatasourceRoute(t *testing.T) {
+	f := newFakeRedis(t)
+	defer f.Close()
+	f.data["app:motd"] = "Hello"
+
+	reg, router, cxt := cookoo.Cookoo()
+	ds := NewDatasource(f.Addr().String(), "app:")
+	defer ds.Close()
+	cxt.AddDatasource("redis", ds)
+
+	reg.Route("test", "Test the datasource.").
+		Does(cookoo.AddToContext, "add").
+		Using("motd").WithDefault("Welcome").From("redis:motd").
+		Using("other").WithDefault("Default").From("redis:other")
+
+	if err := router.HandleRequest("test", cxt, false); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if v := cxt.Get("motd", nil); v != "Hello" {
+		t.Errorf("! Expected Hello, got %v", v)
+	}
+	if v := cxt.Get("other", nil); v != "Default" {
+		t.Errorf("! Expected the default, got %v", v)
+	}
+}
+
+func TestDatasourceErrors(t *testing.T) {
+	f := newFakeRedis(t)
+	defer f.Close()
+
+	ds := NewDatasource(f.Addr().String(), "")
+	ds.Password = "wrong"
+	if _, _, err := ds.Lookup("x"); err == nil || !strings.Contains(err.Error(), "invalid password") {
+		t.Errorf("! Expected an AUTH error, got %v", err)
+	}
+
+	ds.Password = "secret"
+	if _, _, err := ds.Lookup("x"); err != nil {
+		t.Errorf("! Unexpected error: %s", err)
+	}
+	if _, err := ds.do("BOGUS"); err == nil {
+		t.Error("! Expected an error reply.")
+	} else if _, ok := err.(Error); !ok {
+		t.Errorf("! Expected an Error, got %T", err)
+	}
+
+	ds.Close()
+	if _, _, err := ds.Lookup("x"); err != ErrClosed {
+		t.Errorf("! Expected ErrClosed, got %v", err)
+	}
+}
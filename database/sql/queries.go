@@ -0,0 +1,265 @@
+package sql
+
+import (
+	dbsql "database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// Queries is a registry of named SQL statements.
+//
+// Queries are registered up front, usually at the same time as routes, and
+// then run by name with QueryInto and ExecNamed. Each statement is prepared
+// the first time it is used, and the prepared statement is reused after
+// that.
+//
+// Example:
+//
+// 	queries := sql.NewQueries(db).
+// 		Register("user", "SELECT id, name FROM users WHERE id = ?").
+// 		Register("rename", "UPDATE users SET name = ? WHERE id = ?")
+// 	cxt.AddDatasource("queries", queries)
+//
+// 	reg.Route("GET /user", "Show a user").
+// 		Does(sql.QueryInto, "user").
+// 			Using("query").WithDefault("user").
+// 			Using("0").From("query:id")
+type Queries struct {
+	db    *dbsql.DB
+	mu    sync.Mutex
+	sql   map[string]string
+	stmts map[string]*dbsql.Stmt
+}
+
+// NewQueries creates a new query registry for a database.
+func NewQueries(db *dbsql.DB) *Queries {
+	return &Queries{
+		db:    db,
+		sql:   map[string]string{},
+		stmts: map[string]*dbsql.Stmt{},
+	}
+}
+
+// Register adds a named statement. Registering a name again replaces the
+// statement.
+func (q *Queries) Register(name, statement string) *Queries {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if stmt, ok := q.stmts[name]; ok {
+		stmt.Close()
+		delete(q.stmts, name)
+	}
+	q.sql[name] = statement
+	return q
+}
+
+// DB returns the database handle.
+func (q *Queries) DB() *dbsql.DB {
+	return q.db
+}
+
+// Prepare gets the prepared statement for a named query.
+func (q *Queries) Prepare(name string) (*dbsql.Stmt, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if stmt, ok := q.stmts[name]; ok {
+		return stmt, nil
+	}
+	statement, ok := q.sql[name]
+	if !ok {
+		return nil, fmt.Errorf("No query named '%s' is registered.", name)
+	}
+	stmt, err := q.db.Prepare(statement)
+	if err != nil {
+		return nil, err
+	}
+	q.stmts[name] = stmt
+	return stmt, nil
+}
+
+// Close closes all of the prepared statements. The database is not closed.
+func (q *Queries) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for name, stmt := range q.stmts {
+		stmt.Close()
+		delete(q.stmts, name)
+	}
+	return nil
+}
+
+// QueryInto runs a named query and returns its rows.
+//
+// Each row is returned as a map of column names to values. Byte slices are
+// converted to strings. If an 'into' param is given, the rows are also
+// scanned into it. It must be a pointer to a slice of structs. A column is
+// stored in the field whose `db` tag matches the column name, or else in
+// the field whose name matches it, ignoring case.
+//
+// Params:
+// 	- query (string): The name of the query. This is required.
+// 	- queries (string): The name of the Queries datasource. Default: "queries"
+// 	- "0"... : The query's arguments, in order.
+// 	- into: A pointer to a slice of structs to fill.
+//
+// Returns:
+// 	- []map[string]interface{}: The rows.
+func QueryInto(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	name, stmt, irq := namedStmt(cxt, params)
+	if irq != nil {
+		return nil, irq
+	}
+
+	rows, err := stmt.QueryContext(cxt.GoContext(), positionalArgs(params)...)
+	if err != nil {
+		return fatalError(err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return fatalError(err)
+	}
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return fatalError(err)
+		}
+		row := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			if b, ok := vals[i].([]byte); ok {
+				vals[i] = string(b)
+			}
+			row[col] = vals[i]
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return fatalError(err)
+	}
+
+	if into, ok := params.Has("into"); ok {
+		if err := fillStructs(into, results); err != nil {
+			return nil, &cookoo.FatalError{Message: fmt.Sprintf("Could not scan query %s: %s", name, err)}
+		}
+	}
+	return results, nil
+}
+
+// ExecNamed runs a named statement and returns the number of rows affected.
+//
+// Params:
+// 	- query (string): The name of the statement. This is required.
+// 	- queries (string): The name of the Queries datasource. Default: "queries"
+// 	- "0"... : The statement's arguments, in order.
+//
+// Returns:
+// 	- int64: The number of rows affected.
+func ExecNamed(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	_, stmt, irq := namedStmt(cxt, params)
+	if irq != nil {
+		return nil, irq
+	}
+
+	res, err := stmt.ExecContext(cxt.GoContext(), positionalArgs(params)...)
+	if err != nil {
+		return fatalError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fatalError(err)
+	}
+	return n, nil
+}
+
+// namedStmt finds the prepared statement named by the 'query' param.
+func namedStmt(cxt cookoo.Context, params *cookoo.Params) (string, *dbsql.Stmt, cookoo.Interrupt) {
+	name, ok := cookoo.HasString("query", params)
+	if !ok {
+		return "", nil, &cookoo.FatalError{Message: "Expected a 'query'"}
+	}
+	dsName := cookoo.GetString("queries", "queries", params)
+	queries, ok := cxt.Datasource(dsName).(*Queries)
+	if !ok {
+		return "", nil, &cookoo.FatalError{Message: fmt.Sprintf("No Queries datasource named '%s' found.", dsName)}
+	}
+	stmt, err := queries.Prepare(name)
+	if err != nil {
+		return "", nil, &cookoo.FatalError{Message: err.Error()}
+	}
+	return name, stmt, nil
+}
+
+// positionalArgs gets the params named "0", "1", and so on.
+func positionalArgs(params *cookoo.Params) []interface{} {
+	args := []interface{}{}
+	for i := 0; ; i++ {
+		v, ok := params.Has(strconv.Itoa(i))
+		if !ok {
+			return args
+		}
+		args = append(args, v)
+	}
+}
+
+// fillStructs copies rows into a pointer to a slice of structs.
+func fillStructs(into interface{}, rows []map[string]interface{}) error {
+	ptr := reflect.ValueOf(into)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Slice || ptr.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("'into' must be a pointer to a slice of structs, not %T", into)
+	}
+	slice := ptr.Elem()
+	itemType := slice.Type().Elem()
+
+	out := reflect.MakeSlice(slice.Type(), 0, len(rows))
+	for _, row := range rows {
+		item := reflect.New(itemType).Elem()
+		for i := 0; i < itemType.NumField(); i++ {
+			field := itemType.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			v, ok := rowValue(row, field)
+			if !ok || v == nil {
+				continue
+			}
+			rv := reflect.ValueOf(v)
+			switch {
+			case rv.Type().AssignableTo(field.Type):
+				item.Field(i).Set(rv)
+			case rv.Type().ConvertibleTo(field.Type) && field.Type.Kind() != reflect.String:
+				item.Field(i).Set(rv.Convert(field.Type))
+			default:
+				return fmt.Errorf("cannot store %T in field %s (%s)", v, field.Name, field.Type)
+			}
+		}
+		out = reflect.Append(out, item)
+	}
+	slice.Set(out)
+	return nil
+}
+
+// rowValue finds the column for a struct field.
+func rowValue(row map[string]interface{}, field reflect.StructField) (interface{}, bool) {
+	if tag := field.Tag.Get("db"); tag != "" {
+		v, ok := row[tag]
+		return v, ok
+	}
+	for col, v := range row {
+		if strings.EqualFold(col, field.Name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
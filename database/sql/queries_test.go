@@ -0,0 +1,133 @@
+package sql
+
+import (
+	dbsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// fakeDriver is a database driver that answers a couple of fixed statements.
+type fakeDriver struct{ prepared []string }
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.HasPrefix(query, "BAD") {
+		return nil, errors.New("syntax error")
+	}
+	c.d.prepared = append(c.d.prepared, query)
+	return &fakeStmt{query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeStmt struct{ query string }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(len(args)), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	// Returns one row for each argument, with the argument as the name.
+	rows := &fakeRows{}
+	for i, a := range args {
+		rows.data = append(rows.data, []driver.Value{int64(i + 1), []byte(a.(string))})
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	data [][]driver.Value
+	i    int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id", "name"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.data) {
+		return io.EOF
+	}
+	copy(dest, r.data[r.i])
+	r.i++
+	return nil
+}
+
+var testDriver = &fakeDriver{}
+
+func init() {
+	dbsql.Register("cookoofake", testDriver)
+}
+
+type user struct {
+	ID       int
+	Username string `db:"name"`
+}
+
+func TestQueries(t *testing.T) {
+	db, err := dbsql.Open("cookoofake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	queries := NewQueries(db).
+		Register("users", "SELECT id, name FROM users WHERE name IN (?, ?)").
+		Register("rename", "UPDATE users SET name = ? WHERE id = ?").
+		Register("bad", "BAD SQL")
+	defer queries.Close()
+
+	reg, router, cxt := cookoo.Cookoo()
+	cxt.AddDatasource("queries", queries)
+	var users []user
+
+	reg.Route("test", "Test named queries.").
+		Does(QueryInto, "users").
+		Using("query").WithDefault("users").
+		Using("0").WithDefault("matt").
+		Using("1").From("cxt:other").
+		Using("into").WithDefault(&users).
+		Does(ExecNamed, "renamed").
+		Using("query").WithDefault("rename").
+		Using("0").WithDefault("matt").
+		Using("1").WithDefault(1)
+	reg.Route("missing", "Test a missing query.").
+		Does(ExecNamed, "x").Using("query").WithDefault("nope")
+	reg.Route("bad", "Test a bad query.").
+		Does(ExecNamed, "x").Using("query").WithDefault("bad")
+
+	cxt.Put("other", "angie")
+	if err := router.HandleRequest("test", cxt, false); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+
+	rows := cxt.Get("users", nil).([]map[string]interface{})
+	if len(rows) != 2 || rows[1]["name"] != "angie" || rows[1]["id"] != int64(2) {
+		t.Errorf("! Unexpected rows: %v", rows)
+	}
+	if len(users) != 2 || users[0].ID != 1 || users[0].Username != "matt" {
+		t.Errorf("! Unexpected users: %v", users)
+	}
+	if n := cxt.Get("renamed", nil); n != int64(2) {
+		t.Errorf("! Expected 2 rows affected, got %v", n)
+	}
+
+	router.HandleRequest("test", cxt, false)
+	if len(testDriver.prepared) != 2 {
+		t.Errorf("! Expected each statement to be prepared once, got %v", testDriver.prepared)
+	}
+
+	if err := router.HandleRequest("missing", cxt, false); err == nil {
+		t.Error("! Expected a missing query to fail.")
+	}
+	if err := router.HandleRequest("bad", cxt, false); err == nil {
+		t.Error("! Expected a bad query to fail.")
+	}
+}
@@ -0,0 +1,125 @@
+package cookoo
+
+import (
+	"reflect"
+)
+
+// Cloneable is a value that knows how to copy itself.
+//
+// Context.DeepCopy calls Clone instead of copying a Cloneable value field by
+// field. Clone should return a value of the same type, which shares no
+// mutable state with the original. If it returns a value of another type, it
+// is ignored, and the value is copied as if it were not Cloneable.
+type Cloneable interface {
+	Clone() interface{}
+}
+
+// deepCopyValue recursively clones a value. See ExecutionContext.DeepCopy.
+func deepCopyValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(v), map[copiedRef]reflect.Value{}).Interface()
+}
+
+// copiedRef identifies a pointer or map that has already been copied, so
+// that cycles and shared references are preserved.
+type copiedRef struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+func deepCopy(src reflect.Value, seen map[copiedRef]reflect.Value) reflect.Value {
+	if dst, ok := cloned(src); ok {
+		return dst
+	}
+
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return src
+		}
+		ref := copiedRef{src.Pointer(), src.Type()}
+		if dst, ok := seen[ref]; ok {
+			return dst
+		}
+		dst := reflect.New(src.Type().Elem())
+		seen[ref] = dst
+		dst.Elem().Set(deepCopy(src.Elem(), seen))
+		return dst
+	case reflect.Map:
+		if src.IsNil() {
+			return src
+		}
+		ref := copiedRef{src.Pointer(), src.Type()}
+		if dst, ok := seen[ref]; ok {
+			return dst
+		}
+		dst := reflect.MakeMapWithSize(src.Type(), src.Len())
+		seen[ref] = dst
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(iter.Key(), deepCopy(iter.Value(), seen))
+		}
+		return dst
+	case reflect.Slice:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(deepCopy(src.Index(i), seen))
+		}
+		return dst
+	case reflect.Array:
+		dst := reflect.New(src.Type()).Elem()
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(deepCopy(src.Index(i), seen))
+		}
+		return dst
+	case reflect.Struct:
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if src.Type().Field(i).IsExported() {
+				dst.Field(i).Set(deepCopy(src.Field(i), seen))
+			}
+		}
+		return dst
+	case reflect.Interface:
+		if src.IsNil() {
+			return src
+		}
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(deepCopy(src.Elem(), seen))
+		return dst
+	}
+	// Channels, funcs, and scalars are returned as-is.
+	return src
+}
+
+// cloned copies a Cloneable value with its Clone method. It returns false if
+// the value is not Cloneable, or Clone returns the wrong type.
+func cloned(src reflect.Value) (reflect.Value, bool) {
+	switch src.Kind() {
+	case reflect.Interface, reflect.Invalid:
+		// Interfaces are checked when their element is copied.
+		return src, false
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if src.IsNil() {
+			return src, false
+		}
+	}
+	if !src.CanInterface() {
+		return src, false
+	}
+	c, ok := src.Interface().(Cloneable)
+	if !ok {
+		return src, false
+	}
+	dst := reflect.ValueOf(c.Clone())
+	if !dst.IsValid() || dst.Type() != src.Type() {
+		return src, false
+	}
+	return dst, true
+}
@@ -0,0 +1,154 @@
+package cookoo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// RouteInfo describes a route, as returned by Registry.Describe.
+type RouteInfo struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Commands    []CommandDetails `json:"commands"`
+}
+
+// Describe describes every route in the registry, in the order the routes
+// were added.
+//
+// This is useful for documenting an application. Since the result is plain
+// data, it can be encoded as JSON, or used to generate help text. See also
+// ListRoutes.
+func (r *Registry) Describe() []RouteInfo {
+	infos := make([]RouteInfo, 0, len(r.orderedRouteNames))
+	for _, name := range r.orderedRouteNames {
+		spec := r.routes[name]
+		infos = append(infos, RouteInfo{
+			Name:        spec.Name(),
+			Description: spec.Description(),
+			Commands:    spec.Commands(),
+		})
+	}
+	return infos
+}
+
+// Describe describes every route that the router can run. See
+// Registry.Describe.
+func (r *Router) Describe() []RouteInfo {
+	return r.registry.Describe()
+}
+
+// MarshalJSON encodes the param, describing a default that cannot be encoded.
+func (p ParamDetails) MarshalJSON() ([]byte, error) {
+	type plain ParamDetails
+	out := struct {
+		plain
+		Default json.RawMessage `json:"default"`
+	}{plain: plain(p), Default: dumpValue(p.Default)}
+	return json.Marshal(out)
+}
+
+// ListRoutes writes a description of the routes in a registry.
+//
+// This can be used to expose the routes of an application, for example at
+// `/routes` on a web server, or with a `--list-routes` flag on the command
+// line:
+//
+// 	reg.Route("GET /routes", "List the routes.").
+// 		Does(cookoo.ListRoutes, "routes").
+// 			Using("registry").WithDefault(reg).
+// 			Using("format").WithDefault("json")
+//
+// In the text format, each route is listed with its description, its
+// commands in the order they run, and each command's params with their
+// defaults and sources. The JSON format is the encoding of Registry.Describe.
+//
+// Params:
+// 	- registry (*Registry): The registry to describe. This is required.
+// 	- format (string): "text" or "json". Default: "text"
+// 	- internal (bool): Include routes whose names start with "@". Default: false
+// 	- prefix (string): Only list routes whose names start with this prefix.
+// 	- writer (io.Writer): Where to write the description. If none is given,
+// 	  the HTTP response (`http.ResponseWriter` in the context) is used if
+// 	  there is one, and otherwise os.Stdout.
+//
+// Returns:
+// 	- The listed routes, as a []RouteInfo.
+func ListRoutes(cxt Context, params *Params) (interface{}, Interrupt) {
+	reg, ok := params.Get("registry", nil).(*Registry)
+	if !ok {
+		return nil, &FatalError{"Expected a 'registry'"}
+	}
+	out, ok := params.Get("writer", nil).(io.Writer)
+	if !ok {
+		if out, ok = cxt.Get("http.ResponseWriter", nil).(io.Writer); !ok {
+			out = os.Stdout
+		}
+	}
+	internal := GetBool("internal", false, params)
+	prefix := GetString("prefix", "", params)
+
+	routes := []RouteInfo{}
+	for _, info := range reg.Describe() {
+		if (!internal && strings.HasPrefix(info.Name, "@")) || !strings.HasPrefix(info.Name, prefix) {
+			continue
+		}
+		routes = append(routes, info)
+	}
+
+	var err error
+	switch format := GetString("format", "text", params); format {
+	case "json":
+		err = json.NewEncoder(out).Encode(routes)
+	case "text":
+		err = writeRoutes(out, routes)
+	default:
+		return nil, &FatalError{fmt.Sprintf("Unknown format '%s'", format)}
+	}
+	if err != nil {
+		return nil, &FatalError{err.Error()}
+	}
+	return routes, nil
+}
+
+// writeRoutes writes the text format of ListRoutes.
+func writeRoutes(out io.Writer, routes []RouteInfo) error {
+	var b strings.Builder
+	for i, route := range routes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(route.Name + "\n")
+		if route.Description != "" {
+			fmt.Fprintf(&b, "\t%s\n", route.Description)
+		}
+		for _, cmd := range route.Commands {
+			if cmd.Help != "" {
+				fmt.Fprintf(&b, "\t- %s: %s\n", cmd.Name, cmd.Help)
+			} else {
+				fmt.Fprintf(&b, "\t- %s\n", cmd.Name)
+			}
+			for _, p := range cmd.Params {
+				attrs := []string{}
+				if p.Default != nil {
+					attrs = append(attrs, fmt.Sprintf("default: %s", dumpValue(p.Default)))
+				}
+				if len(p.From) > 0 {
+					attrs = append(attrs, "from: "+strings.Join(p.From, " "))
+				}
+				line := "\t\t" + p.Name
+				if len(attrs) > 0 {
+					line += " (" + strings.Join(attrs, ", ") + ")"
+				}
+				if p.Help != "" {
+					line += ": " + p.Help
+				}
+				b.WriteString(line + "\n")
+			}
+		}
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
@@ -0,0 +1,84 @@
+package cookoo
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func describedRegistry() (*Registry, *Router, Context) {
+	reg, router, cxt := Cookoo()
+	reg.Route("@setup", "Shared setup.").
+		Does(AddToContext, "setup")
+	reg.Route("migrate", "Migrate the database.").
+		Includes("@setup").
+		Does(AddToContext, "migrate").Help("Apply pending migrations.").
+		Using("steps").WithDefault(0).From("cxt:steps").Help("How many to apply.").
+		Using("hook").WithDefault(func() {})
+	reg.Route("serve", "Run the server.")
+	return reg, router, cxt
+}
+
+func TestDescribe(t *testing.T) {
+	reg, router, _ := describedRegistry()
+	routes := router.Describe()
+	if len(routes) != 3 || routes[1].Name != "migrate" || routes[1].Description != "Migrate the database." {
+		t.Fatalf("! Unexpected routes: %+v", routes)
+	}
+	cmds := routes[1].Commands
+	if len(cmds) != 2 || cmds[0].Name != "setup" || cmds[1].Help != "Apply pending migrations." {
+		t.Fatalf("! Unexpected commands: %+v", cmds)
+	}
+	if p := cmds[1].Params[0]; p.Name != "steps" || p.Default != 0 || p.From[0] != "cxt:steps" {
+		t.Errorf("! Unexpected param: %+v", p)
+	}
+
+	data, err := json.Marshal(reg.Describe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	if !strings.Contains(s, `"name":"steps","help":"How many to apply.","from":["cxt:steps"],"default":0`) ||
+		!strings.Contains(s, `"default":"\u003cfunc()\u003e"`) || !strings.Contains(s, `"params":[]`) {
+		t.Errorf("! Unexpected JSON: %s", s)
+	}
+}
+
+func TestListRoutes(t *testing.T) {
+	reg, router, cxt := describedRegistry()
+	var out bytes.Buffer
+	reg.Route("list", "List routes.").
+		Does(ListRoutes, "routes").
+		Using("registry").WithDefault(reg).
+		Using("writer").WithDefault(&out).
+		Using("format").From("cxt:format")
+
+	if err := router.HandleRequest("list", cxt, false); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	for _, want := range []string{"migrate\n\tMigrate the database.\n", "\t- migrate: Apply pending migrations.\n", "\t\tsteps (default: 0, from: cxt:steps): How many to apply.\n", "\nlist\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("! Expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "@setup") {
+		t.Error("! Expected internal routes to be left out.")
+	}
+
+	out.Reset()
+	cxt.Put("format", "json")
+	if err := router.HandleRequest("list", cxt, false); err != nil {
+		t.Fatal(err)
+	}
+	var routes []RouteInfo
+	if err := json.Unmarshal(out.Bytes(), &routes); err != nil || len(routes) != 3 {
+		t.Errorf("! Expected 3 routes in JSON, got %d: %v", len(routes), err)
+	}
+
+	cxt.Put("format", "yaml")
+	if err := router.HandleRequest("list", cxt, false); err == nil {
+		t.Error("! Expected an error for an unknown format.")
+	}
+}
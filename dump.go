@@ -0,0 +1,83 @@
+package cookoo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// DumpContext writes a JSON snapshot of a context for debugging.
+//
+// The snapshot looks like this:
+//
+// 	{
+// 	  "values": {"name": "Matt", "age": 42},
+// 	  "datasources": ["db", "env"]
+// 	}
+//
+// Values that cannot be encoded as JSON are described instead. Funcs and
+// channels are written as their type, such as "<func() string>". Other
+// values that fail to encode are written with fmt's %v. Datasources are
+// listed by name only.
+//
+// The snapshot is lossy, and is not intended to be read back into a context.
+// Use SaveContextJSON for that. Contexts also implement json.Marshaler, so
+// a context can be passed straight to json.Marshal.
+func DumpContext(w io.Writer, cxt Context) error {
+	data, err := marshalContext(cxt)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+type contextDump struct {
+	Values      map[string]json.RawMessage `json:"values"`
+	Datasources []string                   `json:"datasources"`
+}
+
+func marshalContext(cxt Context) ([]byte, error) {
+	dump := contextDump{
+		Values:      map[string]json.RawMessage{},
+		Datasources: []string{},
+	}
+	for k, v := range cxt.AsMap() {
+		dump.Values[k] = dumpValue(v)
+	}
+	for name := range cxt.Datasources() {
+		dump.Datasources = append(dump.Datasources, name)
+	}
+	sort.Strings(dump.Datasources)
+	return json.Marshal(dump)
+}
+
+// dumpValue encodes a single value, describing it if it cannot be encoded.
+func dumpValue(v interface{}) (out json.RawMessage) {
+	describe := func(s string) json.RawMessage {
+		data, _ := json.Marshal(s)
+		return data
+	}
+
+	if v != nil {
+		switch reflect.TypeOf(v).Kind() {
+		case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+			return describe(fmt.Sprintf("<%T>", v))
+		}
+	}
+
+	// A broken MarshalJSON method should not break the dump.
+	defer func() {
+		if err := recover(); err != nil {
+			out = describe(fmt.Sprintf("<%T: %v>", v, err))
+		}
+	}()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return describe(fmt.Sprintf("%v", v))
+	}
+	return data
+}
@@ -0,0 +1,87 @@
+package cookoo
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type dumpStruct struct {
+	Name string
+	Tags []string
+}
+
+type panickyValue struct{}
+
+func (p panickyValue) MarshalJSON() ([]byte, error) {
+	panic("oops")
+}
+
+func TestKeys(t *testing.T) {
+	c := NewContext()
+	c.Put("b", 1)
+	c.Put("a", 2)
+	if keys := c.Keys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("! Expected [a b], got %v", keys)
+	}
+
+	child := c.NewChild()
+	child.Put("c", 3)
+	child.Put("a", 4)
+	if keys := child.Keys(); !reflect.DeepEqual(keys, []string{"a", "b", "c"}) {
+		t.Errorf("! Expected [a b c], got %v", keys)
+	}
+}
+
+func TestDumpContext(t *testing.T) {
+	c := NewContext()
+	c.Put("string", "hello")
+	c.Put("int", 42)
+	c.Put("struct", dumpStruct{"Matt", []string{"a"}})
+	c.Put("func", func() string { return "hello" })
+	c.Put("chan", make(chan int))
+	c.Put("panic", panickyValue{})
+	c.AddDatasource("foo", new(ExampleDatasource))
+
+	var buf bytes.Buffer
+	if err := DumpContext(&buf, c); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+
+	var out struct {
+		Values      map[string]interface{}
+		Datasources []string
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("! Could not decode dump %s: %s", buf.String(), err)
+	}
+
+	if out.Values["string"] != "hello" || out.Values["int"] != 42.0 {
+		t.Errorf("! Unexpected scalar values: %v", out.Values)
+	}
+	expects := map[string]interface{}{"Name": "Matt", "Tags": []interface{}{"a"}}
+	if !reflect.DeepEqual(out.Values["struct"], expects) {
+		t.Errorf("! Expected %v, got %v", expects, out.Values["struct"])
+	}
+	if out.Values["func"] != "<func() string>" {
+		t.Errorf("! Expected the func to be described, got %v", out.Values["func"])
+	}
+	if out.Values["chan"] != "<chan int>" {
+		t.Errorf("! Expected the chan to be described, got %v", out.Values["chan"])
+	}
+	if _, ok := out.Values["panic"].(string); !ok {
+		t.Errorf("! Expected the panicking value to be described, got %v", out.Values["panic"])
+	}
+	if !reflect.DeepEqual(out.Datasources, []string{"foo"}) {
+		t.Errorf("! Expected datasource names, got %v", out.Datasources)
+	}
+
+	// Contexts can be passed straight to json.Marshal.
+	for _, cxt := range []Context{c, SyncContext(c), ReadOnlyContext(c), c.NewChild()} {
+		data, err := json.Marshal(cxt)
+		if err != nil || !bytes.Equal(data, buf.Bytes()) {
+			t.Errorf("! Expected %T to marshal like DumpContext, got %s (%v)", cxt, data, err)
+		}
+	}
+}
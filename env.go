@@ -0,0 +1,184 @@
+package cookoo
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// EnvDatasource is a KeyValueDatasource for environment variables.
+//
+// If a Prefix is set, it is prepended to every key before the environment is
+// read. So with the prefix "MYAPP_", `Value("PORT")` reads `MYAPP_PORT`.
+//
+// Environment variables are always strings. A variable that is not set
+// returns nil, so defaults can be used in From() clauses:
+//
+// 	cxt.AddDatasource("env", cookoo.NewEnvDatasource("MYAPP_"))
+//
+// 	reg.Route("serve", "Start the server").
+// 		Does(Serve, "server").
+// 			Using("port").WithDefault("8080").From("env:PORT")
+type EnvDatasource struct {
+	Prefix string
+}
+
+// NewEnvDatasource creates a new environment datasource with the given prefix.
+func NewEnvDatasource(prefix string) *EnvDatasource {
+	return &EnvDatasource{Prefix: prefix}
+}
+
+// Value returns the value of an environment variable, or nil if it is not set.
+func (e *EnvDatasource) Value(key string) interface{} {
+	v, ok := os.LookupEnv(e.Prefix + key)
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+// Get returns the value of an environment variable, or the default value.
+func (e *EnvDatasource) Get(key string, defaultVal interface{}) interface{} {
+	return DatasourceGet(e, key, defaultVal)
+}
+
+// Has returns the value of an environment variable, and whether it is set.
+func (e *EnvDatasource) Has(key string) (interface{}, bool) {
+	return DatasourceHas(e, key)
+}
+
+// Validator describes a value that can check itself for correctness.
+//
+// LoadConfig calls Validate on a config struct after it is loaded.
+type Validator interface {
+	Validate() error
+}
+
+// LoadConfig loads environment variables into a config struct.
+//
+// Each field to be loaded must have an `env` tag naming its variable. A
+// `default` tag gives the value to use when the variable is not set. Fields
+// may be strings, bools, any size of int, uint, or float, or time.Duration.
+// Fields without an `env` tag are left alone.
+//
+// 	type Config struct {
+// 		Host    string        `env:"HOST" default:"localhost"`
+// 		Port    int           `env:"PORT" default:"8080"`
+// 		Timeout time.Duration `env:"TIMEOUT" default:"30s"`
+// 	}
+//
+// After the fields are set, if the struct implements Validator, its Validate
+// method is called.
+//
+// Params
+//
+// 	- config: A pointer to the config struct. This is required.
+// 	- prefix (string): A prefix to add to each variable name. Default: ""
+// 	- datasource (string): The name of a KeyValueDatasource to read from,
+// 	  such as an EnvDatasource. If this is not set, the environment is
+// 	  read directly.
+//
+// Returns
+//
+// 	- The config struct pointer.
+//
+// If a value cannot be parsed, or validation fails, a FatalError is returned.
+func LoadConfig(cxt Context, params *Params) (interface{}, Interrupt) {
+	out, ok := params.Has("config")
+	if !ok {
+		return nil, &FatalError{"Expected a 'config'"}
+	}
+	prefix := GetString("prefix", "", params)
+
+	var src KeyValueDatasource = &EnvDatasource{}
+	if name, ok := HasString("datasource", params); ok {
+		src, ok = cxt.Datasource(name).(KeyValueDatasource)
+		if !ok {
+			return nil, &FatalError{fmt.Sprintf("No KeyValueDatasource named '%s' found.", name)}
+		}
+	}
+
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil, &FatalError{fmt.Sprintf("Expected 'config' to be a pointer to a struct, got %T", out)}
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, ok := field.Tag.Lookup("env")
+		if !ok || field.PkgPath != "" {
+			continue
+		}
+
+		var str string
+		if v, ok := src.Value(prefix + name).(string); ok {
+			str = v
+		} else if def, ok := field.Tag.Lookup("default"); ok {
+			str = def
+		} else {
+			continue
+		}
+
+		if err := setFromString(rv.Field(i), str); err != nil {
+			return nil, &FatalError{fmt.Sprintf("Could not set %s from %s%s: %s", field.Name, prefix, name, err)}
+		}
+	}
+
+	if v, ok := out.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, &FatalError{fmt.Sprintf("Invalid config: %s", err)}
+		}
+	}
+
+	return out, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setFromString parses a string into a value of the field's kind.
+func setFromString(field reflect.Value, str string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(str)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(str, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(str, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(str, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
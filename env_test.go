@@ -0,0 +1,145 @@
+package cookoo
+
+import (
+	"errors"
+	"flag"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Host    string        `env:"HOST" default:"localhost"`
+	Port    int           `env:"PORT"`
+	Debug   bool          `env:"DEBUG"`
+	Timeout time.Duration `env:"TIMEOUT" default:"30s"`
+	Ignored string
+}
+
+func (c *testConfig) Validate() error {
+	if c.Port == 0 {
+		return errors.New("a port is required")
+	}
+	return nil
+}
+
+func TestEnvDatasource(t *testing.T) {
+	t.Setenv("COOKOOTEST_FOO", "bar")
+
+	ds := NewEnvDatasource("COOKOOTEST_")
+	if v := ds.Value("FOO"); v != "bar" {
+		t.Errorf("! Expected bar, got %v", v)
+	}
+	if v := ds.Value("NOPE"); v != nil {
+		t.Errorf("! Expected nil, got %v", v)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("COOKOOTEST_PORT", "9090")
+	t.Setenv("COOKOOTEST_DEBUG", "true")
+	t.Setenv("Ignored", "nope")
+
+	reg, router, cxt := Cookoo()
+	cfg := &testConfig{}
+	reg.Route("test", "Test config.").
+		Does(LoadConfig, "config").
+		Using("config").WithDefault(cfg).
+		Using("prefix").WithDefault("COOKOOTEST_")
+
+	if e := router.HandleRequest("test", cxt, false); e != nil {
+		t.Fatalf("! Unexpected error: %s", e)
+	}
+
+	if cxt.Get("config", nil) != cfg {
+		t.Error("! Expected the config to be stored in the context.")
+	}
+	if cfg.Port != 9090 {
+		t.Errorf("! Expected port 9090, got %d", cfg.Port)
+	}
+	if !cfg.Debug {
+		t.Error("! Expected debug to be true.")
+	}
+	if cfg.Host != "localhost" {
+		t.Errorf("! Expected the default host, got %s", cfg.Host)
+	}
+	if cfg.Timeout != 30*time.Second {
+		t.Errorf("! Expected the default timeout, got %s", cfg.Timeout)
+	}
+	if cfg.Ignored != "" {
+		t.Error("! Expected untagged fields to be ignored.")
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	cxt.AddDatasource("env", NewEnvDatasource("COOKOOTEST_"))
+	reg.Route("test", "Test config.").
+		Does(LoadConfig, "config").
+		Using("config").WithDefault(&testConfig{}).
+		Using("datasource").WithDefault("env")
+
+	e := router.HandleRequest("test", cxt, false)
+	if e == nil {
+		t.Fatal("! Expected validation to fail without a port.")
+	}
+	if _, ok := e.(*FatalError); !ok {
+		t.Errorf("! Expected a FatalError, got %T", e)
+	}
+
+	t.Setenv("COOKOOTEST_PORT", "not a number")
+	if e := router.HandleRequest("test", cxt, false); e == nil {
+		t.Error("! Expected a bad port to fail.")
+	}
+}
+
+func TestFlagDatasourceChain(t *testing.T) {
+	t.Setenv("COOKOOTEST_HOST", "example.com")
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Int("port", 8080, "The port.")
+	flags.Bool("debug", false, "Debug mode.")
+	flags.String("host", "localhost", "The host.")
+	if err := flags.Parse([]string{"-port", "9090"}); err != nil {
+		t.Fatal(err)
+	}
+
+	fds := NewFlagDatasource(flags)
+	if v := fds.Value("port"); v != 9090 {
+		t.Errorf("! Expected the typed flag value, got %v (%T)", v, v)
+	}
+	if _, ok := fds.Has("debug"); ok {
+		t.Error("! Expected unset flags to be missing.")
+	}
+	fds.IncludeDefaults = true
+	if v := fds.Value("debug"); v != false {
+		t.Errorf("! Expected the flag default, got %v", v)
+	}
+	fds.IncludeDefaults = false
+
+	cfg := ChainedGetter{NewEnvDatasource("COOKOOTEST_"), fds}
+	if v := GetString("HOST", "localhost", cfg); v != "example.com" {
+		t.Errorf("! Expected the host from the environment, got %s", v)
+	}
+	if v := GetInt("port", 80, cfg); v != 9090 {
+		t.Errorf("! Expected the port flag, got %d", v)
+	}
+	if v := GetBool("debug", true, cfg); !v {
+		t.Error("! Expected the default for debug.")
+	}
+
+	reg, router, cxt := Cookoo()
+	cxt.AddDatasource("cfg", cfg)
+	reg.Route("test", "Test a chained datasource.").
+		Does(AddToContext, "add").
+		Using("host").WithDefault("localhost").From("cfg:HOST").
+		Using("port").WithDefault(80).From("cfg:port")
+	if err := router.HandleRequest("test", cxt, false); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if v := cxt.Get("host", nil); v != "example.com" {
+		t.Errorf("! Expected the host from the environment, got %v", v)
+	}
+	if v := cxt.Get("port", nil); v != 9090 {
+		t.Errorf("! Expected the port from the flags, got %v", v)
+	}
+}
@@ -0,0 +1,57 @@
+package cookoo
+
+import "flag"
+
+// FlagDatasource is a KeyValueDatasource for command-line flags.
+//
+// Values are read from a parsed flag.FlagSet, by flag name. By default, only
+// flags that were set on the command line are visible, so that a flag's
+// default does not hide values from other sources in a ChainedGetter. Set
+// IncludeDefaults to see every defined flag.
+//
+// Flags created with the flag package's typed functions (Int, Bool,
+// Duration, and so on) return values of that type. Other flags return their
+// string form.
+type FlagDatasource struct {
+	Flags           *flag.FlagSet
+	IncludeDefaults bool
+}
+
+// NewFlagDatasource creates a new flag datasource. The flags should already be
+// parsed.
+func NewFlagDatasource(flags *flag.FlagSet) *FlagDatasource {
+	return &FlagDatasource{Flags: flags}
+}
+
+// Value returns the value of the named flag, or nil if it is not set.
+func (f *FlagDatasource) Value(key string) interface{} {
+	fl := f.Flags.Lookup(key)
+	if fl == nil || !(f.IncludeDefaults || f.isSet(key)) {
+		return nil
+	}
+	if g, ok := fl.Value.(flag.Getter); ok {
+		return g.Get()
+	}
+	return fl.Value.String()
+}
+
+// Get returns the value of the named flag, or the default value.
+func (f *FlagDatasource) Get(key string, defaultVal interface{}) interface{} {
+	return DatasourceGet(f, key, defaultVal)
+}
+
+// Has returns the value of the named flag, and whether it is set.
+func (f *FlagDatasource) Has(key string) (interface{}, bool) {
+	return DatasourceHas(f, key)
+}
+
+// isSet checks whether a flag was set on the command line.
+func (f *FlagDatasource) isSet(key string) bool {
+	set := false
+	f.Flags.Visit(func(fl *flag.Flag) {
+		if fl.Name == key {
+			set = true
+		}
+	})
+	return set
+}
@@ -9,7 +9,12 @@ KeyValueDatasource will both be Getter implementations.
 */
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Getter can get values in two ways.
@@ -24,9 +29,10 @@ import (
 // an interface. For that reason, you may need to wrap Cxt in GettableCxt
 // to make it a true Getter.
 //
-// In Cookoo 1.x, KeyValueDatasource uses Value() instead of Get()/Has(). For
-// that reason, you can wrap a KeyValueDatasource in a GettableDS() to make
-// it behave like a Getter.
+// In Cookoo 1.x, KeyValueDatasource uses Value() instead of Get()/Has(). The
+// built-in datasources also implement Getter, so they can be used directly.
+// Other datasources can be wrapped in a GettableDS() to make them behave like
+// a Getter.
 type Getter interface {
 	Get(string, interface{}) interface{}
 	Has(string) (interface{}, bool)
@@ -34,10 +40,15 @@ type Getter interface {
 
 // GettableDS makes a KeyValueDatasource into a Getter.
 //
-// This is forward-compatibility code, and will be rendered unnecessary in
-// Cookoo 2.x.
+// If the datasource already implements Getter, it is returned as-is.
+//
+// Deprecated: The built-in datasources implement Getter directly. Custom
+// datasources can implement Get and Has with DatasourceGet and DatasourceHas.
 func GettableDS(ds KeyValueDatasource) Getter {
-	return &gettableDatasource{ds}
+	if g, ok := ds.(Getter); ok {
+		return g
+	}
+	return &GettableDatasource{ds}
 }
 
 // GettableCxt makes a Context into a Getter.
@@ -48,29 +59,45 @@ func GettableCxt(cxt Context) Getter {
 	return &gettableContext{cxt}
 }
 
-// GettableDatasource Makes a KeyValueDatasource match the Getter interface.
+// DatasourceGet implements Getter.Get for a KeyValueDatasource.
 //
-// In future versions of Cookoo, core Datasources will directly implement Getter.
-type gettableDatasource struct {
-	KeyValueDatasource
-}
-
-func (g *gettableDatasource) Get(key string, defaultVal interface{}) interface{} {
-	ret := g.KeyValueDatasource.Value(key)
+// If the datasource returns nil for the key, the default value is returned.
+func DatasourceGet(ds KeyValueDatasource, key string, defaultVal interface{}) interface{} {
+	ret := ds.Value(key)
 	if ret == nil || !reflect.ValueOf(ret).IsValid() {
 		return defaultVal
 	}
 	return ret
 }
 
-func (g *gettableDatasource) Has(key string) (interface{}, bool) {
-	ret := g.KeyValueDatasource.Value(key)
+// DatasourceHas implements Getter.Has for a KeyValueDatasource.
+//
+// A nil value is treated as not found.
+func DatasourceHas(ds KeyValueDatasource, key string) (interface{}, bool) {
+	ret := ds.Value(key)
 	if ret == nil || !reflect.ValueOf(ret).IsValid() {
 		return nil, false
 	}
 	return ret, true
 }
 
+// GettableDatasource makes a KeyValueDatasource match the Getter interface.
+//
+// Deprecated: The built-in datasources implement Getter directly.
+type GettableDatasource struct {
+	KeyValueDatasource
+}
+
+// Get returns the datasource's value for key, or the default value.
+func (g *GettableDatasource) Get(key string, defaultVal interface{}) interface{} {
+	return DatasourceGet(g.KeyValueDatasource, key, defaultVal)
+}
+
+// Has returns the datasource's value for key, and whether it was found.
+func (g *GettableDatasource) Has(key string) (interface{}, bool) {
+	return DatasourceHas(g.KeyValueDatasource, key)
+}
+
 // GettableContext wraps a context and makes it a Getter.
 // Since Context returns ContextValue objects, we have to write this stupid wrapper.
 type gettableContext struct {
@@ -85,180 +112,476 @@ func (g *gettableContext) Has(key string) (interface{}, bool) {
 	return g.Context.Has(key)
 }
 
+// ValueTypeError indicates that a value was not of the expected type.
+//
+// This is returned by the error-returning getters, such as GetStringE.
+type ValueTypeError struct {
+	Key      string
+	Actual   reflect.Type
+	Expected reflect.Type
+}
+
+func (e *ValueTypeError) Error() string {
+	return fmt.Sprintf("cookoo: value for key %q is %s, not %s", e.Key, e.Actual, e.Expected)
+}
+
+// typeMismatch returns a ValueTypeError for a value of the wrong type.
+// A nil value is treated as missing, and returns nil.
+func typeMismatch(key string, val interface{}, expected reflect.Type) error {
+	if val == nil {
+		return nil
+	}
+	return &ValueTypeError{Key: key, Actual: reflect.TypeOf(val), Expected: expected}
+}
+
+// GetValue gets a value of any type from any Getter.
+//
+// If the key is not found, or the value is not a T, the default value is
+// returned. This works for types that have no dedicated helper:
+//
+// 	timeout := GetValue[time.Duration]("timeout", 5*time.Second, params)
+func GetValue[T any](key string, defaultValue T, source Getter) T {
+	ret, _ := GetValueE(key, defaultValue, source)
+	return ret
+}
+
+// GetValueE gets a value of any type from any Getter, returning an error if
+// the value is the wrong type.
+//
+// If the key is not found, the default value is returned with no error. If
+// the value is not a T, the default value is returned along with a
+// *ValueTypeError.
+func GetValueE[T any](key string, defaultValue T, source Getter) (T, error) {
+	out := source.Get(key, defaultValue)
+	if ret, ok := out.(T); ok {
+		return ret, nil
+	}
+	return defaultValue, typeMismatch(key, out, reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// HasValue returns the value for key, and a flag indicating whether it was
+// found.
+//
+// If the value is not a T, the flag is false. If ok is false, the value will
+// be the zero value of T.
+func HasValue[T any](key string, source Getter) (T, bool) {
+	var zero T
+	v, ok := source.Has(key)
+	if !ok {
+		return zero, false
+	}
+	val, ok := v.(T)
+	if !ok {
+		return zero, false
+	}
+	return val, true
+}
+
+// GetAs gets a value of any type from any Getter, converting it if needed.
+//
+// This is like GetValue, but when the value is not already a T, GetAs tries
+// a safe conversion before returning the default:
+//
+// 	- Numbers convert to other numeric types if no information is lost. So
+// 	  an int converts to an int64, and a float64 of 2.0 converts to an int,
+// 	  but 2.5 does not, and neither does -1 to a uint.
+// 	- A json.Number converts to any numeric type it fits in, or to a string.
+// 	- Other values convert to named types with the same underlying kind, such
+// 	  as a string to a `type Role string`.
+//
+// The named helpers, such as GetInt, do not convert. They only accept values
+// of exactly the right type.
+func GetAs[T any](key string, defaultValue T, source Getter) T {
+	if v, ok := HasAs[T](key, source); ok {
+		return v
+	}
+	return defaultValue
+}
+
+// HasAs returns the value for key converted to a T, and a flag indicating
+// whether it was found and could be converted. See GetAs.
+func HasAs[T any](key string, source Getter) (T, bool) {
+	var zero T
+	v, ok := source.Has(key)
+	if !ok || v == nil {
+		return zero, false
+	}
+	if val, ok := v.(T); ok {
+		return val, true
+	}
+	out, ok := convertValue(v, reflect.TypeOf((*T)(nil)).Elem())
+	if !ok {
+		return zero, false
+	}
+	return out.Interface().(T), true
+}
+
+// convertValue converts a value to the target type, if it can be done safely.
+func convertValue(v interface{}, target reflect.Type) (reflect.Value, bool) {
+	if n, ok := v.(json.Number); ok {
+		switch {
+		case target.Kind() == reflect.String:
+			return reflect.ValueOf(n.String()).Convert(target), true
+		case isInt(target.Kind()) || isUint(target.Kind()):
+			i, err := n.Int64()
+			if err != nil {
+				return reflect.Value{}, false
+			}
+			v = i
+		case isFloat(target.Kind()):
+			f, err := n.Float64()
+			if err != nil {
+				return reflect.Value{}, false
+			}
+			v = f
+		default:
+			return reflect.Value{}, false
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.Type().ConvertibleTo(target) {
+		return reflect.Value{}, false
+	}
+
+	from, to := rv.Kind(), target.Kind()
+	if isNumber(from) && isNumber(to) {
+		if isInt(from) && isUint(to) && rv.Int() < 0 {
+			return reflect.Value{}, false
+		}
+		if isFloat(from) && isUint(to) && rv.Float() < 0 {
+			return reflect.Value{}, false
+		}
+		out := rv.Convert(target)
+		// The conversion must round-trip, or information was lost.
+		if out.Convert(rv.Type()).Interface() != rv.Interface() {
+			return reflect.Value{}, false
+		}
+		return out, true
+	}
+	if from != to {
+		return reflect.Value{}, false
+	}
+	return rv.Convert(target), true
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+func isNumber(k reflect.Kind) bool {
+	return isInt(k) || isUint(k) || isFloat(k)
+}
+
 // GetString is a convenience function for getting strings.
 //
 // This simplifies getting strings from a Context, a Params, or a
 // GettableDatasource.
 func GetString(key, defaultValue string, source Getter) string {
-	out := source.Get(key, defaultValue)
-	ret, ok := out.(string)
-	if !ok {
-		return defaultValue
-	}
-	return ret
+	return GetValue(key, defaultValue, source)
+}
+
+// GetStringE gets a string from any Getter, returning an error if the value is the wrong type.
+//
+// If the key is not found, the default value is returned with no error. If
+// the value is not a string, the default value is returned along with a
+// *ValueTypeError.
+func GetStringE(key, defaultValue string, source Getter) (string, error) {
+	return GetValueE(key, defaultValue, source)
 }
 
 // GetBool gets a boolean value from any Getter.
 func GetBool(key string, defaultValue bool, source Getter) bool {
-	out := source.Get(key, defaultValue)
-	ret, ok := out.(bool)
-	if !ok {
-		return defaultValue
-	}
-	return ret
+	return GetValue(key, defaultValue, source)
+}
+
+// GetBoolE gets a bool from any Getter. See GetStringE.
+func GetBoolE(key string, defaultValue bool, source Getter) (bool, error) {
+	return GetValueE(key, defaultValue, source)
 }
 
 // GetInt gets an int from any Getter.
 func GetInt(key string, defaultValue int, source Getter) int {
-	out := source.Get(key, defaultValue)
-	ret, ok := out.(int)
-	if !ok {
-		return defaultValue
-	}
-	return ret
+	return GetValue(key, defaultValue, source)
+}
+
+// GetIntE gets an int from any Getter. See GetStringE.
+func GetIntE(key string, defaultValue int, source Getter) (int, error) {
+	return GetValueE(key, defaultValue, source)
 }
 
 // GetInt64 gets an int64 from any Getter.
 func GetInt64(key string, defaultValue int64, source Getter) int64 {
-	out := source.Get(key, defaultValue)
-	ret, ok := out.(int64)
-	if !ok {
-		return defaultValue
-	}
-	return ret
+	return GetValue(key, defaultValue, source)
+}
+
+// GetInt64E gets an int64 from any Getter. See GetStringE.
+func GetInt64E(key string, defaultValue int64, source Getter) (int64, error) {
+	return GetValueE(key, defaultValue, source)
 }
 
 // GetInt32 gets an int32 from any Getter.
 func GetInt32(key string, defaultValue int32, source Getter) int32 {
-	out := source.Get(key, defaultValue)
-	ret, ok := out.(int32)
-	if !ok {
-		return defaultValue
-	}
-	return ret
+	return GetValue(key, defaultValue, source)
+}
+
+// GetInt32E gets an int32 from any Getter. See GetStringE.
+func GetInt32E(key string, defaultValue int32, source Getter) (int32, error) {
+	return GetValueE(key, defaultValue, source)
 }
 
 // GetUint64 gets a uint64 from any Getter.
 func GetUint64(key string, defaultVal uint64, source Getter) uint64 {
-	out := source.Get(key, defaultVal)
-	ret, ok := out.(uint64)
-	if !ok {
-		return defaultVal
-	}
-	return ret
+	return GetValue(key, defaultVal, source)
+}
+
+// GetUint64E gets a uint64 from any Getter. See GetStringE.
+func GetUint64E(key string, defaultVal uint64, source Getter) (uint64, error) {
+	return GetValueE(key, defaultVal, source)
 }
 
 // GetFloat64 gets a float64 from any Getter.
 func GetFloat64(key string, defaultVal float64, source Getter) float64 {
-	out := source.Get(key, defaultVal)
-	ret, ok := out.(float64)
-	if !ok {
-		return defaultVal
-	}
-	return ret
+	return GetValue(key, defaultVal, source)
+}
+
+// GetFloat64E gets a float64 from any Getter. See GetStringE.
+func GetFloat64E(key string, defaultVal float64, source Getter) (float64, error) {
+	return GetValueE(key, defaultVal, source)
 }
 
 // HasString is a convenience function to perform Has() and return a string.
 func HasString(key string, source Getter) (string, bool) {
-	v, ok := source.Has(key)
-	if !ok {
-		return "", ok
-	}
-	strval, kk := v.(string)
-	if !kk {
-		return "", kk
-	}
-	return strval, kk
+	return HasValue[string](key, source)
 }
 
 // HasBool returns the value and a flag indicated whether the flag value was found.
 //
 // Default value is false if ok is false.
 func HasBool(key string, source Getter) (bool, bool) {
-	v, ok := source.Has(key)
-	if !ok {
-		return false, ok
-	}
-	strval, kk := v.(bool)
-	if !kk {
-		return false, kk
-	}
-	return strval, kk
+	return HasValue[bool](key, source)
 }
 
 // HasInt returns the int value for key, and a flag indicated if it was found.
 //
 // If ok is false, the int value will be 0
 func HasInt(key string, source Getter) (int, bool) {
-	v, ok := source.Has(key)
-	if !ok {
-		return 0, ok
-	}
-	val, kk := v.(int)
-	if !kk {
-		return 0, kk
-	}
-	return val, kk
+	return HasValue[int](key, source)
 }
 
 // HasInt64 returns the int64 value for key, and a flag indicated if it was found.
 //
 // If ok is false, the int value will be 0
 func HasInt64(key string, source Getter) (int64, bool) {
-	v, ok := source.Has(key)
-	if !ok {
-		return 0, ok
-	}
-	val, kk := v.(int64)
-	if !kk {
-		return 0, kk
-	}
-	return val, kk
+	return HasValue[int64](key, source)
 }
 
 // HasInt32 returns the int32 value for key, and a flag indicated if it was found.
 //
 // If ok is false, the int value will be 0
 func HasInt32(key string, source Getter) (int32, bool) {
+	return HasValue[int32](key, source)
+}
+
+// HasUint64 returns the uint64 value for key, and a flag indicated if it was found.
+//
+// If ok is false, the int value will be 0
+func HasUint64(key string, source Getter) (uint64, bool) {
+	return HasValue[uint64](key, source)
+}
+
+// HasFloat64 returns the float64 value for key, and a flag indicated if it was found.
+//
+// If ok is false, the float value will be 0
+func HasFloat64(key string, source Getter) (float64, bool) {
+	return HasValue[float64](key, source)
+}
+
+// GetIntCoerce gets an int from any Getter, parsing it if it is a string.
+//
+// This is useful for values from environment variables, query parameters,
+// and other sources where everything is a string. If the value is a string
+// that cannot be parsed, or is some other type, the default value is
+// returned.
+func GetIntCoerce(key string, defaultValue int, source Getter) int {
+	ret, _ := GetIntCoerceE(key, defaultValue, source)
+	return ret
+}
+
+// GetIntCoerceE is like GetIntCoerce, but returns an error if the value could not be used.
+//
+// If the key is not found, the default value is returned with no error.
+func GetIntCoerceE(key string, defaultValue int, source Getter) (int, error) {
+	out := source.Get(key, defaultValue)
+	switch v := out.(type) {
+	case int:
+		return v, nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return defaultValue, coerceError(key, v, "int", err)
+		}
+		return i, nil
+	}
+	return defaultValue, typeMismatch(key, out, reflect.TypeOf(defaultValue))
+}
+
+// GetBoolCoerce gets a bool from any Getter, parsing it if it is a string.
+//
+// Strings are parsed with strconv.ParseBool, so "1", "t", "true", and so on
+// are all true. See GetIntCoerce.
+func GetBoolCoerce(key string, defaultValue bool, source Getter) bool {
+	ret, _ := GetBoolCoerceE(key, defaultValue, source)
+	return ret
+}
+
+// GetBoolCoerceE is like GetBoolCoerce, but returns an error if the value could not be used.
+func GetBoolCoerceE(key string, defaultValue bool, source Getter) (bool, error) {
+	out := source.Get(key, defaultValue)
+	switch v := out.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return defaultValue, coerceError(key, v, "bool", err)
+		}
+		return b, nil
+	}
+	return defaultValue, typeMismatch(key, out, reflect.TypeOf(defaultValue))
+}
+
+// GetFloat64Coerce gets a float64 from any Getter, parsing it if it is a string.
+//
+// See GetIntCoerce.
+func GetFloat64Coerce(key string, defaultVal float64, source Getter) float64 {
+	ret, _ := GetFloat64CoerceE(key, defaultVal, source)
+	return ret
+}
+
+// GetFloat64CoerceE is like GetFloat64Coerce, but returns an error if the value could not be used.
+func GetFloat64CoerceE(key string, defaultVal float64, source Getter) (float64, error) {
+	out := source.Get(key, defaultVal)
+	switch v := out.(type) {
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return defaultVal, coerceError(key, v, "float64", err)
+		}
+		return f, nil
+	}
+	return defaultVal, typeMismatch(key, out, reflect.TypeOf(defaultVal))
+}
+
+// coerceError describes a string that could not be parsed.
+func coerceError(key, val, typ string, err error) error {
+	return fmt.Errorf("cookoo: value for key %q is %q, which is not a valid %s: %w", key, val, typ, err)
+}
+
+// GetDuration gets a time.Duration from any Getter.
+//
+// A time.Duration is returned as-is. A string is parsed with
+// time.ParseDuration, so "30s" and "5m" both work. An int or int64 is
+// treated as a number of seconds. If the value is none of these, or is a
+// string that cannot be parsed, the default value is returned.
+func GetDuration(key string, defaultValue time.Duration, source Getter) time.Duration {
+	if v, ok := HasDuration(key, source); ok {
+		return v
+	}
+	return defaultValue
+}
+
+// HasDuration returns the time.Duration value for key, and a flag indicating if it was found.
+//
+// Values are converted as in GetDuration. If the value cannot be converted,
+// ok is false and the duration will be 0.
+func HasDuration(key string, source Getter) (time.Duration, bool) {
 	v, ok := source.Has(key)
 	if !ok {
-		return 0, ok
+		return 0, false
 	}
-	val, kk := v.(int32)
-	if !kk {
-		return 0, kk
+	switch val := v.(type) {
+	case time.Duration:
+		return val, true
+	case string:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return 0, false
+		}
+		return d, true
+	case int:
+		return time.Duration(val) * time.Second, true
+	case int64:
+		return time.Duration(val) * time.Second, true
 	}
-	return val, kk
+	return 0, false
 }
 
-// HasUint64 returns the uint64 value for key, and a flag indicated if it was found.
+// GetStringSlice gets a []string from any Getter.
 //
-// If ok is false, the int value will be 0
-func HasUint64(key string, source Getter) (uint64, bool) {
+// If the value is a single string, it is promoted to a one-element slice.
+// This is common for values that come from form fields or query parameters,
+// which may hold one value or many. Otherwise, if the value is not a
+// []string, the default value is returned.
+func GetStringSlice(key string, defaultValue []string, source Getter) []string {
+	if v, ok := HasStringSlice(key, source); ok {
+		return v
+	}
+	return defaultValue
+}
+
+// HasStringSlice returns the []string value for key, and a flag indicating if it was found.
+//
+// A single string is promoted to a one-element slice, as in GetStringSlice.
+func HasStringSlice(key string, source Getter) ([]string, bool) {
 	v, ok := source.Has(key)
 	if !ok {
-		return 0, ok
+		return nil, false
 	}
-	val, kk := v.(uint64)
-	if !kk {
-		return 0, kk
+	switch val := v.(type) {
+	case []string:
+		return val, true
+	case string:
+		return []string{val}, true
 	}
-	return val, kk
+	return nil, false
 }
 
-// HasFloat64 returns the float64 value for key, and a flag indicated if it was found.
+// GetIntSlice gets an []int from any Getter.
 //
-// If ok is false, the float value will be 0
-func HasFloat64(key string, source Getter) (float64, bool) {
+// Like GetStringSlice, a single int is promoted to a one-element slice.
+func GetIntSlice(key string, defaultValue []int, source Getter) []int {
+	if v, ok := HasIntSlice(key, source); ok {
+		return v
+	}
+	return defaultValue
+}
+
+// HasIntSlice returns the []int value for key, and a flag indicating if it was found.
+//
+// A single int is promoted to a one-element slice.
+func HasIntSlice(key string, source Getter) ([]int, bool) {
 	v, ok := source.Has(key)
 	if !ok {
-		return 0, ok
+		return nil, false
 	}
-	val, kk := v.(float64)
-	if !kk {
-		return 0, kk
+	switch val := v.(type) {
+	case []int:
+		return val, true
+	case int:
+		return []int{val}, true
 	}
-	return val, kk
+	return nil, false
 }
 
 // GetFromFirst gets the value from the first Getter that has the key.
@@ -279,6 +602,130 @@ func GetFromFirst(key string, defaultVal interface{}, sources ...Getter) (interf
 	return defaultVal, &DefaultGetter{defaultVal}
 }
 
+// ChainedGetter is a Getter that checks several Getters in order.
+//
+// Unlike GetFromFirst, a ChainedGetter is itself a Getter, so it can be
+// passed to the Get* and Has* helpers. It is also a KeyValueDatasource, so it
+// can be added to a context and used in From() clauses. This makes it easy
+// to layer configuration sources:
+//
+// 	cfg := cookoo.ChainedGetter{
+// 		cookoo.NewEnvDatasource("MYAPP_"),
+// 		cookoo.NewFlagDatasource(flag.CommandLine),
+// 	}
+// 	cxt.AddDatasource("cfg", cfg)
+//
+// 	port := cookoo.GetString("PORT", "8080", cfg)
+//
+// In the example above, PORT is read from $MYAPP_PORT, then from the -PORT
+// flag, and then the default is used.
+type ChainedGetter []Getter
+
+// Get returns the value from the first Getter that has the key, or the
+// default value.
+func (c ChainedGetter) Get(key string, defaultVal interface{}) interface{} {
+	if v, ok := c.Has(key); ok {
+		return v
+	}
+	return defaultVal
+}
+
+// Has returns the value from the first Getter that has the key.
+func (c ChainedGetter) Has(key string) (interface{}, bool) {
+	for _, g := range c {
+		if v, ok := g.Has(key); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Value returns the value from the first Getter that has the key, or nil.
+func (c ChainedGetter) Value(key string) interface{} {
+	v, _ := c.Has(key)
+	return v
+}
+
+// MissingKeysError indicates that required keys were not found.
+type MissingKeysError struct {
+	Keys []string
+}
+
+func (e *MissingKeysError) Error() string {
+	if len(e.Keys) == 1 {
+		return fmt.Sprintf("cookoo: missing required key %q", e.Keys[0])
+	}
+	quoted := make([]string, len(e.Keys))
+	for i, k := range e.Keys {
+		quoted[i] = strconv.Quote(k)
+	}
+	return "cookoo: missing required keys " + strings.Join(quoted, ", ")
+}
+
+// Require checks that a Getter has every one of the given keys.
+//
+// This makes it easy for a command to validate its required params at the
+// top:
+//
+// 	if err := cookoo.Require(params, "name", "email"); err != nil {
+// 		return nil, &cookoo.FatalError{Message: err.Error()}
+// 	}
+//
+// If any keys are missing, a *MissingKeysError listing all of them is
+// returned.
+func Require(source Getter, keys ...string) error {
+	var missing []string
+	for _, k := range keys {
+		if _, ok := source.Has(k); !ok {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingKeysError{Keys: missing}
+	}
+	return nil
+}
+
+// MustGetString gets a string that is required to be present.
+//
+// If the key is missing, a *MissingKeysError is returned. If the value is
+// not a string, a *ValueTypeError is returned.
+func MustGetString(key string, source Getter) (string, error) {
+	v, ok := source.Has(key)
+	if !ok {
+		return "", &MissingKeysError{Keys: []string{key}}
+	}
+	str, ok := v.(string)
+	if !ok {
+		return "", &ValueTypeError{Key: key, Actual: reflect.TypeOf(v), Expected: reflect.TypeOf("")}
+	}
+	return str, nil
+}
+
+// GetFirstOf gets the first value found for any of several keys.
+//
+// This is useful when a value may go by different names in different
+// sources, such as an environment variable MY_PORT and a param "port".
+//
+// The keys are the outer loop: every source is checked for the first key,
+// then every source is checked for the second key, and so on. So the order
+// of the keys is honored across all sources, and a later key never overrides
+// an earlier one.
+//
+// The value, the key that matched, and the Getter that had it are returned.
+// If nothing matches, the value is nil, the key is empty, and the Getter is a
+// DefaultGetter.
+func GetFirstOf(sources []Getter, keys ...string) (ContextValue, string, Getter) {
+	for _, key := range keys {
+		for _, s := range sources {
+			if val, ok := s.Has(key); ok {
+				return val, key, s
+			}
+		}
+	}
+	return nil, "", &DefaultGetter{nil}
+}
+
 // DefaultGetter represents a Getter instance for a default value.
 //
 // A default getter always returns the given default value.
@@ -292,3 +739,155 @@ func (e *DefaultGetter) Get(name string, value interface{}) interface{} {
 func (e *DefaultGetter) Has(name string) (interface{}, bool) {
 	return e.val, true
 }
+
+// FallbackGetter is a Getter that consults a function when a key is missing.
+//
+// When the Inner Getter does not have a key, the Fallback function is called.
+// If the Fallback returns true, its value is used. Otherwise, Get returns the
+// default value and Has returns false.
+//
+// This is useful for sources that compute or lazily load values on demand.
+//
+// Example:
+//
+// 	g := &FallbackGetter{
+// 		Inner: params,
+// 		Fallback: func(key string) (ContextValue, bool) {
+// 			return config.Lookup(key)
+// 		},
+// 	}
+// 	port := GetInt("port", 8080, g)
+type FallbackGetter struct {
+	Inner    Getter
+	Fallback func(key string) (ContextValue, bool)
+}
+
+// Get returns the value from the Inner Getter, the Fallback, or the default, in that order.
+func (f *FallbackGetter) Get(key string, defaultVal interface{}) interface{} {
+	if v, ok := f.Has(key); ok {
+		return v
+	}
+	return defaultVal
+}
+
+// Has checks the Inner Getter and then the Fallback for a value.
+func (f *FallbackGetter) Has(key string) (interface{}, bool) {
+	if f.Inner != nil {
+		if v, ok := f.Inner.Has(key); ok {
+			return v, true
+		}
+	}
+	if f.Fallback != nil {
+		if v, ok := f.Fallback(key); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// CaseInsensitiveGetter wraps a Getter so that keys are lowercased.
+//
+// Both Get and Has lowercase the key before it is passed to the inner Getter.
+// So this expects the inner Getter to store its keys in lowercase:
+//
+// 	g := CaseInsensitiveGetter(headers)
+// 	g.Get("Content-Type", "") // Reads "content-type"
+func CaseInsensitiveGetter(inner Getter) Getter {
+	return &caseInsensitiveGetter{inner}
+}
+
+type caseInsensitiveGetter struct {
+	inner Getter
+}
+
+func (c *caseInsensitiveGetter) Get(key string, defaultVal interface{}) interface{} {
+	return c.inner.Get(strings.ToLower(key), defaultVal)
+}
+
+func (c *caseInsensitiveGetter) Has(key string) (interface{}, bool) {
+	return c.inner.Has(strings.ToLower(key))
+}
+
+// PrefixGetter wraps a Getter so that every key is given a prefix.
+//
+// This is useful for reading namespaced values. For example, with the prefix
+// "myapp.", `Get("port", 80)` reads "myapp.port" from the inner Getter.
+//
+// PrefixGetter and CaseInsensitiveGetter can be combined. When a
+// CaseInsensitiveGetter wraps a PrefixGetter, only the key is lowercased.
+// When a PrefixGetter wraps a CaseInsensitiveGetter, the prefix is lowercased
+// too.
+func PrefixGetter(prefix string, inner Getter) Getter {
+	return &prefixGetter{prefix, inner}
+}
+
+type prefixGetter struct {
+	prefix string
+	inner  Getter
+}
+
+func (p *prefixGetter) Get(key string, defaultVal interface{}) interface{} {
+	return p.inner.Get(p.prefix+key, defaultVal)
+}
+
+func (p *prefixGetter) Has(key string) (interface{}, bool) {
+	return p.inner.Has(p.prefix + key)
+}
+
+// SourceInfo describes where GetWithSource found a value.
+type SourceInfo struct {
+	// Index is the position of the source in the list of sources, or -1 if
+	// the default value was used.
+	Index int
+	// Name is the name of the source. See NamedGetter.
+	Name string
+	// Default is true if no source had the key and the default was used.
+	Default bool
+	// Getter is the Getter that supplied the value.
+	Getter Getter
+}
+
+// GetWithSource gets a value from the first Getter that has the key, and
+// describes where the value came from.
+//
+// This behaves like GetFromFirst, but returns a SourceInfo that can be used
+// for auditing configuration. If a source has a `Name() string` method (as
+// those created with NamedGetter do), that name is used in the SourceInfo.
+// Otherwise, the name is the Go type of the source.
+//
+// If no source has the key, the default value is returned, and the
+// SourceInfo is marked as Default with the name "default".
+func GetWithSource(key string, defaultVal interface{}, sources ...Getter) (ContextValue, SourceInfo) {
+	for i, s := range sources {
+		if val, ok := s.Has(key); ok {
+			return val, SourceInfo{Index: i, Name: getterName(s), Getter: s}
+		}
+	}
+	return defaultVal, SourceInfo{Index: -1, Name: "default", Default: true, Getter: &DefaultGetter{defaultVal}}
+}
+
+// NamedGetter attaches a name to a Getter.
+//
+// The name is reported by GetWithSource.
+func NamedGetter(name string, g Getter) Getter {
+	return &namedGetter{g, name}
+}
+
+type namedGetter struct {
+	Getter
+	name string
+}
+
+// Name returns the name of the Getter.
+func (n *namedGetter) Name() string {
+	return n.name
+}
+
+func getterName(g Getter) string {
+	if n, ok := g.(interface {
+		Name() string
+	}); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", g)
+}
@@ -1,6 +1,12 @@
 package cookoo
 
-import "testing"
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
 
 type testDs struct {
 	val string
@@ -71,6 +77,360 @@ func TestGetters (t *testing.T) {
 	}
 }
 
+func TestGettersE(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"string": "hello",
+		"int":    42,
+		"bool":   true,
+		"nil":    nil,
+	})
+
+	if v, err := GetStringE("string", "boo", p); err != nil || v != "hello" {
+		t.Errorf("! Expected hello, got %s (%v)", v, err)
+	}
+	if v, err := GetStringE("missing", "boo", p); err != nil || v != "boo" {
+		t.Errorf("! Expected the default, got %s (%v)", v, err)
+	}
+	if v, err := GetIntE("nil", 7, p); err != nil || v != 7 {
+		t.Errorf("! Expected a nil value to be treated as missing, got %d (%v)", v, err)
+	}
+
+	v, err := GetStringE("int", "boo", p)
+	if err == nil {
+		t.Fatal("! Expected an error for an int.")
+	}
+	if v != "boo" {
+		t.Errorf("! Expected the default, got %s", v)
+	}
+	if err.Error() != `cookoo: value for key "int" is int, not string` {
+		t.Errorf("! Unexpected error message: %s", err)
+	}
+	te, ok := err.(*ValueTypeError)
+	if !ok {
+		t.Fatalf("! Expected a *ValueTypeError, got %T", err)
+	}
+	if te.Key != "int" || te.Actual.Kind() != reflect.Int || te.Expected.Kind() != reflect.String {
+		t.Errorf("! Unexpected error fields: %+v", te)
+	}
+
+	if _, err := GetIntE("string", 0, p); err == nil {
+		t.Error("! Expected an error for a string.")
+	}
+	if _, err := GetBoolE("int", false, p); err == nil {
+		t.Error("! Expected an error for an int.")
+	}
+	if _, err := GetFloat64E("int", 0, p); err == nil {
+		t.Error("! Expected an error for an int.")
+	}
+
+	// The plain getters still return the default.
+	if v := GetInt("string", 3, p); v != 3 {
+		t.Errorf("! Expected the default, got %d", v)
+	}
+}
+
+type getterPoint struct {
+	X, Y int
+}
+
+func TestGetValue(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"string":  "hello",
+		"int":     42,
+		"point":   getterPoint{1, 2},
+		"timeout": 3 * time.Second,
+	})
+
+	// Hits
+	if v := GetValue("string", "boo", p); v != "hello" {
+		t.Errorf("! Expected hello, got %s", v)
+	}
+	if v := GetValue("int", 0, p); v != 42 {
+		t.Errorf("! Expected 42, got %d", v)
+	}
+	if v := GetValue("point", getterPoint{}, p); v != (getterPoint{1, 2}) {
+		t.Errorf("! Expected {1 2}, got %v", v)
+	}
+	if v := GetValue[time.Duration]("timeout", 5*time.Second, p); v != 3*time.Second {
+		t.Errorf("! Expected 3s, got %s", v)
+	}
+
+	// Misses
+	if v := GetValue("nope", "boo", p); v != "boo" {
+		t.Errorf("! Expected boo, got %s", v)
+	}
+	if v := GetValue("nope", 7, p); v != 7 {
+		t.Errorf("! Expected 7, got %d", v)
+	}
+	if v := GetValue("nope", getterPoint{3, 4}, p); v != (getterPoint{3, 4}) {
+		t.Errorf("! Expected {3 4}, got %v", v)
+	}
+
+	// Wrong types
+	if v := GetValue("int", "boo", p); v != "boo" {
+		t.Errorf("! Expected boo, got %s", v)
+	}
+	if v := GetValue("string", 7, p); v != 7 {
+		t.Errorf("! Expected 7, got %d", v)
+	}
+	if v := GetValue("string", getterPoint{3, 4}, p); v != (getterPoint{3, 4}) {
+		t.Errorf("! Expected {3 4}, got %v", v)
+	}
+	if _, err := GetValueE("string", getterPoint{}, p); err == nil {
+		t.Error("! Expected an error for a string.")
+	}
+}
+
+type getterRole string
+
+func TestGetAs(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"int":      42,
+		"negative": -1,
+		"whole":    2.0,
+		"half":     2.5,
+		"big":      int64(1) << 40,
+		"number":   json.Number("12"),
+		"decimal":  json.Number("1.5"),
+		"role":     "admin",
+		"point":    getterPoint{1, 2},
+	})
+
+	if v := GetAs[int64]("int", 0, p); v != 42 {
+		t.Errorf("! Expected int to convert to int64, got %d", v)
+	}
+	if v := GetAs[float64]("int", 0, p); v != 42 {
+		t.Errorf("! Expected int to convert to float64, got %f", v)
+	}
+	if v := GetAs[int]("whole", 0, p); v != 2 {
+		t.Errorf("! Expected 2.0 to convert to int, got %d", v)
+	}
+	if v := GetAs[int]("half", 7, p); v != 7 {
+		t.Errorf("! Expected 2.5 not to convert to int, got %d", v)
+	}
+	if v := GetAs[uint]("negative", 7, p); v != 7 {
+		t.Errorf("! Expected -1 not to convert to uint, got %d", v)
+	}
+	if v := GetAs[int32]("big", 7, p); v != 7 {
+		t.Errorf("! Expected an overflow not to convert, got %d", v)
+	}
+	if v := GetAs[float64]("number", 0, p); v != 12 {
+		t.Errorf("! Expected json.Number to convert to float64, got %f", v)
+	}
+	if v := GetAs[int]("number", 0, p); v != 12 {
+		t.Errorf("! Expected json.Number to convert to int, got %d", v)
+	}
+	if v := GetAs[int]("decimal", 7, p); v != 7 {
+		t.Errorf("! Expected 1.5 not to convert to int, got %d", v)
+	}
+	if v := GetAs[string]("number", "", p); v != "12" {
+		t.Errorf("! Expected json.Number to convert to string, got %s", v)
+	}
+	if v := GetAs[getterRole]("role", "", p); v != "admin" {
+		t.Errorf("! Expected string to convert to a named string, got %s", v)
+	}
+	if v := GetAs("point", getterPoint{}, p); v != (getterPoint{1, 2}) {
+		t.Errorf("! Expected the struct, got %v", v)
+	}
+	if v := GetAs[string]("int", "default", p); v != "default" {
+		t.Errorf("! Expected an int not to convert to string, got %s", v)
+	}
+	if v := GetAs[int]("nope", 7, p); v != 7 {
+		t.Errorf("! Expected the default, got %d", v)
+	}
+
+	if v, ok := HasAs[int64]("int", p); !ok || v != 42 {
+		t.Errorf("! Expected to find 42, got %d", v)
+	}
+	if v, ok := HasAs[int]("half", p); ok || v != 0 {
+		t.Errorf("! Expected 2.5 not to be found as an int, got %d", v)
+	}
+
+	// The named helpers stay strict.
+	if v := GetInt64("int", 7, p); v != 7 {
+		t.Errorf("! Expected GetInt64 not to convert, got %d", v)
+	}
+}
+
+func TestHasValue(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"string": "hello",
+		"int":    42,
+		"point":  getterPoint{1, 2},
+	})
+
+	if v, ok := HasValue[string]("string", p); !ok || v != "hello" {
+		t.Errorf("! Expected hello, got %s", v)
+	}
+	if v, ok := HasValue[int]("int", p); !ok || v != 42 {
+		t.Errorf("! Expected 42, got %d", v)
+	}
+	if v, ok := HasValue[getterPoint]("point", p); !ok || v != (getterPoint{1, 2}) {
+		t.Errorf("! Expected {1 2}, got %v", v)
+	}
+
+	if _, ok := HasValue[string]("nope", p); ok {
+		t.Error("! Expected a missing string to not be found.")
+	}
+	if v, ok := HasValue[int]("string", p); ok || v != 0 {
+		t.Errorf("! Expected a wrong type to give 0, false. Got %d, %t", v, ok)
+	}
+	if v, ok := HasValue[getterPoint]("int", p); ok || v != (getterPoint{}) {
+		t.Errorf("! Expected a wrong type to give the zero value. Got %v", v)
+	}
+}
+
+func TestCoerceGetters(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"int":        9090,
+		"intString":  "9090",
+		"bool":       true,
+		"boolString": "t",
+		"float":      0.5,
+		"floatStr":   "0.25",
+		"garbage":    "abc",
+		"wrong":      []string{"1"},
+	})
+
+	if v := GetIntCoerce("int", 8080, p); v != 9090 {
+		t.Errorf("! Expected 9090, got %d", v)
+	}
+	if v := GetIntCoerce("intString", 8080, p); v != 9090 {
+		t.Errorf("! Expected the string to be parsed, got %d", v)
+	}
+	if v := GetIntCoerce("garbage", 8080, p); v != 8080 {
+		t.Errorf("! Expected the default, got %d", v)
+	}
+	if v := GetIntCoerce("nope", 8080, p); v != 8080 {
+		t.Errorf("! Expected the default, got %d", v)
+	}
+
+	if v := GetBoolCoerce("bool", false, p); !v {
+		t.Error("! Expected true")
+	}
+	if v := GetBoolCoerce("boolString", false, p); !v {
+		t.Error("! Expected the string to be parsed.")
+	}
+	if v := GetBoolCoerce("garbage", true, p); !v {
+		t.Error("! Expected the default.")
+	}
+
+	if v := GetFloat64Coerce("float", 0, p); v != 0.5 {
+		t.Errorf("! Expected 0.5, got %f", v)
+	}
+	if v := GetFloat64Coerce("floatStr", 0, p); v != 0.25 {
+		t.Errorf("! Expected the string to be parsed, got %f", v)
+	}
+	if v := GetFloat64Coerce("garbage", 1.5, p); v != 1.5 {
+		t.Errorf("! Expected the default, got %f", v)
+	}
+
+	if v, err := GetIntCoerceE("garbage", 8080, p); err == nil || v != 8080 {
+		t.Errorf("! Expected an error and the default, got %d, %v", v, err)
+	} else if !strings.Contains(err.Error(), `"garbage"`) {
+		t.Errorf("! Expected the error to name the key, got %s", err)
+	}
+	if _, err := GetBoolCoerceE("wrong", false, p); err == nil {
+		t.Error("! Expected an error for a slice.")
+	}
+	if _, err := GetFloat64CoerceE("nope", 0, p); err != nil {
+		t.Errorf("! Expected no error for a missing key, got %s", err)
+	}
+
+	// Everything from the environment is a string.
+	t.Setenv("COOKOOTEST_PORT", "9191")
+	if v := GetIntCoerce("PORT", 8080, NewEnvDatasource("COOKOOTEST_")); v != 9191 {
+		t.Errorf("! Expected 9191 from the environment, got %d", v)
+	}
+}
+
+func TestDurationGetters(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"typed":   3 * time.Second,
+		"string":  "5m",
+		"int":     30,
+		"int64":   int64(2),
+		"garbage": "soon",
+		"wrong":   1.5,
+	})
+	def := time.Minute
+
+	if v := GetDuration("typed", def, p); v != 3*time.Second {
+		t.Errorf("! Expected 3s, got %s", v)
+	}
+	if v := GetDuration("string", def, p); v != 5*time.Minute {
+		t.Errorf("! Expected 5m, got %s", v)
+	}
+	if v := GetDuration("int", def, p); v != 30*time.Second {
+		t.Errorf("! Expected 30s, got %s", v)
+	}
+	if v := GetDuration("int64", def, p); v != 2*time.Second {
+		t.Errorf("! Expected 2s, got %s", v)
+	}
+	if v := GetDuration("garbage", def, p); v != def {
+		t.Errorf("! Expected the default for a garbage string, got %s", v)
+	}
+	if v := GetDuration("wrong", def, p); v != def {
+		t.Errorf("! Expected the default for a float, got %s", v)
+	}
+	if v := GetDuration("nope", def, p); v != def {
+		t.Errorf("! Expected the default, got %s", v)
+	}
+
+	if v, ok := HasDuration("string", p); !ok || v != 5*time.Minute {
+		t.Errorf("! Expected to find 5m, got %s", v)
+	}
+	if v, ok := HasDuration("garbage", p); ok || v != 0 {
+		t.Errorf("! Expected a garbage string not to be found, got %s", v)
+	}
+}
+
+func TestSliceGetters(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"strings": []string{"a", "b"},
+		"string":  "c",
+		"ints":    []int{1, 2},
+		"int":     3,
+		"wrong":   true,
+	})
+	def := []string{"default"}
+
+	if v := GetStringSlice("strings", def, p); !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Errorf("! Expected [a b], got %v", v)
+	}
+	if v := GetStringSlice("string", def, p); !reflect.DeepEqual(v, []string{"c"}) {
+		t.Errorf("! Expected a single string to be promoted, got %v", v)
+	}
+	if v := GetStringSlice("nope", def, p); !reflect.DeepEqual(v, def) {
+		t.Errorf("! Expected the default, got %v", v)
+	}
+	if v := GetStringSlice("wrong", def, p); !reflect.DeepEqual(v, def) {
+		t.Errorf("! Expected the default for a wrong type, got %v", v)
+	}
+	if v, ok := HasStringSlice("string", p); !ok || len(v) != 1 {
+		t.Errorf("! Expected to find a one-element slice, got %v", v)
+	}
+	if _, ok := HasStringSlice("ints", p); ok {
+		t.Error("! Expected []int not to be a string slice.")
+	}
+
+	if v := GetIntSlice("ints", nil, p); !reflect.DeepEqual(v, []int{1, 2}) {
+		t.Errorf("! Expected [1 2], got %v", v)
+	}
+	if v := GetIntSlice("int", nil, p); !reflect.DeepEqual(v, []int{3}) {
+		t.Errorf("! Expected a single int to be promoted, got %v", v)
+	}
+	if v, ok := HasIntSlice("string", p); ok || v != nil {
+		t.Errorf("! Expected a string not to be an int slice, got %v", v)
+	}
+
+	// Query parameters come from a datasource as single strings.
+	ds := GettableDS(&testDs{"hello"})
+	if v := GetStringSlice("q", nil, ds); !reflect.DeepEqual(v, []string{"hello"}) {
+		t.Errorf("! Expected [hello], got %v", v)
+	}
+}
+
 func TestGetFromFirst(t *testing.T) {
 	ds := &testDs{"hello"}
 	gds := GettableDS(ds)
@@ -101,3 +461,228 @@ func TestGetFromFirst(t *testing.T) {
 	}
 }
 
+
+func TestGetFromFirstRawDatasource(t *testing.T) {
+	t.Setenv("COOKOOTEST_HOST", "example.com")
+
+	c := NewContext()
+	c.AddDatasource("env", NewEnvDatasource("COOKOOTEST_"))
+	p := NewParamsWithValues(map[string]interface{}{"port": 8080})
+
+	ds, ok := c.Datasource("env").(Getter)
+	if !ok {
+		t.Fatal("! Expected EnvDatasource to be a Getter.")
+	}
+
+	v, src := GetFromFirst("HOST", "localhost", ds, p)
+	if v != "example.com" || src != ds {
+		t.Errorf("! Expected example.com from the datasource, got %v from %T", v, src)
+	}
+	v, src = GetFromFirst("port", 80, ds, p)
+	if v != 8080 || src != p {
+		t.Errorf("! Expected 8080 from the params, got %v from %T", v, src)
+	}
+	if v := GetString("NOPE", "default", ds); v != "default" {
+		t.Errorf("! Expected the default for an unset variable, got %s", v)
+	}
+
+	if g := GettableDS(ds.(KeyValueDatasource)); g != ds {
+		t.Error("! Expected GettableDS to return a Getter unchanged.")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"name":  "Matt",
+		"email": "matt@example.com",
+		"age":   42,
+	})
+
+	if err := Require(p, "name", "email", "age"); err != nil {
+		t.Errorf("! Unexpected error: %s", err)
+	}
+
+	err := Require(p, "name", "phone")
+	if err == nil {
+		t.Fatal("! Expected a missing key to fail.")
+	}
+	if err.Error() != `cookoo: missing required key "phone"` {
+		t.Errorf("! Unexpected message: %s", err)
+	}
+
+	err = Require(p, "phone", "name", "address", "city")
+	if err == nil {
+		t.Fatal("! Expected missing keys to fail.")
+	}
+	mk, ok := err.(*MissingKeysError)
+	if !ok {
+		t.Fatalf("! Expected a MissingKeysError, got %T", err)
+	}
+	if !reflect.DeepEqual(mk.Keys, []string{"phone", "address", "city"}) {
+		t.Errorf("! Expected all missing keys, got %v", mk.Keys)
+	}
+	if err.Error() != `cookoo: missing required keys "phone", "address", "city"` {
+		t.Errorf("! Unexpected message: %s", err)
+	}
+}
+
+func TestMustGetString(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{"name": "Matt", "age": 42})
+
+	if v, err := MustGetString("name", p); err != nil || v != "Matt" {
+		t.Errorf("! Expected Matt, got %s (%v)", v, err)
+	}
+	if _, err := MustGetString("nope", p); err == nil {
+		t.Error("! Expected a missing key to fail.")
+	} else if _, ok := err.(*MissingKeysError); !ok {
+		t.Errorf("! Expected a MissingKeysError, got %T", err)
+	}
+	if _, err := MustGetString("age", p); err == nil {
+		t.Error("! Expected an int to fail.")
+	} else if _, ok := err.(*ValueTypeError); !ok {
+		t.Errorf("! Expected a ValueTypeError, got %T", err)
+	}
+}
+
+func TestGetFirstOf(t *testing.T) {
+	env := NewParamsWithValues(map[string]interface{}{"HOST": "env.example.com"})
+	p := NewParamsWithValues(map[string]interface{}{
+		"port": 8080,
+		"host": "params.example.com",
+	})
+	sources := []Getter{env, p}
+
+	v, key, src := GetFirstOf(sources, "MY_PORT", "port")
+	if v != 8080 || key != "port" || src != p {
+		t.Errorf("! Expected 8080 from port in params, got %v from %s in %T", v, key, src)
+	}
+
+	// Keys are the outer loop, so the first key wins even in a later source.
+	v, key, src = GetFirstOf(sources, "host", "HOST")
+	if v != "params.example.com" || key != "host" || src != p {
+		t.Errorf("! Expected host from params, got %v from %s", v, key)
+	}
+
+	v, key, src = GetFirstOf(sources, "nope", "nada")
+	if v != nil || key != "" {
+		t.Errorf("! Expected no match, got %v from %s", v, key)
+	}
+	if _, ok := src.(*DefaultGetter); !ok {
+		t.Errorf("! Expected a DefaultGetter, got %T", src)
+	}
+}
+
+func TestFallbackGetter(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"inner": "hello",
+	})
+	calls := 0
+	g := &FallbackGetter{
+		Inner: p,
+		Fallback: func(key string) (ContextValue, bool) {
+			calls++
+			if key == "lazy" {
+				return "world", true
+			}
+			return nil, false
+		},
+	}
+
+	if v := GetString("inner", "nope", g); v != "hello" {
+		t.Errorf("Expected hello, got %s", v)
+	}
+	if calls != 0 {
+		t.Error("Expected fallback to not be called for a key in the inner Getter.")
+	}
+
+	if v := GetString("lazy", "nope", g); v != "world" {
+		t.Errorf("Expected world, got %s", v)
+	}
+	if v, ok := g.Has("lazy"); !ok || v != "world" {
+		t.Errorf("Expected Has to find world, got %v", v)
+	}
+
+	if v := GetString("missing", "nope", g); v != "nope" {
+		t.Errorf("Expected default, got %s", v)
+	}
+	if _, ok := g.Has("missing"); ok {
+		t.Error("Expected missing key to not be found.")
+	}
+}
+
+type mapDs map[string]interface{}
+
+func (m mapDs) Value(key string) interface{} {
+	return m[key]
+}
+
+func TestCaseInsensitiveAndPrefixGetters(t *testing.T) {
+	ds := &GettableDatasource{mapDs{
+		"myapp.port":  8080,
+		"http_accept": "text/html",
+	}}
+
+	g := CaseInsensitiveGetter(PrefixGetter("myapp.", ds))
+	if v := GetInt("PORT", 80, g); v != 8080 {
+		t.Errorf("! Expected 8080, got %d", v)
+	}
+	if v, ok := g.Has("Port"); !ok || v != 8080 {
+		t.Errorf("! Expected Has to agree with Get, got %v, %t", v, ok)
+	}
+	if _, ok := g.Has("host"); ok {
+		t.Error("! Expected host to be missing.")
+	}
+
+	g = PrefixGetter("MyApp.", CaseInsensitiveGetter(ds))
+	if v := GetInt("Port", 80, g); v != 8080 {
+		t.Errorf("! Expected the prefix to be lowercased too, got %d", v)
+	}
+
+	headers := CaseInsensitiveGetter(ds)
+	v, src := GetFromFirst("HTTP_Accept", "*/*", PrefixGetter("myapp.", ds), headers)
+	if v != "text/html" || src != headers {
+		t.Errorf("! Expected text/html from the headers, got %v", v)
+	}
+	if v := PrefixGetter("myapp.", ds).Get("nope", "default"); v != "default" {
+		t.Errorf("! Expected the default, got %v", v)
+	}
+}
+
+func TestGetWithSource(t *testing.T) {
+	c := NewContext()
+	c.Put("a", "from context")
+	p := NewParamsWithValues(map[string]interface{}{
+		"a": "from params",
+		"b": "from params",
+	})
+	ds := GettableDS(&testDs{"from datasource"})
+
+	sources := []Getter{
+		NamedGetter("context", GettableCxt(c)),
+		NamedGetter("params", p),
+		ds,
+	}
+
+	v, info := GetWithSource("a", "default", sources...)
+	if v != "from context" || info.Index != 0 || info.Name != "context" || info.Default {
+		t.Errorf("Unexpected result for a: %v, %+v", v, info)
+	}
+
+	v, info = GetWithSource("b", "default", sources...)
+	if v != "from params" || info.Index != 1 || info.Name != "params" || info.Default {
+		t.Errorf("Unexpected result for b: %v, %+v", v, info)
+	}
+
+	v, info = GetWithSource("c", "default", sources...)
+	if v != "from datasource" || info.Index != 2 || info.Name != "*cookoo.GettableDatasource" {
+		t.Errorf("Unexpected result for c: %v, %+v", v, info)
+	}
+
+	v, info = GetWithSource("c", "default", sources[:2]...)
+	if v != "default" || info.Index != -1 || info.Name != "default" || !info.Default {
+		t.Errorf("Unexpected result for default: %v, %+v", v, info)
+	}
+	if GetString("c", "", info.Getter) != "default" {
+		t.Error("Expected the default Getter to return the default.")
+	}
+}
@@ -0,0 +1,69 @@
+package cookoo
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+)
+
+// SaveContextGob writes the values of a context to a writer using encoding/gob.
+//
+// This is useful for checkpointing the state of a long-running route so that
+// it can be resumed later with LoadContextGob.
+//
+// This is a function rather than a `GobEncode(io.Writer) error` method on
+// Context. A method with that name would clash with gob.GobEncoder, whose
+// GobEncode method takes no arguments and returns a []byte, so gob would
+// refuse to encode any value that holds a context.
+//
+// Only context values are saved. Datasources and loggers are considered to be
+// part of the running application, not the request, and are not encoded.
+//
+// Values are stored as interfaces, so any concrete type that is not one of
+// Go's basic types must be registered with `gob.Register()` before it can
+// be encoded. Values that cannot be encoded are skipped, and a warning is
+// logged to the context.
+//
+// Example:
+//
+// 	gob.Register(&MyState{})
+// 	cxt.Put("state", &MyState{Step: 2})
+//
+// 	var buf bytes.Buffer
+// 	if err := cookoo.SaveContextGob(&buf, cxt); err != nil {
+// 		// Handle the error.
+// 	}
+func SaveContextGob(w io.Writer, cxt Context) error {
+	vals := make(map[string]interface{}, cxt.Len())
+	for k, v := range cxt.AsMap() {
+		// Test encode each value so that one bad value does not spoil the
+		// entire checkpoint.
+		if err := gob.NewEncoder(new(bytes.Buffer)).Encode(&v); err != nil {
+			cxt.Logf("warn", "Skipping context value '%s' (%T) during gob encoding: %s", k, v, err)
+			continue
+		}
+		vals[k] = v
+	}
+
+	return gob.NewEncoder(w).Encode(vals)
+}
+
+// LoadContextGob reads a gob-encoded set of context values into a new context.
+//
+// The data should have been written by SaveContextGob. As with encoding, any
+// non-basic types must be registered with `gob.Register()` before decoding.
+//
+// The returned context has no datasources or loggers. Those should be added
+// by the application before the context is used.
+func LoadContextGob(r io.Reader) (Context, error) {
+	vals := map[string]interface{}{}
+	if err := gob.NewDecoder(r).Decode(&vals); err != nil {
+		return nil, err
+	}
+
+	cxt := NewContext()
+	for k, v := range vals {
+		cxt.Put(k, v)
+	}
+	return cxt, nil
+}
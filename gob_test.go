@@ -0,0 +1,57 @@
+package cookoo
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+type CheckpointStruct struct {
+	Name  string
+	Steps []string
+}
+
+func TestContextGob(t *testing.T) {
+	gob.Register(&CheckpointStruct{})
+
+	c := NewContext()
+	c.Put("a", "Hello")
+	c.Put("b", 42)
+	c.Put("c", &CheckpointStruct{"test", []string{"one", "two"}})
+	c.Put("d", func() string { return "Not encodable" })
+	c.Put("e", nil)
+	c.AddDatasource("foo", new(ExampleDatasource))
+
+	var buf bytes.Buffer
+	if err := SaveContextGob(&buf, c); err != nil {
+		t.Fatalf("! Failed to encode context: %s", err)
+	}
+
+	c2, err := LoadContextGob(&buf)
+	if err != nil {
+		t.Fatalf("! Failed to decode context: %s", err)
+	}
+
+	if v := c2.Get("a", nil); v != "Hello" {
+		t.Errorf("! Expected Hello, got %v", v)
+	}
+	if v := c2.Get("b", nil); v != 42 {
+		t.Errorf("! Expected 42, got %v", v)
+	}
+
+	s, ok := c2.Get("c", nil).(*CheckpointStruct)
+	if !ok {
+		t.Fatal("! Expected a *CheckpointStruct.")
+	}
+	if s.Name != "test" || len(s.Steps) != 2 || s.Steps[1] != "two" {
+		t.Errorf("! Unexpected struct value: %v", s)
+	}
+
+	if _, ok := c2.Has("d"); ok {
+		t.Error("! Expected func to be skipped.")
+	}
+
+	if _, ok := c2.HasDatasource("foo"); ok {
+		t.Error("! Datasources should not be encoded.")
+	}
+}
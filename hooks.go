@@ -0,0 +1,157 @@
+package cookoo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hook is run by the router before or after a route.
+//
+// A hook receives the context and the name of the route. Returning nil lets
+// the route carry on. Returning an Interrupt is handled just as it would be
+// from a command: a Stop ends the route without error, a Reroute runs
+// another route, a RecoverableError is logged and ignored, and any other
+// error ends the route with that error.
+type Hook func(cxt Context, route string) Interrupt
+
+// ErrorHook is run by the router when a route fails.
+//
+// It receives the error that ended the route. Returning nil leaves the error
+// as it is. Returning an Interrupt is handled as for a Hook. So a Stop
+// swallows the error, a Reroute can run an error-handling route, and another
+// error replaces the original.
+type ErrorHook func(cxt Context, route string, err error) Interrupt
+
+type routeHook struct {
+	prefix string
+	hook   Hook
+}
+
+type errorHook struct {
+	prefix string
+	hook   ErrorHook
+}
+
+// Before adds a hook that runs before every route whose name begins with
+// prefix. An empty prefix matches every route.
+//
+// Hooks run in the order they were added. Like middleware, hooks are run
+// once for each call to HandleRequest, with the resolved route name. They are
+// not run again for reroutes, and they are run even if the route does not
+// exist (in which case the route will fail with a RouteError).
+//
+// A Before hook can abort the route by returning an Interrupt. This makes
+// hooks suitable for things like authorization checks:
+//
+// 	router.Before("@admin", func(cxt cookoo.Context, route string) cookoo.Interrupt {
+// 		if _, ok := cxt.Has("user.Admin"); !ok {
+// 			return &cookoo.FatalError{Message: "Forbidden"}
+// 		}
+// 		return nil
+// 	})
+func (r *Router) Before(prefix string, hook Hook) {
+	r.before = append(r.before, routeHook{prefix, hook})
+}
+
+// After adds a hook that runs after every route whose name begins with
+// prefix. See Before.
+//
+// After hooks are only run if the route succeeds. Use OnError to handle
+// failures.
+func (r *Router) After(prefix string, hook Hook) {
+	r.after = append(r.after, routeHook{prefix, hook})
+}
+
+// OnError adds a hook that runs when a route whose name begins with prefix
+// fails. See Before.
+//
+// Errors from commands, from Before and After hooks, and from the route
+// itself (for example, a missing route) are all passed to OnError hooks.
+//
+// If any OnError hook matches a route, then a panic in that route is
+// recovered and handled as a FatalError.
+func (r *Router) OnError(prefix string, hook ErrorHook) {
+	r.onError = append(r.onError, errorHook{prefix, hook})
+}
+
+// PRIVATE ==========================================================
+
+// runHooked runs a route with its hooks.
+func (r *Router) runHooked(route string, cxt Context, run func() error) (err error) {
+	var onError []ErrorHook
+	for _, h := range r.onError {
+		if strings.HasPrefix(route, h.prefix) {
+			onError = append(onError, h.hook)
+		}
+	}
+	if len(onError) > 0 {
+		defer func() {
+			if p := recover(); p != nil {
+				err = &FatalError{fmt.Sprintf("Route %s panicked: %v", route, p)}
+			}
+			if err != nil {
+				err = r.runErrorHooks(onError, route, cxt, err)
+			}
+		}()
+	}
+
+	if done, e := r.runHooks(r.before, route, cxt); done {
+		return e
+	}
+	if e := run(); e != nil {
+		return e
+	}
+	_, e := r.runHooks(r.after, route, cxt)
+	return e
+}
+
+// runHooks runs each matching hook. If a hook ends the route, done is true
+// and err is what the route should return.
+func (r *Router) runHooks(hooks []routeHook, route string, cxt Context) (done bool, err error) {
+	for _, h := range hooks {
+		if !strings.HasPrefix(route, h.prefix) {
+			continue
+		}
+		if irq := h.hook(cxt, route); irq != nil {
+			if done, err = r.hookInterrupt(irq, route, cxt); done {
+				return done, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// runErrorHooks runs the OnError hooks, returning the error the route should
+// return.
+func (r *Router) runErrorHooks(hooks []ErrorHook, route string, cxt Context, err error) error {
+	for _, hook := range hooks {
+		if irq := hook(cxt, route, err); irq != nil {
+			if done, e := r.hookInterrupt(irq, route, cxt); done {
+				if e == nil {
+					return nil
+				}
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// hookInterrupt handles an interrupt returned by a hook, the same way that
+// runCommands handles one from a command.
+func (r *Router) hookInterrupt(irq Interrupt, route string, cxt Context) (done bool, err error) {
+	switch irq := irq.(type) {
+	case *Reroute:
+		routeName, e := r.ResolveRequest(irq.RouteTo(), cxt)
+		if e != nil {
+			return true, e
+		}
+		return true, r.runRoute(routeName, cxt, false)
+	case *Stop:
+		return true, nil
+	case *RecoverableError:
+		cxt.Logf("warn", "Continuing after Recoverable Error in a hook on route %s: %v", route, irq)
+		return false, nil
+	}
+	return true, irq.(error)
+}
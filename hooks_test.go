@@ -0,0 +1,98 @@
+package cookoo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	reg.Route("test", "Test hooks.").
+		Does(AddToContext, "add").Using("ran").WithDefault(true)
+	reg.Route("@admin", "Test aborting.").
+		Does(AddToContext, "add").Using("ran").WithDefault(true)
+	reg.Route("fail", "Test errors.").
+		Does(FatalErrorCommand, "fail")
+
+	var calls []string
+	record := func(name string) Hook {
+		return func(cxt Context, route string) Interrupt {
+			calls = append(calls, name+":"+route)
+			return nil
+		}
+	}
+	router.Before("", record("before"))
+	router.After("", record("after"))
+	router.Before("@", func(cxt Context, route string) Interrupt {
+		return &FatalError{"Forbidden"}
+	})
+	router.OnError("", func(cxt Context, route string, err error) Interrupt {
+		calls = append(calls, "error:"+route)
+		return nil
+	})
+
+	if err := router.HandleRequest("test", cxt, false); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if strings.Join(calls, " ") != "before:test after:test" {
+		t.Errorf("! Unexpected hook calls: %v", calls)
+	}
+
+	calls = nil
+	cxt.Put("ran", false)
+	err := router.HandleRequest("@admin", cxt, false)
+	if err == nil || err.Error() != "Forbidden" {
+		t.Errorf("! Expected the Before hook to abort the route, got %v", err)
+	}
+	if cxt.Get("ran", true) != false {
+		t.Error("! Expected the route not to run.")
+	}
+	if strings.Join(calls, " ") != "before:@admin error:@admin" {
+		t.Errorf("! Unexpected hook calls: %v", calls)
+	}
+
+	calls = nil
+	if err := router.HandleRequest("fail", cxt, false); err == nil {
+		t.Error("! Expected the route to fail.")
+	}
+	if strings.Join(calls, " ") != "before:fail error:fail" {
+		t.Errorf("! Unexpected hook calls: %v", calls)
+	}
+}
+
+func TestErrorHooks(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	reg.Route("panic", "Test recovery.").
+		Does(func(Context, *Params) (interface{}, Interrupt) {
+			panic("oops")
+		}, "panic")
+	reg.Route("fail", "Test rerouting on error.").
+		Does(FatalErrorCommand, "fail")
+	reg.Route("error page", "Handle an error.").
+		Does(AddToContext, "add").Using("handled").WithDefault(true)
+	reg.Route("other", "Not matched by the hooks.").
+		Does(FatalErrorCommand, "fail")
+
+	router.OnError("panic", func(cxt Context, route string, err error) Interrupt {
+		if !strings.Contains(err.Error(), "oops") {
+			t.Errorf("! Expected the panic to be in the error, got %s", err)
+		}
+		return &Stop{}
+	})
+	router.OnError("fail", func(cxt Context, route string, err error) Interrupt {
+		return &Reroute{"error page"}
+	})
+
+	if err := router.HandleRequest("panic", cxt, false); err != nil {
+		t.Errorf("! Expected the error to be swallowed, got %s", err)
+	}
+	if err := router.HandleRequest("fail", cxt, false); err != nil {
+		t.Errorf("! Expected the error route to succeed, got %s", err)
+	}
+	if cxt.Get("handled", false) != true {
+		t.Error("! Expected the error route to run.")
+	}
+	if err := router.HandleRequest("other", cxt, false); err == nil {
+		t.Error("! Expected hooks for other prefixes to be skipped.")
+	}
+}
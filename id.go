@@ -0,0 +1,100 @@
+package cookoo
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// IDEntropy is the source of randomness used by GenerateID.
+//
+// By default, this is `crypto/rand.Reader`. Tests may replace it with a
+// deterministic reader to generate predictable IDs.
+var IDEntropy io.Reader = rand.Reader
+
+// IDClock is the clock used by GenerateID for time-based IDs (ULIDs).
+//
+// By default, this is `time.Now`. Tests may replace it with a fixed clock.
+var IDClock = time.Now
+
+// GenerateID generates a unique ID.
+//
+// Two kinds of IDs are supported:
+//
+// 	- uuid: A random (version 4) UUID, e.g. "7d444840-9dc0-41c2-8d4b-b3a5bc6d0d9e".
+// 	- ulid: A lexically sortable ULID, e.g. "01ARZ3NDEKTSV4RRFFQ69G5FAV".
+//
+// Params
+//
+// 	- kind (string): Either "uuid" or "ulid". Default: "uuid"
+//
+// Returns
+//
+// 	- The ID as a string.
+func GenerateID(cxt Context, params *Params) (interface{}, Interrupt) {
+	kind := GetString("kind", "uuid", params)
+
+	var id string
+	var err error
+	switch kind {
+	case "uuid":
+		id, err = newUUID()
+	case "ulid":
+		id, err = newULID()
+	default:
+		return nil, &FatalError{fmt.Sprintf("Unknown ID kind '%s'", kind)}
+	}
+
+	if err != nil {
+		return nil, &FatalError{fmt.Sprintf("Could not generate %s: %s", kind, err)}
+	}
+	return id, nil
+}
+
+// newUUID generates a version 4 UUID as defined in RFC 4122.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(IDEntropy, b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// ulidAlphabet is Crockford's base32 alphabet.
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID generates a ULID: a 48-bit millisecond timestamp followed by 80
+// bits of randomness, encoded as 26 characters of Crockford's base32.
+func newULID() (string, error) {
+	var b [16]byte
+	ms := uint64(IDClock().UnixNano() / int64(time.Millisecond))
+	binary.BigEndian.PutUint64(b[0:8], ms<<16)
+	if _, err := io.ReadFull(IDEntropy, b[6:]); err != nil {
+		return "", err
+	}
+
+	hi := binary.BigEndian.Uint64(b[0:8])
+	lo := binary.BigEndian.Uint64(b[8:16])
+
+	// Encode 128 bits, most significant first, 5 bits at a time. The first
+	// character only holds the top three bits.
+	out := make([]byte, 26)
+	for i := range out {
+		shift := uint(5 * (25 - i))
+		var v uint64
+		switch {
+		case shift >= 64:
+			v = hi >> (shift - 64)
+		case shift+5 <= 64:
+			v = lo >> shift
+		default:
+			v = (lo >> shift) | (hi << (64 - shift))
+		}
+		out[i] = ulidAlphabet[v&0x1f]
+	}
+	return string(out), nil
+}
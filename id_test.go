@@ -0,0 +1,65 @@
+package cookoo
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestGenerateID(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	reg.Route("test", "Test IDs.").
+		Does(GenerateID, "uuid").
+		Does(GenerateID, "ulid").
+		Using("kind").WithDefault("ulid").
+		Route("bad", "Test a bad kind.").
+		Does(GenerateID, "bad").
+		Using("kind").WithDefault("guid")
+
+	if e := router.HandleRequest("test", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+
+	uuid := cxt.Get("uuid", "").(string)
+	if ok, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", uuid); !ok {
+		t.Errorf("! Expected a v4 UUID, got %s", uuid)
+	}
+	ulid := cxt.Get("ulid", "").(string)
+	if ok, _ := regexp.MatchString("^[0-7][0-9A-HJKMNP-TV-Z]{25}$", ulid); !ok {
+		t.Errorf("! Expected a ULID, got %s", ulid)
+	}
+
+	if e := router.HandleRequest("bad", cxt, false); e == nil {
+		t.Error("! Expected an unknown kind to fail.")
+	}
+}
+
+func TestGenerateIDDeterministic(t *testing.T) {
+	entropy, clock := IDEntropy, IDClock
+	defer func() {
+		IDEntropy, IDClock = entropy, clock
+	}()
+
+	IDClock = func() time.Time {
+		return time.Unix(1469918176, 385000000)
+	}
+
+	reg, router, cxt := Cookoo()
+	reg.Route("test", "Test IDs.").
+		Does(GenerateID, "uuid").
+		Does(GenerateID, "ulid").
+		Using("kind").WithDefault("ulid")
+
+	IDEntropy = bytes.NewReader(bytes.Repeat([]byte{0xff}, 32))
+	if e := router.HandleRequest("test", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+
+	if v := cxt.Get("uuid", ""); v != "ffffffff-ffff-4fff-bfff-ffffffffffff" {
+		t.Errorf("! Unexpected UUID: %s", v)
+	}
+	if v := cxt.Get("ulid", ""); v != "01ARYZ6S41ZZZZZZZZZZZZZZZZ" {
+		t.Errorf("! Unexpected ULID: %s", v)
+	}
+}
@@ -0,0 +1,78 @@
+package cookoo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// SaveContextJSON writes the values of a context to a writer as JSON.
+//
+// This is the JSON counterpart to SaveContextGob. The output has the same
+// shape as DumpContext, but values that cannot be encoded as JSON are
+// skipped, and a warning is logged to the context. Datasources are listed
+// by name, but are not saved.
+//
+// Because JSON has fewer types than Go, values do not always come back the
+// same: numbers are restored as json.Numbers, so that large integers are not
+// rounded, and structs as maps. Use GetAs, or Bind, to read them back into
+// the types you need.
+func SaveContextJSON(w io.Writer, cxt Context) error {
+	dump := contextDump{
+		Values:      map[string]json.RawMessage{},
+		Datasources: []string{},
+	}
+	for k, v := range cxt.AsMap() {
+		data, err := json.Marshal(v)
+		if err != nil {
+			cxt.Logf("warn", "Skipping context value '%s' (%T) during JSON encoding: %s", k, v, err)
+			continue
+		}
+		dump.Values[k] = data
+	}
+	for name := range cxt.Datasources() {
+		dump.Datasources = append(dump.Datasources, name)
+	}
+	sort.Strings(dump.Datasources)
+
+	return json.NewEncoder(w).Encode(dump)
+}
+
+// LoadContextJSON reads JSON-encoded context values into a new context.
+//
+// The data should have been written by SaveContextJSON. As with
+// LoadContextGob, the returned context has no datasources or loggers.
+func LoadContextJSON(r io.Reader) (Context, error) {
+	cxt := new(ExecutionContext).Init()
+	if err := json.NewDecoder(r).Decode(cxt); err != nil {
+		return nil, err
+	}
+	return cxt, nil
+}
+
+// UnmarshalJSON reads values written by SaveContextJSON into the context.
+// Existing values with the same names are replaced.
+//
+// Only the output of SaveContextJSON round-trips. MarshalJSON writes the
+// same shape, but it is a lossy snapshot (see DumpContext), so values it
+// could not encode come back as the strings that describe them.
+//
+// Datasources are not restored.
+func (cxt *ExecutionContext) UnmarshalJSON(data []byte) error {
+	var dump struct {
+		Values map[string]interface{} `json:"values"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&dump); err != nil {
+		return err
+	}
+	if cxt.values == nil {
+		cxt.Init()
+	}
+	for k, v := range dump.Values {
+		cxt.Put(k, v)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package cookoo
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestContextJSON(t *testing.T) {
+	cxt := NewContext()
+	cxt.Put("name", "Matt")
+	cxt.Put("age", 42)
+	cxt.Put("id", int64(1<<62+1))
+	cxt.Put("tags", []string{"a", "b"})
+	cxt.Put("callback", func() {})
+	cxt.AddDatasource("ds", &ExampleDatasource{})
+
+	var buf bytes.Buffer
+	if err := SaveContextJSON(&buf, cxt); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+
+	restored, err := LoadContextJSON(&buf)
+	if err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if restored.Len() != 4 {
+		t.Errorf("! Expected 4 values, got %v", restored.Keys())
+	}
+	if v := restored.Get("name", nil); v != "Matt" {
+		t.Errorf("! Expected Matt, got %v", v)
+	}
+	if v := GetAs[int]("age", 0, GettableCxt(restored)); v != 42 {
+		t.Errorf("! Expected 42, got %v", restored.Get("age", nil))
+	}
+	if v := GetAs[int64]("id", 0, GettableCxt(restored)); v != 1<<62+1 {
+		t.Errorf("! Expected a large ID to survive, got %v", restored.Get("id", nil))
+	}
+	if tags := restored.Get("tags", nil).([]interface{}); len(tags) != 2 || tags[1] != "b" {
+		t.Errorf("! Unexpected tags: %v", tags)
+	}
+	if _, ok := restored.Has("callback"); ok {
+		t.Error("! Expected the func to be skipped.")
+	}
+	if _, ok := restored.HasDatasource("ds"); ok {
+		t.Error("! Expected datasources not to be restored.")
+	}
+
+	// Unmarshalling merges into an existing context.
+	existing := NewContext()
+	existing.Put("name", "Angie")
+	existing.Put("other", true)
+	if err := json.Unmarshal([]byte(`{"values":{"name":"Matt"}}`), existing); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if existing.Get("name", nil) != "Matt" || existing.Get("other", nil) != true {
+		t.Errorf("! Unexpected values: %v", existing.AsMap())
+	}
+
+	if _, err := LoadContextJSON(bytes.NewBufferString("not json")); err == nil {
+		t.Error("! Expected bad JSON to fail.")
+	}
+}
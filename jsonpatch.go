@@ -0,0 +1,303 @@
+package cookoo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ApplyJSONPatch applies a JSON Patch (RFC 6902) to a document in the context.
+//
+// The supported operations are add, remove, replace, move, copy, and test.
+// Paths are JSON Pointers (RFC 6901), such as "/users/0/name".
+//
+// The document is normalized through JSON before the patch is applied. So a
+// struct or a map in the context will be replaced by the generic JSON form
+// (map[string]interface{}, []interface{}, float64, and so on). A []byte is
+// treated as raw JSON. The patch may be a []byte of raw JSON, or any value
+// that encodes to a JSON array of operations, such as a []interface{}.
+//
+// The patch is applied as a whole. If any operation fails, the document in
+// the context is left unchanged.
+//
+// Params
+//
+// 	- target (string): The name of the document in the context. This is
+// 	  required.
+// 	- patch (string): The name of the patch in the context. This is required.
+//
+// Returns
+//
+// 	- The patched document. It is also put back into the context under the
+// 	  target name.
+//
+// A failed test operation, a bad path, or a malformed patch causes a
+// FatalError.
+func ApplyJSONPatch(cxt Context, params *Params) (interface{}, Interrupt) {
+	target, ok := HasString("target", params)
+	if !ok {
+		return nil, &FatalError{"Expected a 'target'"}
+	}
+	patchKey, ok := HasString("patch", params)
+	if !ok {
+		return nil, &FatalError{"Expected a 'patch'"}
+	}
+
+	doc, err := toJSONValue(cxt.Get(target, nil))
+	if err != nil {
+		return nil, &FatalError{fmt.Sprintf("Could not read document '%s': %s", target, err)}
+	}
+
+	var ops []jsonPatchOp
+	if err := decodeJSONValue(cxt.Get(patchKey, nil), &ops); err != nil {
+		return nil, &FatalError{fmt.Sprintf("Could not read patch '%s': %s", patchKey, err)}
+	}
+
+	for i, op := range ops {
+		doc, err = op.apply(doc)
+		if err != nil {
+			return nil, &FatalError{fmt.Sprintf("JSON Patch operation %d (%s %s) failed: %s", i, op.Op, op.Path, err)}
+		}
+	}
+
+	cxt.Put(target, doc)
+	return doc, nil
+}
+
+// jsonPatchOp is a single JSON Patch operation.
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// apply runs the operation on the document, returning the new document.
+func (op jsonPatchOp) apply(doc interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, fmt.Errorf("no value given")
+		}
+		var val interface{}
+		if err := json.Unmarshal(op.Value, &val); err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return pointerAdd(doc, path, val)
+		case "replace":
+			if len(path) == 0 {
+				return val, nil
+			}
+			if doc, _, err = pointerRemove(doc, path); err != nil {
+				return nil, err
+			}
+			return pointerAdd(doc, path, val)
+		default:
+			actual, err := pointerGet(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !jsonEqual(actual, val) {
+				return nil, fmt.Errorf("value does not match")
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = pointerRemove(doc, path)
+		return doc, err
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var val interface{}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("cannot move a value into one of its children")
+			}
+			doc, val, err = pointerRemove(doc, from)
+		} else {
+			val, err = pointerGet(doc, from)
+			if err == nil {
+				val, err = toJSONValue(val)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, val)
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// parsePointer splits a JSON Pointer into its unescaped tokens.
+func parsePointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return []string{}, nil
+	}
+	if ptr[0] != '/' {
+		return nil, fmt.Errorf("path %q must begin with '/'", ptr)
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array index. If allowEnd is true, "-" and len(list)
+// are accepted as the position after the last item.
+func arrayIndex(token string, list []interface{}, allowEnd bool) (int, error) {
+	limit := len(list) - 1
+	if allowEnd {
+		limit = len(list)
+		if token == "-" {
+			return limit, nil
+		}
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > limit || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+func pointerGet(node interface{}, path []string) (interface{}, error) {
+	for _, key := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			v, ok := n[key]
+			if !ok {
+				return nil, fmt.Errorf("no such key %q", key)
+			}
+			node = v
+		case []interface{}:
+			i, err := arrayIndex(key, n, false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("cannot look up %q in a %T", key, node)
+		}
+	}
+	return node, nil
+}
+
+func pointerAdd(node interface{}, path []string, val interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+	key, last := path[0], len(path) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if last {
+			n[key] = val
+			return n, nil
+		}
+		child, ok := n[key]
+		if !ok {
+			return nil, fmt.Errorf("no such key %q", key)
+		}
+		child, err := pointerAdd(child, path[1:], val)
+		if err != nil {
+			return nil, err
+		}
+		n[key] = child
+		return n, nil
+	case []interface{}:
+		i, err := arrayIndex(key, n, last)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = val
+			return n, nil
+		}
+		if n[i], err = pointerAdd(n[i], path[1:], val); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot add %q to a %T", key, node)
+}
+
+// pointerRemove removes the value at the path, returning the new document
+// and the value that was removed.
+func pointerRemove(node interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+	key, last := path[0], len(path) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("no such key %q", key)
+		}
+		if last {
+			delete(n, key)
+			return n, child, nil
+		}
+		child, removed, err := pointerRemove(child, path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[key] = child
+		return n, removed, nil
+	case []interface{}:
+		i, err := arrayIndex(key, n, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if last {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+		child, removed, err := pointerRemove(n[i], path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[i] = child
+		return n, removed, nil
+	}
+	return nil, nil, fmt.Errorf("cannot remove %q from a %T", key, node)
+}
+
+// toJSONValue makes a generic JSON copy of a value.
+func toJSONValue(v interface{}) (interface{}, error) {
+	var out interface{}
+	err := decodeJSONValue(v, &out)
+	return out, err
+}
+
+// decodeJSONValue decodes a value into out by way of JSON. A []byte is
+// decoded directly.
+func decodeJSONValue(v interface{}, out interface{}) error {
+	data, ok := v.([]byte)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, out)
+}
+
+// jsonEqual compares two values by their JSON encodings.
+func jsonEqual(a, b interface{}) bool {
+	aj, aerr := json.Marshal(a)
+	bj, berr := json.Marshal(b)
+	return aerr == nil && berr == nil && bytes.Equal(aj, bj)
+}
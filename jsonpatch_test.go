@@ -0,0 +1,94 @@
+package cookoo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func runPatch(doc interface{}, patch string) (Context, error) {
+	reg, router, cxt := Cookoo()
+	reg.Route("test", "Test patching.").
+		Does(ApplyJSONPatch, "patched").
+		Using("target").WithDefault("doc").
+		Using("patch").WithDefault("patch")
+
+	cxt.Put("doc", doc)
+	cxt.Put("patch", []byte(patch))
+	return cxt, router.HandleRequest("test", cxt, false)
+}
+
+func TestApplyJSONPatchAdd(t *testing.T) {
+	doc := map[string]interface{}{"name": "Matt", "tags": []string{"a", "c"}}
+	cxt, e := runPatch(doc, `[
+		{"op": "add", "path": "/age", "value": 42},
+		{"op": "add", "path": "/tags/1", "value": "b"},
+		{"op": "add", "path": "/tags/-", "value": "d"}
+	]`)
+	if e != nil {
+		t.Fatalf("! Unexpected error: %s", e)
+	}
+
+	expects := map[string]interface{}{
+		"name": "Matt",
+		"age":  42.0,
+		"tags": []interface{}{"a", "b", "c", "d"},
+	}
+	if out := cxt.Get("doc", nil); !reflect.DeepEqual(out, expects) {
+		t.Errorf("! Expected %v, got %v", expects, out)
+	}
+	if out := cxt.Get("patched", nil); !reflect.DeepEqual(out, expects) {
+		t.Errorf("! Expected the patched document to be returned, got %v", out)
+	}
+}
+
+func TestApplyJSONPatchReplace(t *testing.T) {
+	doc := []byte(`{"user": {"name": "Matt", "emails": ["old@example.com"]}, "a/b": 1}`)
+	cxt, e := runPatch(doc, `[
+		{"op": "replace", "path": "/user/name", "value": "Matthew"},
+		{"op": "replace", "path": "/user/emails/0", "value": "new@example.com"},
+		{"op": "move", "from": "/a~1b", "path": "/user/id"},
+		{"op": "copy", "from": "/user/name", "path": "/owner"},
+		{"op": "remove", "path": "/user/emails"},
+		{"op": "test", "path": "/user", "value": {"id": 1, "name": "Matthew"}}
+	]`)
+	if e != nil {
+		t.Fatalf("! Unexpected error: %s", e)
+	}
+
+	expects := map[string]interface{}{
+		"user":  map[string]interface{}{"name": "Matthew", "id": 1.0},
+		"owner": "Matthew",
+	}
+	if out := cxt.Get("doc", nil); !reflect.DeepEqual(out, expects) {
+		t.Errorf("! Expected %v, got %v", expects, out)
+	}
+}
+
+func TestApplyJSONPatchFailedTest(t *testing.T) {
+	doc := map[string]interface{}{"name": "Matt"}
+	cxt, e := runPatch(doc, `[
+		{"op": "replace", "path": "/name", "value": "Matthew"},
+		{"op": "test", "path": "/name", "value": "Matt"}
+	]`)
+	if e == nil {
+		t.Fatal("! Expected a failed test to cause an error.")
+	}
+	if _, ok := e.(*FatalError); !ok {
+		t.Errorf("! Expected a FatalError, got %T", e)
+	}
+	if doc["name"] != "Matt" || cxt.Get("doc", nil).(map[string]interface{})["name"] != "Matt" {
+		t.Error("! Expected the document to be unchanged.")
+	}
+
+	for _, patch := range []string{
+		`[{"op": "remove", "path": "/nope"}]`,
+		`[{"op": "add", "path": "/nope/deeper", "value": 1}]`,
+		`[{"op": "add", "path": "name", "value": 1}]`,
+		`[{"op": "frobnicate", "path": "/name"}]`,
+		`{"op": "remove"}`,
+	} {
+		if _, e := runPatch(doc, patch); e == nil {
+			t.Errorf("! Expected patch %s to fail.", patch)
+		}
+	}
+}
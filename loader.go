@@ -0,0 +1,124 @@
+package cookoo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// routeFile is the format read by LoadJSON.
+type routeFile struct {
+	Routes []struct {
+		Name     string `json:"name"`
+		Help     string `json:"help"`
+		Commands []struct {
+			Name    string `json:"name"`
+			Command string `json:"command"`
+			Include string `json:"include"`
+			Params  []struct {
+				Name    string      `json:"name"`
+				Default interface{} `json:"default"`
+				From    string      `json:"from"`
+			} `json:"params"`
+		} `json:"commands"`
+	} `json:"routes"`
+}
+
+// LoadJSON adds routes to the registry from a JSON definition.
+//
+// This makes it possible to change routing without recompiling. The commands
+// are looked up by name in the given table, so the table must have every
+// command that the definition uses. A definition looks like this:
+//
+// 	{
+// 	  "routes": [
+// 	    {
+// 	      "name": "hello",
+// 	      "help": "Say hello.",
+// 	      "commands": [
+// 	        {
+// 	          "name": "out",
+// 	          "command": "printf",
+// 	          "params": [
+// 	            {"name": "format", "default": "Hello %s\n"},
+// 	            {"name": "0", "default": "World", "from": "cxt:name"}
+// 	          ]
+// 	        }
+// 	      ]
+// 	    },
+// 	    {
+// 	      "name": "hello twice",
+// 	      "help": "Say hello again.",
+// 	      "commands": [{"include": "hello"}, {"include": "hello"}]
+// 	    }
+// 	  ]
+// 	}
+//
+// This is the same as building the routes with Route, Does, Using,
+// WithDefault, From, and Includes. An entry in "commands" that has an
+// "include" includes another route, which must already be in the registry or
+// earlier in the definition.
+//
+// Defaults are decoded as JSON, so numbers become float64 values. Commands
+// that need ints can read them with GetAs.
+//
+// The whole definition is checked before any routes are added. If a command
+// or an included route is not found, an error is returned and the registry is
+// not changed.
+func (r *Registry) LoadJSON(in io.Reader, commands map[string]Command) error {
+	var def routeFile
+	if err := json.NewDecoder(in).Decode(&def); err != nil {
+		return fmt.Errorf("Could not parse route definition: %s", err)
+	}
+
+	known := map[string]bool{}
+	for name := range r.routes {
+		known[name] = true
+	}
+	for _, route := range def.Routes {
+		if route.Name == "" {
+			return errors.New("Route definition has a route with no name.")
+		}
+		for _, cmd := range route.Commands {
+			switch {
+			case cmd.Include != "":
+				if !known[cmd.Include] {
+					return fmt.Errorf("Route %s includes unknown route %s.", route.Name, cmd.Include)
+				}
+			case commands[cmd.Command] == nil:
+				return fmt.Errorf("Route %s uses unknown command %q.", route.Name, cmd.Command)
+			}
+		}
+		known[route.Name] = true
+	}
+
+	for _, route := range def.Routes {
+		r.Route(route.Name, route.Help)
+		for _, cmd := range route.Commands {
+			if cmd.Include != "" {
+				r.Includes(cmd.Include)
+				continue
+			}
+			r.Does(commands[cmd.Command], cmd.Name)
+			for _, p := range cmd.Params {
+				r.Using(p.Name).WithDefault(p.Default)
+				if p.From != "" {
+					r.From(p.From)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFile adds routes to the registry from a JSON file. See LoadJSON.
+func (r *Registry) LoadFile(path string, commands map[string]Command) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.LoadJSON(f, commands)
+}
@@ -0,0 +1,107 @@
+package cookoo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRoutes = `{
+  "routes": [
+    {
+      "name": "hello",
+      "help": "Say hello.",
+      "commands": [
+        {
+          "name": "hi",
+          "command": "add",
+          "params": [
+            {"name": "greeting", "default": "Hello"},
+            {"name": "name", "default": "World", "from": "cxt:who"},
+            {"name": "count", "default": 2}
+          ]
+        }
+      ]
+    },
+    {
+      "name": "hello again",
+      "help": "Say hello, then say goodbye.",
+      "commands": [
+        {"include": "hello"},
+        {"name": "goodbye", "command": "add", "params": [{"name": "bye", "default": "Goodbye"}]}
+      ]
+    }
+  ]
+}`
+
+func TestLoadJSON(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	commands := map[string]Command{"add": AddToContext}
+
+	if err := reg.LoadJSON(strings.NewReader(testRoutes), commands); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+
+	if !router.HasRoute("hello") || !router.HasRoute("hello again") {
+		t.Fatalf("! Expected both routes to be loaded, got %v", reg.RouteNames())
+	}
+	if spec, _ := reg.RouteSpec("hello"); spec.description != "Say hello." {
+		t.Errorf("! Expected the help text to be loaded, got %s", spec.description)
+	}
+
+	cxt.Put("who", "Matt")
+	if err := router.HandleRequest("hello again", cxt, false); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if v := cxt.Get("greeting", nil); v != "Hello" {
+		t.Errorf("! Expected Hello, got %v", v)
+	}
+	if v := cxt.Get("name", nil); v != "Matt" {
+		t.Errorf("! Expected the name from the context, got %v", v)
+	}
+	if v := GetAs[int]("count", 0, GettableCxt(cxt)); v != 2 {
+		t.Errorf("! Expected a count of 2, got %v", cxt.Get("count", nil))
+	}
+	if v := cxt.Get("bye", nil); v != "Goodbye" {
+		t.Errorf("! Expected Goodbye, got %v", v)
+	}
+}
+
+func TestLoadJSONErrors(t *testing.T) {
+	reg := NewRegistry()
+	commands := map[string]Command{"add": AddToContext}
+
+	bad := []string{
+		`{"routes": [{"name": "a", "commands": [{"name": "x", "command": "nope"}]}]}`,
+		`{"routes": [{"name": "a", "commands": [{"include": "b"}]}, {"name": "b"}]}`,
+		`{"routes": [{"help": "No name."}]}`,
+		`{"routes": `,
+	}
+	for _, def := range bad {
+		if err := reg.LoadJSON(strings.NewReader(def), commands); err == nil {
+			t.Errorf("! Expected %s to fail.", def)
+		}
+	}
+	if len(reg.RouteNames()) != 0 {
+		t.Errorf("! Expected no routes to be added, got %v", reg.RouteNames())
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(path, []byte(testRoutes), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reg := NewRegistry()
+	if err := reg.LoadFile(path, map[string]Command{"add": AddToContext}); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if len(reg.RouteNames()) != 2 {
+		t.Errorf("! Expected two routes, got %v", reg.RouteNames())
+	}
+	if err := reg.LoadFile(path+".missing", nil); err == nil {
+		t.Error("! Expected a missing file to fail.")
+	}
+}
@@ -0,0 +1,50 @@
+package cookoo
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// LoadShed creates middleware that sheds requests when too many are in flight.
+//
+// Each request is given a priority by priorityFn. A request's priority is
+// treated as extra headroom: a request with priority p is admitted only while
+// fewer than maxInFlight+p requests are in flight. So under load, requests
+// with priority 0 are shed once maxInFlight is reached, requests with a
+// negative priority are shed sooner, and requests with a positive priority
+// are still admitted. If priorityFn is nil, every request has priority 0.
+//
+// The in-flight count is shared by every request that runs through the
+// returned middleware.
+//
+// A shed request is not run. Instead, a FatalError beginning with "Service
+// Unavailable" is returned. Web applications should answer these with a 503.
+//
+// Example:
+//
+// 	router.Use(cookoo.LoadShed(100, func(cxt cookoo.Context) int {
+// 		if _, ok := cxt.Has("user.Admin"); ok {
+// 			return 10
+// 		}
+// 		return 0
+// 	}))
+func LoadShed(maxInFlight int, priorityFn func(cxt Context) int) Middleware {
+	var inFlight int64
+	return func(next RequestHandler) RequestHandler {
+		return func(name string, cxt Context, taint bool) error {
+			priority := 0
+			if priorityFn != nil {
+				priority = priorityFn(cxt)
+			}
+
+			n := atomic.AddInt64(&inFlight, 1)
+			defer atomic.AddInt64(&inFlight, -1)
+			if n > int64(maxInFlight+priority) {
+				cxt.Logf("warn", "Shedding request %s with priority %d (%d in flight)", name, priority, n-1)
+				return &FatalError{fmt.Sprintf("Service Unavailable: too many requests in flight to handle %s", name)}
+			}
+
+			return next(name, cxt, taint)
+		}
+	}
+}
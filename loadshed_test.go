@@ -0,0 +1,47 @@
+package cookoo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadShed(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	router.Use(LoadShed(1, func(cxt Context) int {
+		return cxt.Get("priority", 0).(int)
+	}))
+
+	reg.Route("work", "Do some work.").
+		Does(AddToContext, "add").
+		Using("worked").WithDefault(true)
+
+	var low, high error
+	reg.Route("outer", "Make requests while this one is in flight.").
+		Does(Command(func(c Context, p *Params) (interface{}, Interrupt) {
+			lowCxt := NewContext()
+			low = router.HandleRequest("work", lowCxt, false)
+
+			highCxt := NewContext()
+			highCxt.Put("priority", 1)
+			high = router.HandleRequest("work", highCxt, false)
+			return nil, nil
+		}), "requests")
+
+	if e := router.HandleRequest("outer", cxt, false); e != nil {
+		t.Fatalf("! Unexpected error: %s", e)
+	}
+
+	if low == nil {
+		t.Error("! Expected the low-priority request to be shed.")
+	} else if _, ok := low.(*FatalError); !ok || !strings.HasPrefix(low.Error(), "Service Unavailable") {
+		t.Errorf("! Expected a Service Unavailable FatalError, got %T: %s", low, low)
+	}
+	if high != nil {
+		t.Errorf("! Expected the high-priority request to be admitted, got %s", high)
+	}
+
+	// Once nothing is in flight, low-priority requests are admitted again.
+	if e := router.HandleRequest("work", NewContext(), false); e != nil {
+		t.Errorf("! Expected the request to be admitted, got %s", e)
+	}
+}
@@ -0,0 +1,95 @@
+package cookoo
+
+import (
+	"fmt"
+	"time"
+)
+
+// Locker is a datasource that can provide locks.
+//
+// Locks are identified by a string key. A Locker may be backed by anything
+// from a local mutex to a network service, which makes it suitable for
+// coordinating work across several instances of an application.
+//
+// Lock attempts to acquire the lock for the given key. If the lock is
+// acquired, ok is true, and release must be called to give the lock up. The
+// ttl is the maximum amount of time the lock should be held. After that, a
+// Locker may assume that the holder has died and release the lock itself.
+//
+// Lock should not block indefinitely. If the lock cannot be acquired, it
+// should return ok = false.
+type Locker interface {
+	Lock(key string, ttl time.Duration) (release func(), ok bool)
+}
+
+// WithDistributedLock runs a command while holding a lock.
+//
+// The lock is acquired from a datasource that implements Locker. If the lock
+// is acquired, the command is run and the lock is released when the command
+// returns. If the lock cannot be acquired, the route is stopped (not as an
+// error). This is useful for tasks that must only be done by one instance
+// at a time.
+//
+// The wrapped command receives the same params as WithDistributedLock.
+//
+// Params
+//
+// 	- command (Command): The command to run. This is required.
+// 	- key (string): The name of the lock. This is required.
+// 	- datasource (string): The name of the Locker datasource. Default: "locker"
+// 	- ttl (time.Duration): The maximum time to hold the lock. Default: 30 seconds.
+//
+// Returns
+//
+// 	- Whatever the wrapped command returns.
+//
+// Example:
+//
+// 	reg.Route("cleanup", "Clean up old records").
+// 		Does(cookoo.WithDistributedLock, "cleanup").
+// 			Using("command").WithDefault(CleanupRecords).
+// 			Using("key").WithDefault("cleanup").
+// 			Using("ttl").WithDefault(5 * time.Minute)
+func WithDistributedLock(cxt Context, params *Params) (interface{}, Interrupt) {
+	cmd, ok := commandParam("command", params)
+	if !ok {
+		return nil, &FatalError{"Expected 'command' to be a Command"}
+	}
+	key, ok := HasString("key", params)
+	if !ok {
+		return nil, &FatalError{"Expected a 'key'"}
+	}
+	dsName := GetString("datasource", "locker", params)
+	ttl, ok := params.Get("ttl", 30*time.Second).(time.Duration)
+	if !ok {
+		return nil, &FatalError{"Expected 'ttl' to be a time.Duration"}
+	}
+
+	locker, ok := cxt.Datasource(dsName).(Locker)
+	if !ok {
+		return nil, &FatalError{fmt.Sprintf("No Locker datasource named '%s' found.", dsName)}
+	}
+
+	release, ok := locker.Lock(key, ttl)
+	if !ok {
+		cxt.Logf("info", "Could not acquire lock '%s'. Stopping.", key)
+		return nil, &Stop{}
+	}
+	defer release()
+
+	return cmd(cxt, params)
+}
+
+// commandParam gets a Command from the params.
+//
+// A function passed into WithDefault() is not a Command unless it has been
+// explicitly converted, so this accepts either form.
+func commandParam(name string, params *Params) (Command, bool) {
+	switch cmd := params.Get(name, nil).(type) {
+	case Command:
+		return cmd, true
+	case func(Context, *Params) (interface{}, Interrupt):
+		return cmd, true
+	}
+	return nil, false
+}
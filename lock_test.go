@@ -0,0 +1,63 @@
+package cookoo
+
+import (
+	"testing"
+	"time"
+)
+
+type MockLocker struct {
+	held     map[string]bool
+	released int
+}
+
+func (l *MockLocker) Lock(key string, ttl time.Duration) (func(), bool) {
+	if l.held[key] {
+		return nil, false
+	}
+	l.held[key] = true
+	return func() {
+		delete(l.held, key)
+		l.released++
+	}, true
+}
+
+func TestWithDistributedLock(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	locker := &MockLocker{held: map[string]bool{}}
+	cxt.AddDatasource("locker", locker)
+
+	reg.Route("test", "Test locking.").
+		Does(WithDistributedLock, "locked").
+		Using("command").WithDefault(AnotherCommand).
+		Using("key").WithDefault("test").
+		Using("ttl").WithDefault(time.Second).
+		Does(AddToContext, "after").
+		Using("foo").WithDefault("bar")
+
+	if e := router.HandleRequest("test", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	if v, ok := cxt.Get("locked", nil).(*FooType); !ok || v.test != 5 {
+		t.Errorf("! Expected the locked command to run, got %v", cxt.Get("locked", nil))
+	}
+	if locker.released != 1 {
+		t.Errorf("! Expected the lock to be released once, got %d", locker.released)
+	}
+	if _, ok := cxt.Has("foo"); !ok {
+		t.Error("! Expected the route to continue after the lock.")
+	}
+
+	// Contended lock.
+	cxt = NewContext()
+	cxt.AddDatasource("locker", locker)
+	locker.held["test"] = true
+	if e := router.HandleRequest("test", cxt, false); e != nil {
+		t.Errorf("! Contended lock should stop, not error: %s", e)
+	}
+	if v := cxt.Get("locked", nil); v != nil {
+		t.Errorf("! Expected the command to not run, got %v", v)
+	}
+	if _, ok := cxt.Has("foo"); ok {
+		t.Error("! Expected the route to stop when the lock is contended.")
+	}
+}
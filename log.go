@@ -0,0 +1,160 @@
+package cookoo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogHandler handles a context's log messages.
+//
+// By default, Context.Log and Context.Logf write plain text through the Go
+// log package to the context's loggers. When a context has a LogHandler, its
+// messages are sent to the handler instead. The prefix passed to Log or
+// Logf is used as the level.
+//
+// Handle is given the context that logged the message, so that it can add
+// fields from the context's values. Handlers must be safe for concurrent
+// use.
+type LogHandler interface {
+	Handle(cxt Context, level, msg string)
+}
+
+// LogHandlerContext is a context that can send its log messages to a
+// LogHandler.
+//
+// All of the contexts in this package implement it. A read-only context
+// ignores SetLogHandler.
+type LogHandlerContext interface {
+	LogHandler() LogHandler
+	SetLogHandler(h LogHandler)
+}
+
+// LogWith creates middleware that sends the log messages for each request to
+// the given handler.
+//
+// The handler is set on the context for the length of the request, and the
+// context's previous handler is restored afterward. Contexts that are not
+// a LogHandlerContext are left alone.
+//
+// Example:
+//
+// 	router.Use(cookoo.LogWith(cookoo.NewJSONLogger(os.Stderr, "info")))
+func LogWith(h LogHandler) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(name string, cxt Context, taint bool) error {
+			lc, ok := cxt.(LogHandlerContext)
+			if !ok {
+				return next(name, cxt, taint)
+			}
+			prev := lc.LogHandler()
+			lc.SetLogHandler(h)
+			defer lc.SetLogHandler(prev)
+			return next(name, cxt, taint)
+		}
+	}
+}
+
+// logHandler returns the context's LogHandler, or nil if it has none.
+func logHandler(cxt Context) LogHandler {
+	if lc, ok := cxt.(LogHandlerContext); ok {
+		return lc.LogHandler()
+	}
+	return nil
+}
+
+// logSkipped checks whether a context ignores log messages with the given
+// prefix, as set by ExecutionContext.SkipLogPrefix.
+func logSkipped(cxt Context, prefix string) bool {
+	if s, ok := cxt.(interface{ skipped(string) bool }); ok {
+		return s.skipped(prefix)
+	}
+	return false
+}
+
+// logLevels ranks the levels understood by JSONLogger.
+var logLevels = map[string]int{
+	"debug":   0,
+	"info":    1,
+	"warn":    2,
+	"warning": 2,
+	"error":   3,
+	"fatal":   3,
+}
+
+// logLevel ranks a level. Unknown levels are treated as "info".
+func logLevel(level string) int {
+	if n, ok := logLevels[strings.ToLower(strings.TrimSpace(level))]; ok {
+		return n
+	}
+	return logLevels["info"]
+}
+
+// JSONLogger is a LogHandler that writes each message as a line of JSON.
+//
+// Each line has the time, the level, the message, and a field for each
+// entry in Fields that is set in the context:
+//
+// 	{"command":"load","level":"warn","msg":"No user found.","route":"GET /user","time":"2015-06-01T12:00:00Z"}
+//
+// Messages below Level are dropped. The levels, from lowest to highest, are
+// debug, info, warn, and error. Any other level is treated as info.
+type JSONLogger struct {
+	// Out is where the JSON is written.
+	Out io.Writer
+	// Level is the lowest level that is written.
+	Level string
+	// Fields maps output field names to the names of context values.
+	Fields map[string]string
+
+	mu sync.Mutex
+}
+
+// NewJSONLogger creates a JSONLogger that writes messages at or above level.
+//
+// The route and command names are added to each message, as the "route"
+// and "command" fields. Add to Fields to include other context values, such
+// as a request ID:
+//
+// 	l := cookoo.NewJSONLogger(os.Stderr, "info")
+// 	l.Fields["request_id"] = "request.ID"
+func NewJSONLogger(out io.Writer, level string) *JSONLogger {
+	return &JSONLogger{
+		Out:   out,
+		Level: level,
+		Fields: map[string]string{
+			"route":   "route.Name",
+			"command": "command.Name",
+		},
+	}
+}
+
+// Handle writes a message as JSON.
+func (l *JSONLogger) Handle(cxt Context, level, msg string) {
+	if logLevel(level) < logLevel(l.Level) {
+		return
+	}
+
+	entry := map[string]json.RawMessage{
+		"time":  dumpValue(ContextClock().Format(time.RFC3339Nano)),
+		"level": dumpValue(level),
+		"msg":   dumpValue(strings.TrimSuffix(msg, "\n")),
+	}
+	for field, key := range l.Fields {
+		if v, ok := cxt.Has(key); ok {
+			entry[field] = dumpValue(v)
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		data = []byte(fmt.Sprintf(`{"level":"error","msg":%s}`, dumpValue("Could not encode log message: "+err.Error())))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Out.Write(append(data, '\n'))
+}
@@ -0,0 +1,93 @@
+package cookoo
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLogger(t *testing.T) {
+	ContextClock = func() time.Time { return time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { ContextClock = time.Now }()
+
+	var out bytes.Buffer
+	l := NewJSONLogger(&out, "info")
+	l.Fields["request_id"] = "request.ID"
+
+	cxt := NewContext()
+	cxt.(LogHandlerContext).SetLogHandler(l)
+	cxt.Put("route.Name", "test")
+	cxt.Put("request.ID", 42)
+
+	cxt.Logf("debug", "Dropped %d", 1)
+	cxt.Logf("warn", "Hello %s", "World")
+	cxt.Log("custom", "Plain ", "text")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("! Expected 2 lines, got %q", out.String())
+	}
+	expect := `{"level":"warn","msg":"Hello World","request_id":42,"route":"test","time":"2015-06-01T12:00:00Z"}`
+	if lines[0] != expect {
+		t.Errorf("! Expected %s, got %s", expect, lines[0])
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("! Expected JSON, got %s", lines[1])
+	}
+	if entry["level"] != "custom" || entry["msg"] != "Plain text" {
+		t.Errorf("! Unexpected entry: %v", entry)
+	}
+
+	out.Reset()
+	cxt.(*ExecutionContext).SkipLogPrefix("warn")
+	cxt.Log("warn", "Skipped")
+	child := cxt.NewChild()
+	child.Put("route.Name", "child")
+	child.Log("error", "From the child")
+	if !strings.Contains(out.String(), `"route":"child"`) || strings.Contains(out.String(), "Skipped") {
+		t.Errorf("! Unexpected output: %s", out.String())
+	}
+
+	// Children, and children of wrapped contexts, skip the same prefixes.
+	out.Reset()
+	child.Log("warn", "Skipped")
+	child.Logf("warn", "Skipped %d", 2)
+	SyncContext(cxt).NewChild().Logf("warn", "Skipped %d", 3)
+	ReadOnlyContext(cxt).NewChild().Logf("warn", "Skipped %d", 4)
+	if out.Len() != 0 {
+		t.Errorf("! Expected skipped prefixes to be skipped by children, got %s", out.String())
+	}
+}
+
+func TestLogWith(t *testing.T) {
+	var out bytes.Buffer
+	reg, router, cxt := Cookoo()
+	reg.Route("test", "Test logging.").
+		DoesFunc("log", func(c Context, p *Params) (interface{}, Interrupt) {
+			c.Logf("info", "Logging from %s", GetString("command.Name", "", GettableCxt(c)))
+			return nil, nil
+		})
+
+	router.Use(LogWith(NewJSONLogger(&out, "")))
+	sync := SyncContext(cxt)
+	if err := router.HandleRequest("test", sync, false); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if !strings.Contains(out.String(), `"command":"log","level":"info","msg":"Logging from log","route":"test"`) {
+		t.Errorf("! Unexpected output: %s", out.String())
+	}
+	if sync.(LogHandlerContext).LogHandler() != nil {
+		t.Error("! Expected the log handler to be removed after the request.")
+	}
+}
+
+func TestLogHandlerContexts(t *testing.T) {
+	for _, cxt := range []Context{NewContext(), SyncContext(NewContext()), ReadOnlyContext(NewContext()), NewChildContext(NewContext())} {
+		if _, ok := cxt.(LogHandlerContext); !ok {
+			t.Errorf("! Expected %T to be a LogHandlerContext.", cxt)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+/* Package metrics records how long Cookoo routes and commands take.
+
+Instrument a router once, at startup, instead of timing each command by hand:
+
+	m := metrics.New()
+	metrics.Instrument(router, m)
+
+Every request handled by the router, and every command it runs, is then
+counted and timed. Routes are labelled by route name, and commands by route
+and command name. Each is also labelled with an outcome: "ok", "error",
+"recoverable", "reroute", "stop", or "retry".
+
+The Metrics type keeps counters and latency histograms in memory, and serves
+them in the Prometheus text format, so it can be scraped without any
+Prometheus libraries:
+
+	http.Handle("/metrics", m)
+
+To send measurements somewhere else, implement Collector and pass it to
+Instrument instead.
+*/
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// Collector receives measurements from an instrumented router.
+//
+// Collectors must be safe for concurrent use.
+type Collector interface {
+	// ObserveRoute is called after each request is handled.
+	ObserveRoute(route string, elapsed time.Duration, err error)
+	// ObserveCommand is called after each attempt at a command.
+	ObserveCommand(route, command string, elapsed time.Duration, irq cookoo.Interrupt)
+}
+
+// Instrument adds middleware to a router that reports to the collector.
+func Instrument(router *cookoo.Router, c Collector) {
+	router.Use(RouteMiddleware(c))
+	router.UseCommand(CommandMiddleware(c))
+}
+
+// Unresolved is the route label for requests that could not be resolved to
+// a route.
+//
+// Request names are not used as labels, since any client could then create
+// as many series as it liked.
+const Unresolved = "unresolved"
+
+// RouteMiddleware creates middleware that times each request.
+//
+// The route is reported by its resolved name, as stored in `route.Name`. If
+// the request could not be resolved to a route that exists, it is reported
+// as Unresolved.
+func RouteMiddleware(c Collector) cookoo.Middleware {
+	return func(next cookoo.RequestHandler) cookoo.RequestHandler {
+		return func(name string, cxt cookoo.Context, taint bool) error {
+			start := time.Now()
+			err := next(name, cxt, taint)
+			route := cookoo.GetString("route.Name", Unresolved, cookoo.GettableCxt(cxt))
+			var rerr *cookoo.RouteError
+			if errors.As(err, &rerr) {
+				route = Unresolved
+			}
+			c.ObserveRoute(route, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// CommandMiddleware creates command middleware that times each command.
+func CommandMiddleware(c Collector) cookoo.CommandMiddleware {
+	return func(route, name string, next cookoo.Command) cookoo.Command {
+		return func(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			start := time.Now()
+			res, irq := next(cxt, params)
+			c.ObserveCommand(route, name, time.Since(start), irq)
+			return res, irq
+		}
+	}
+}
+
+// Outcome describes an interrupt as one of "ok", "error", "recoverable",
+// "reroute", "stop", or "retry".
+func Outcome(irq cookoo.Interrupt) string {
+	switch irq.(type) {
+	case nil:
+		return "ok"
+	case *cookoo.RecoverableError:
+		return "recoverable"
+	case *cookoo.Reroute:
+		return "reroute"
+	case *cookoo.Stop:
+		return "stop"
+	case *cookoo.Retry:
+		return "retry"
+	}
+	return "error"
+}
+
+// DefaultBuckets are the histogram buckets used by New, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics is an in-memory Collector.
+//
+// It is also an http.Handler that serves the metrics in the Prometheus text
+// format. The metrics are:
+//
+// 	- cookoo_route_requests_total{route, outcome}
+// 	- cookoo_route_duration_seconds{route}
+// 	- cookoo_command_runs_total{route, command, outcome}
+// 	- cookoo_command_duration_seconds{route, command}
+type Metrics struct {
+	buckets []float64
+
+	mu            sync.Mutex
+	routeCounts   map[string]uint64
+	routeTimes    map[string]*histogram
+	commandCounts map[string]uint64
+	commandTimes  map[string]*histogram
+}
+
+// New creates a new Metrics with the given histogram buckets, in seconds.
+// If no buckets are given, DefaultBuckets are used.
+func New(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	sort.Float64s(b)
+
+	return &Metrics{
+		buckets:       b,
+		routeCounts:   map[string]uint64{},
+		routeTimes:    map[string]*histogram{},
+		commandCounts: map[string]uint64{},
+		commandTimes:  map[string]*histogram{},
+	}
+}
+
+// ObserveRoute records a request.
+func (m *Metrics) ObserveRoute(route string, elapsed time.Duration, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routeCounts[labels("route", route, "outcome", outcome)]++
+	m.histogram(m.routeTimes, labels("route", route)).observe(elapsed.Seconds())
+}
+
+// ObserveCommand records an attempt at a command.
+func (m *Metrics) ObserveCommand(route, command string, elapsed time.Duration, irq cookoo.Interrupt) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commandCounts[labels("route", route, "command", command, "outcome", Outcome(irq))]++
+	m.histogram(m.commandTimes, labels("route", route, "command", command)).observe(elapsed.Seconds())
+}
+
+// histogram gets the histogram for a set of labels, creating it if needed.
+func (m *Metrics) histogram(hs map[string]*histogram, lbls string) *histogram {
+	h, ok := hs[lbls]
+	if !ok {
+		h = newHistogram(m.buckets)
+		hs[lbls] = h
+	}
+	return h
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.Write(res)
+}
+
+// Write writes the metrics in the Prometheus text format.
+func (m *Metrics) Write(out io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := bufio.NewWriter(out)
+	writeCounter(w, "cookoo_route_requests_total", "Requests handled, by route and outcome.", m.routeCounts)
+	writeHistogram(w, "cookoo_route_duration_seconds", "Time taken to handle requests, by route.", m.routeTimes)
+	writeCounter(w, "cookoo_command_runs_total", "Command attempts, by route, command, and outcome.", m.commandCounts)
+	writeHistogram(w, "cookoo_command_duration_seconds", "Time taken by command attempts, by route and command.", m.commandTimes)
+	return w.Flush()
+}
+
+func writeCounter(w io.Writer, name, help string, counts map[string]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, lbls := range sortedKeys(counts) {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, lbls, counts[lbls])
+	}
+}
+
+func writeHistogram(w io.Writer, name, help string, hs map[string]*histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, lbls := range sortedKeys(hs) {
+		hs[lbls].write(w, name, lbls)
+	}
+}
+
+// histogram is a cumulative histogram, as Prometheus expects.
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *histogram) write(w io.Writer, name, lbls string) {
+	for i, b := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%g\"} %d\n", name, lbls, b, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, lbls, h.count)
+	fmt.Fprintf(w, "%s_sum{%s} %g\n", name, lbls, h.sum)
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, lbls, h.count)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels formats name/value pairs as Prometheus labels.
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", pairs[i], labelEscaper.Replace(pairs[i+1])))
+	}
+	return strings.Join(parts, ",")
+}
+
+// sortedKeys returns a map's keys in order, so that output is stable.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/cookoo"
+)
+
+func fail(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	return nil, &cookoo.FatalError{Message: "Failed"}
+}
+
+func TestInstrument(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	reg.Route("test", "Test metrics.").
+		Does(cookoo.AddToContext, "add").Using("a").WithDefault(1).
+		Does(cookoo.AddToContext, "again")
+	reg.Route("fail", "Test failures.").
+		Does(fail, "fail")
+
+	m := New(1, 0.5)
+	Instrument(router, m)
+
+	router.HandleRequest("test", cxt, false)
+	router.HandleRequest("test", cxt, false)
+	router.HandleRequest("fail", cxt, false)
+	router.HandleRequest("GET /no/such/route/123", cookoo.NewContext(), false)
+
+	var out bytes.Buffer
+	if err := m.Write(&out); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	expect := []string{
+		`cookoo_route_requests_total{route="test",outcome="ok"} 2`,
+		`cookoo_route_requests_total{route="fail",outcome="error"} 1`,
+		`cookoo_route_requests_total{route="unresolved",outcome="error"} 1`,
+		`cookoo_route_duration_seconds_bucket{route="test",le="0.5"} 2`,
+		`cookoo_route_duration_seconds_bucket{route="test",le="+Inf"} 2`,
+		`cookoo_route_duration_seconds_count{route="test"} 2`,
+		`cookoo_command_runs_total{route="test",command="add",outcome="ok"} 2`,
+		`cookoo_command_runs_total{route="fail",command="fail",outcome="error"} 1`,
+		`cookoo_command_duration_seconds_count{route="test",command="again"} 2`,
+		"# TYPE cookoo_command_duration_seconds histogram",
+	}
+	for _, e := range expect {
+		if !strings.Contains(out.String(), e+"\n") {
+			t.Errorf("! Expected %s in:\n%s", e, out.String())
+		}
+	}
+
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, httptest.NewRequest("GET", "/metrics", nil))
+	if res.Body.String() != out.String() {
+		t.Error("! Expected ServeHTTP to write the same metrics.")
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := map[string]cookoo.Interrupt{
+		"ok":          nil,
+		"error":       &cookoo.FatalError{},
+		"recoverable": &cookoo.RecoverableError{},
+		"reroute":     &cookoo.Reroute{},
+		"stop":        &cookoo.Stop{},
+		"retry":       &cookoo.Retry{},
+	}
+	for expect, irq := range tests {
+		if o := Outcome(irq); o != expect {
+			t.Errorf("! Expected %s for %T, got %s", expect, irq, o)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	if l := labels("route", "a \"b\"\n"); l != `route="a \"b\"\n"` {
+		t.Errorf("! Expected escaped labels, got %s", l)
+	}
+}
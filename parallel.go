@@ -0,0 +1,148 @@
+package cookoo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// InParallel marks the most recently specified command, as set by Does, to
+// be run in parallel.
+//
+// Consecutive commands that are marked InParallel form a group. The router
+// starts every command in the group at once, waits for all of them to
+// finish, and then stores each result in the context under its command's
+// name. This is useful for routes that fetch from several independent
+// backends.
+//
+// Example:
+//
+// 	reg.Route("GET /dashboard", "Show the dashboard").
+// 		Does(LoadUser, "user").Using("id").From("query:id").InParallel().
+// 		Does(LoadFeed, "feed").Using("id").From("query:id").InParallel().
+// 		Does(RenderDashboard, "page")
+//
+// In the example above, "user" and "feed" are loaded at the same time, and
+// "page" is run once both are done.
+//
+// Commands in a group share the context, so they must not rely on each
+// other's results. The context's Go context is cancelled as soon as any
+// command in the group fails, so long-running commands can check
+// cxt.GoContext() to give up early. While a group runs, `command.Name` holds
+// the name of the first command in the group.
+//
+// If any command fails, the route ends with a ParallelError holding every
+// error. Otherwise, if a command returns a Reroute, the first Reroute is
+// followed, and if a command returns a Stop, the route stops after the
+// group. RecoverableErrors are logged, as usual.
+//
+// Groups are formed after commands are sorted by Priority.
+func (r *Registry) InParallel() *Registry {
+	r.lastCommandAdded().parallel = true
+	return r
+}
+
+// ParallelError is returned when one or more commands in a parallel group
+// fail. See Registry.InParallel.
+type ParallelError struct {
+	// Errors has the error from each command that failed, in the order the
+	// commands were declared.
+	Errors []error
+}
+
+// Error returns all of the error messages.
+func (e *ParallelError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d parallel command(s) failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors, for use with errors.Is and errors.As.
+func (e *ParallelError) Unwrap() []error {
+	return e.Errors
+}
+
+// runParallel runs a group of commands in parallel, storing their results in
+// the context. It returns the interrupt the group should be handled as, if
+// any.
+//
+// Each command is called with its params, if they have already been
+// resolved, as for doCommand.
+func (r *Router) runParallel(route string, cmds []*commandSpec, params []*Params, cxt Context) Interrupt {
+	parent := cxt.GoContext()
+	goCxt, cancel := context.WithCancel(parent)
+	cxt.SetGoContext(goCxt)
+	defer func() {
+		cancel()
+		cxt.SetGoContext(parent)
+	}()
+
+	results := make([]interface{}, len(cmds))
+	irqs := make([]Interrupt, len(cmds))
+	var wg sync.WaitGroup
+	for i, cmd := range cmds {
+		wg.Add(1)
+		go func(i int, cmd *commandSpec) {
+			defer wg.Done()
+			defer func() {
+				if p := recover(); p != nil {
+					irqs[i] = &FatalError{fmt.Sprintf("Command %s panicked: %v", cmd.name, p)}
+				}
+				if _, ok := isFailure(irqs[i]); ok {
+					cancel()
+				}
+			}()
+			results[i], irqs[i] = r.doCommand(route, cmd, params[i], cxt)
+		}(i, cmd)
+	}
+	wg.Wait()
+
+	var errs []error
+	var reroute, stop Interrupt
+	for i, cmd := range cmds {
+		res, irq := results[i], irqs[i]
+		if irq == nil && cmd.transform != nil {
+			res = cmd.transform(res)
+		}
+		cxt.Put(cmd.name, res)
+
+		if err, ok := isFailure(irq); ok {
+			errs = append(errs, err)
+			continue
+		}
+		switch irq := irq.(type) {
+		case *Reroute:
+			if reroute == nil {
+				reroute = irq
+			}
+		case *Stop:
+			stop = irq
+		case *RecoverableError:
+			cxt.Logf("warn", "Continuing after Recoverable Error on route %s: %v", route, irq)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ParallelError{errs}
+	}
+	if reroute != nil {
+		return reroute
+	}
+	return stop
+}
+
+// isFailure returns the interrupt as an error if it should end the route.
+func isFailure(irq Interrupt) (error, bool) {
+	switch irq.(type) {
+	case nil, *Reroute, *Stop, *RecoverableError:
+		return nil, false
+	}
+	err, ok := irq.(error)
+	if !ok {
+		err = fmt.Errorf("%v", irq)
+	}
+	return err, true
+}
@@ -0,0 +1,88 @@
+package cookoo
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInParallel(t *testing.T) {
+	reg, router, cxt := Cookoo()
+
+	// Each command waits for the other to start, so they can only finish if
+	// they run at the same time.
+	var started sync.WaitGroup
+	started.Add(2)
+	rendezvous := func(cxt Context, params *Params) (interface{}, Interrupt) {
+		started.Done()
+		done := make(chan struct{})
+		go func() {
+			started.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return params.Get("val", nil), nil
+		case <-time.After(2 * time.Second):
+			return nil, &FatalError{"Timed out waiting for the other command."}
+		}
+	}
+
+	reg.Route("test", "Test parallel commands.").
+		Does(rendezvous, "a").Using("val").WithDefault("A").InParallel().
+		Does(rendezvous, "b").Using("val").WithDefault("B").InParallel().
+		Does(FetchParams, "c").Using("a").From("cxt:a")
+
+	if err := router.HandleRequest("test", cxt, false); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if cxt.Get("a", nil) != "A" || cxt.Get("b", nil) != "B" {
+		t.Errorf("! Expected both results, got %v and %v", cxt.Get("a", nil), cxt.Get("b", nil))
+	}
+	if p := cxt.Get("c", nil).(*Params); p.Get("a", nil) != "A" {
+		t.Error("! Expected the next command to see the group's results.")
+	}
+}
+
+func TestInParallelErrors(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	cancelled := false
+	waiter := func(cxt Context, params *Params) (interface{}, Interrupt) {
+		select {
+		case <-cxt.GoContext().Done():
+			cancelled = true
+		case <-time.After(2 * time.Second):
+		}
+		return nil, nil
+	}
+	panicky := func(cxt Context, params *Params) (interface{}, Interrupt) {
+		panic("oops")
+	}
+
+	reg.Route("test", "Test parallel failures.").
+		Does(FatalErrorCommand, "fail").InParallel().
+		Does(waiter, "wait").InParallel().
+		Does(panicky, "panic").InParallel().
+		Does(AddToContext, "add").Using("ran").WithDefault(true)
+
+	err := router.HandleRequest("test", cxt, false)
+	perr, ok := err.(*ParallelError)
+	if !ok {
+		t.Fatalf("! Expected a ParallelError, got %T", err)
+	}
+	if len(perr.Errors) != 2 {
+		t.Errorf("! Expected two errors, got %s", perr)
+	}
+	if _, ok := perr.Errors[0].(*FatalError); !ok {
+		t.Errorf("! Expected the first error to be a FatalError, got %T", perr.Errors[0])
+	}
+	if !cancelled {
+		t.Error("! Expected the Go context to be cancelled.")
+	}
+	if cxt.GoContext().Err() != nil {
+		t.Error("! Expected the original Go context to be restored.")
+	}
+	if _, ok := cxt.Has("ran"); ok {
+		t.Error("! Expected the route to end after the group.")
+	}
+}
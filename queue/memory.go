@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBackend is a Backend that keeps jobs in memory.
+//
+// Jobs are run in the order they were pushed, except that a job is held back
+// until its NotBefore time. Jobs are lost when the process exits.
+type MemoryBackend struct {
+	mu   sync.Mutex
+	jobs []*Job
+	// pushed is closed, and replaced, whenever a job is pushed, to wake up
+	// the workers waiting in Pop.
+	pushed chan struct{}
+}
+
+// NewMemoryBackend creates a new, empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{pushed: make(chan struct{})}
+}
+
+// Push adds a job to the end of the queue.
+func (m *MemoryBackend) Push(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs = append(m.jobs, job)
+	close(m.pushed)
+	m.pushed = make(chan struct{})
+	return nil
+}
+
+// Pop removes the first job that is ready to run. See Backend.
+func (m *MemoryBackend) Pop(ctx context.Context) (*Job, error) {
+	for {
+		m.mu.Lock()
+		now := time.Now()
+		var wait time.Duration
+		for i, job := range m.jobs {
+			if d := job.NotBefore.Sub(now); d > 0 {
+				if wait == 0 || d < wait {
+					wait = d
+				}
+				continue
+			}
+			m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+			m.mu.Unlock()
+			return job, nil
+		}
+		pushed := m.pushed
+		m.mu.Unlock()
+
+		var timer *time.Timer
+		var ready <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			ready = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-pushed:
+		case <-ready:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Len returns the number of jobs in the queue, including those that are not
+// ready to run yet.
+func (m *MemoryBackend) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.jobs)
+}
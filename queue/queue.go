@@ -0,0 +1,336 @@
+/* Package queue runs Cookoo routes asynchronously, in a pool of workers.
+
+A route, and the context values to run it with, are enqueued as a Job. The
+job is stored by a Backend until a worker takes it and runs the route:
+
+	q := queue.New(queue.NewMemoryBackend(), router, cxt)
+	q.Workers = 4
+	q.MaxRetries = 3
+	q.DeadLetter = func(job *queue.Job, err error) {
+		log.Printf("Giving up on %s: %s", job.Route, err)
+	}
+	q.Start()
+	defer q.Stop(context.Background())
+
+	reg.Route("POST /signup", "Sign up").
+		Does(CreateAccount, "account").
+		Does(queue.Enqueue, "welcomeJob").
+			Using("queue").WithDefault(q).
+			Using("route").WithDefault("@send-welcome-email").
+			Using("keys").WithDefault([]string{"account"})
+
+Context values are serialized as JSON (see cookoo.SaveContextJSON), so that
+jobs can be stored outside of the process. This means that values come back
+as JSON types: numbers as float64s, and structs as maps. Use cookoo.GetAs or
+Context.Bind to read them.
+
+Each job is run with a copy of the queue's base context, which provides the
+datasources and loggers, with the job's values added to it. The Job itself
+is available as `queue.Job`. Since jobs are enqueued by the application,
+internal routes (whose names start with "@") can be run.
+
+A job whose route fails is retried, after a backoff, up to MaxRetries times.
+After that, it is passed to the DeadLetter function, or logged if there is
+none.
+
+MemoryBackend keeps jobs in memory. Other stores, such as Redis or NATS, can
+be used by implementing Backend.
+*/
+package queue
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// Job is a route to run, with the context values to run it with.
+type Job struct {
+	ID    string `json:"id"`
+	Route string `json:"route"`
+	// Values are the context values, as written by cookoo.SaveContextJSON.
+	Values json.RawMessage `json:"values"`
+	// Attempts is the number of times the job has been run.
+	Attempts int `json:"attempts"`
+	// Enqueued is when the job was first enqueued.
+	Enqueued time.Time `json:"enqueued"`
+	// NotBefore, if set, is the earliest time the job may run. It is used to
+	// delay retries.
+	NotBefore time.Time `json:"notBefore,omitempty"`
+	// LastError is the error from the last attempt, if it failed.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Backend stores jobs until a worker takes them.
+//
+// Backends must be safe for concurrent use.
+type Backend interface {
+	// Push adds a job to the queue.
+	Push(ctx context.Context, job *Job) error
+	// Pop removes the next job that is ready to run, and returns it. It
+	// blocks until there is one, or until ctx is done, in which case it
+	// returns ctx's error. A job must not be returned before its NotBefore
+	// time.
+	Pop(ctx context.Context) (*Job, error)
+}
+
+// Queue runs enqueued routes in a pool of workers.
+//
+// Fields should be set before the queue is started.
+type Queue struct {
+	// Backend stores the jobs.
+	Backend Backend
+	// Workers is the number of jobs that are run at once. Default: 1
+	Workers int
+	// MaxRetries is the number of times a failed job is run again. Default: 0
+	MaxRetries int
+	// Backoff returns how long to wait before an attempt is retried. The
+	// attempt that failed is given, starting at 1. The default waits one
+	// second, and doubles the wait for each attempt.
+	Backoff func(attempt int) time.Duration
+	// DeadLetter is called with a job that has failed for the last time. If
+	// it is nil, the failure is logged to the base context.
+	DeadLetter func(job *Job, err error)
+
+	router *cookoo.Router
+	base   cookoo.Context
+
+	mu      sync.Mutex
+	started bool
+	// stopPop stops the workers from taking new jobs.
+	stopPop context.CancelFunc
+	// cancelRuns cancels the Go context of every job. It is called if Stop
+	// gives up waiting for jobs to finish.
+	cancelRuns context.CancelFunc
+	workers    sync.WaitGroup
+}
+
+// New creates a new queue.
+//
+// Jobs are run with the router, and with a copy of the base context.
+func New(backend Backend, router *cookoo.Router, base cookoo.Context) *Queue {
+	return &Queue{Backend: backend, Workers: 1, router: router, base: base}
+}
+
+// Enqueue adds a job to run a route. The values of the context, which may be
+// nil, are saved with the job.
+//
+// It returns the new job.
+func (q *Queue) Enqueue(ctx context.Context, route string, values cookoo.Context) (*Job, error) {
+	if values == nil {
+		values = cookoo.NewContext()
+	}
+	var buf bytes.Buffer
+	if err := cookoo.SaveContextJSON(&buf, values); err != nil {
+		return nil, err
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{ID: id, Route: route, Values: buf.Bytes(), Enqueued: time.Now()}
+	if err := q.Backend.Push(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Start starts the workers. Calling Start on a running queue has no effect.
+func (q *Queue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return
+	}
+	q.started = true
+
+	popCtx, stopPop := context.WithCancel(context.Background())
+	runCtx, cancelRuns := context.WithCancel(context.Background())
+	q.stopPop, q.cancelRuns = stopPop, cancelRuns
+
+	n := q.Workers
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		q.workers.Add(1)
+		go q.work(popCtx, runCtx)
+	}
+}
+
+// Stop stops the workers from taking new jobs, and waits for the jobs that
+// are running to finish.
+//
+// If ctx expires first, the Go context of the remaining jobs is cancelled,
+// and ctx's error is returned. Jobs left in the backend stay there, to be run
+// when the queue is started again.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.started {
+		q.mu.Unlock()
+		return nil
+	}
+	q.started = false
+	stopPop, cancelRuns := q.stopPop, q.cancelRuns
+	q.mu.Unlock()
+
+	stopPop()
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		cancelRuns()
+		return nil
+	case <-ctx.Done():
+		cancelRuns()
+		return ctx.Err()
+	}
+}
+
+// work takes jobs from the backend and runs them, until popCtx is done.
+func (q *Queue) work(popCtx, runCtx context.Context) {
+	defer q.workers.Done()
+	for {
+		job, err := q.Backend.Pop(popCtx)
+		if popCtx.Err() != nil {
+			if job != nil {
+				// The job was taken as the queue stopped. Put it back.
+				q.Backend.Push(context.Background(), job)
+			}
+			return
+		}
+		if err != nil {
+			q.base.Logf("error", "Could not take a job from the queue: %s", err)
+			select {
+			case <-time.After(time.Second):
+			case <-popCtx.Done():
+				return
+			}
+			continue
+		}
+		q.run(job, runCtx)
+	}
+}
+
+// run runs a job, and retries it or gives up on it if it fails.
+func (q *Queue) run(job *Job, runCtx context.Context) {
+	job.Attempts++
+	err := q.runRoute(job, runCtx)
+	if err == nil {
+		return
+	}
+	job.LastError = err.Error()
+
+	if job.Attempts <= q.MaxRetries {
+		job.NotBefore = time.Now().Add(q.backoff(job.Attempts))
+		q.base.Logf("info", "Job %s (%s) failed, and will be retried at %s: %s", job.ID, job.Route, job.NotBefore.Format(time.RFC3339), err)
+		perr := q.Backend.Push(context.Background(), job)
+		if perr == nil {
+			return
+		}
+		err = fmt.Errorf("%s (and the retry could not be enqueued: %s)", err, perr)
+	}
+
+	if q.DeadLetter != nil {
+		q.DeadLetter(job, err)
+		return
+	}
+	q.base.Logf("error", "Job %s (%s) failed after %d attempts: %s", job.ID, job.Route, job.Attempts, err)
+}
+
+// runRoute runs a job's route with a fresh context.
+func (q *Queue) runRoute(job *Job, runCtx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	cxt := q.base.Copy()
+	if len(job.Values) > 0 {
+		values, err := cookoo.LoadContextJSON(bytes.NewReader(job.Values))
+		if err != nil {
+			return fmt.Errorf("could not load the job's context: %s", err)
+		}
+		for k, v := range values.AsMap() {
+			cxt.Put(k, v)
+		}
+	}
+	cxt.Put("queue.Job", job)
+	cxt.SetGoContext(runCtx)
+	return q.router.HandleRequest(job.Route, cxt, false)
+}
+
+func (q *Queue) backoff(attempt int) time.Duration {
+	if q.Backoff != nil {
+		return q.Backoff(attempt)
+	}
+	if attempt > 16 {
+		attempt = 16
+	}
+	return time.Second << uint(attempt-1)
+}
+
+// Enqueue is a command that adds a job to a queue.
+//
+// The job's context values can be given as a map, or copied from the
+// current context by name. Both may be used at once.
+//
+// Params:
+// 	- queue (*Queue): The queue. This is required.
+// 	- route (string): The route to run. This is required.
+// 	- values (map[string]interface{}): Values to save with the job.
+// 	- keys ([]string): Names of values in the current context to save with
+// 	  the job.
+//
+// Returns:
+// 	- The ID of the new job, as a string.
+func Enqueue(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	q, ok := params.Get("queue", nil).(*Queue)
+	if !ok {
+		return nil, &cookoo.FatalError{Message: "Expected a 'queue'"}
+	}
+	route, ok := cookoo.HasString("route", params)
+	if !ok || route == "" {
+		return nil, &cookoo.FatalError{Message: "Expected a 'route'"}
+	}
+
+	values := cookoo.NewContext()
+	if vals, ok := params.Get("values", nil).(map[string]interface{}); ok {
+		for k, v := range vals {
+			values.Put(k, v)
+		}
+	}
+	if keys, ok := params.Get("keys", nil).([]string); ok {
+		for _, k := range keys {
+			if v, ok := cxt.Has(k); ok {
+				values.Put(k, v)
+			}
+		}
+	}
+
+	job, err := q.Enqueue(cxt.GoContext(), route, values)
+	if err != nil {
+		return nil, &cookoo.FatalError{Message: fmt.Sprintf("Could not enqueue %s: %s", route, err)}
+	}
+	return job.ID, nil
+}
+
+// newJobID generates a random job ID.
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,150 @@
+package queue
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+func TestQueue(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	done := make(chan string, 10)
+	reg.Route("@greet", "Greet someone.").
+		Does(cookoo.Command(func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			job := c.Get("queue.Job", nil).(*Job)
+			done <- job.Route + ":" + p.Get("name", "").(string)
+			return nil, nil
+		}), "greet").
+		Using("name").From("cxt:name")
+
+	q := New(NewMemoryBackend(), router, cxt)
+	q.Workers = 2
+	q.Start()
+	defer q.Stop(context.Background())
+
+	values := cookoo.NewContext()
+	values.Put("name", "Matt")
+	values.Put("unencodable", make(chan int))
+	if _, err := q.Enqueue(context.Background(), "@greet", values); err != nil {
+		t.Fatal(err)
+	}
+
+	// The Enqueue command.
+	reg.Route("enqueue", "Enqueue a greeting.").
+		Does(Enqueue, "job").
+		Using("queue").WithDefault(q).
+		Using("route").WithDefault("@greet").
+		Using("keys").WithDefault([]string{"name"})
+	run := cookoo.NewContext()
+	run.Put("name", "Sam")
+	if err := router.HandleRequest("enqueue", run, false); err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := run.Get("job", nil).(string); !ok || len(id) != 32 {
+		t.Errorf("! Expected a job ID, got %v", run.Get("job", nil))
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case s := <-done:
+			seen[s] = true
+		case <-time.After(time.Second):
+			t.Fatal("! Timed out waiting for jobs.")
+		}
+	}
+	if !seen["@greet:Matt"] || !seen["@greet:Sam"] {
+		t.Errorf("! Unexpected jobs: %v", seen)
+	}
+}
+
+func TestQueueRetries(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	var attempts int32
+	reg.Route("flaky", "Fail twice.").
+		Does(cookoo.Command(func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				return nil, &cookoo.FatalError{Message: "Not yet"}
+			}
+			return nil, nil
+		}), "flaky")
+	reg.Route("broken", "Always fail.").
+		Does(cookoo.Command(func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			panic("broken")
+		}), "broken")
+
+	q := New(NewMemoryBackend(), router, cxt)
+	q.MaxRetries = 2
+	q.Backoff = func(attempt int) time.Duration { return time.Duration(attempt) * time.Millisecond }
+	dead := make(chan *Job, 1)
+	q.DeadLetter = func(job *Job, err error) { dead <- job }
+	q.Start()
+	defer q.Stop(context.Background())
+
+	q.Enqueue(context.Background(), "flaky", nil)
+	q.Enqueue(context.Background(), "broken", nil)
+
+	select {
+	case job := <-dead:
+		if job.Route != "broken" || job.Attempts != 3 || job.LastError != "panic: broken" {
+			t.Errorf("! Unexpected dead job: %+v", job)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("! Expected the broken job to be dead-lettered.")
+	}
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Errorf("! Expected the flaky job to succeed on the third attempt, got %d attempts", n)
+	}
+}
+
+func TestQueueStop(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	started := make(chan bool, 1)
+	reg.Route("slow", "Wait to be cancelled.").
+		Does(cookoo.Command(func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			started <- true
+			<-c.GoContext().Done()
+			return nil, nil
+		}), "slow")
+
+	backend := NewMemoryBackend()
+	q := New(backend, router, cxt)
+	q.Start()
+	q.Enqueue(context.Background(), "slow", nil)
+	<-started
+	q.Enqueue(context.Background(), "slow", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.Stop(ctx); err != context.DeadlineExceeded {
+		t.Errorf("! Expected a deadline error, got %v", err)
+	}
+	if backend.Len() != 1 {
+		t.Errorf("! Expected the second job to stay queued, found %d jobs", backend.Len())
+	}
+}
+
+func TestMemoryBackend(t *testing.T) {
+	m := NewMemoryBackend()
+	m.Push(context.Background(), &Job{ID: "later", NotBefore: time.Now().Add(20 * time.Millisecond)})
+	m.Push(context.Background(), &Job{ID: "now"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if job, _ := m.Pop(ctx); job == nil || job.ID != "now" {
+		t.Errorf("! Expected the ready job first, got %v", job)
+	}
+	start := time.Now()
+	if job, _ := m.Pop(ctx); job == nil || job.ID != "later" || time.Since(start) < 10*time.Millisecond {
+		t.Errorf("! Expected the delayed job to be held back, got %v", job)
+	}
+
+	short, cancel2 := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel2()
+	if _, err := m.Pop(short); err != context.DeadlineExceeded {
+		t.Errorf("! Expected Pop to give up with the context, got %v", err)
+	}
+}
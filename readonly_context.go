@@ -0,0 +1,190 @@
+package cookoo
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ReadOnlyContext wraps a context, preventing modifications to it.
+//
+// This is useful for sandboxing commands that should not be trusted to
+// change the context, such as plugins. All of the read operations are passed
+// through to the wrapped context. Write operations (Add, Put, AddWithTTL,
+// AddDatasource, RemoveDatasource, AddLogger, RemoveLogger, and SetGoContext)
+// are ignored, and a warning is logged.
+//
+// Note that values and datasources are not themselves made immutable. A
+// command can still modify a pointer or a map that it retrieves from a
+// read-only context.
+func ReadOnlyContext(cxt Context) Context {
+	return &readOnlyContext{cxt: cxt}
+}
+
+type readOnlyContext struct {
+	cxt Context
+}
+
+// Add is ignored.
+func (r *readOnlyContext) Add(key string, val ContextValue) {
+	r.Put(key, val)
+}
+
+// Put is ignored.
+func (r *readOnlyContext) Put(key string, val ContextValue) {
+	r.cxt.Logf("warn", "Ignoring attempt to put '%s' into a read-only context.", key)
+}
+
+// AddWithTTL is ignored.
+func (r *readOnlyContext) AddWithTTL(key string, val ContextValue, ttl time.Duration) {
+	r.Put(key, val)
+}
+
+// Get returns a value from the underlying context.
+func (r *readOnlyContext) Get(key string, def interface{}) ContextValue {
+	return r.cxt.Get(key, def)
+}
+
+// Has checks the underlying context for a value.
+func (r *readOnlyContext) Has(key string) (ContextValue, bool) {
+	return r.cxt.Has(key)
+}
+
+// Datasource returns a datasource from the underlying context.
+func (r *readOnlyContext) Datasource(key string) Datasource {
+	return r.cxt.Datasource(key)
+}
+
+// Datasources returns a copy of the map of datasources.
+//
+// The map is copied so that changes to it are not reflected in the
+// underlying context.
+func (r *readOnlyContext) Datasources() map[string]Datasource {
+	orig := r.cxt.Datasources()
+	ds := make(map[string]Datasource, len(orig))
+	for k, v := range orig {
+		ds[k] = v
+	}
+	return ds
+}
+
+// HasDatasource checks the underlying context for a datasource.
+func (r *readOnlyContext) HasDatasource(key string) (Datasource, bool) {
+	return r.cxt.HasDatasource(key)
+}
+
+// AddDatasource is ignored.
+func (r *readOnlyContext) AddDatasource(key string, ds Datasource) {
+	r.cxt.Logf("warn", "Ignoring attempt to add datasource '%s' to a read-only context.", key)
+}
+
+// RemoveDatasource is ignored.
+func (r *readOnlyContext) RemoveDatasource(key string) {
+	r.cxt.Logf("warn", "Ignoring attempt to remove datasource '%s' from a read-only context.", key)
+}
+
+// Len returns the length of the underlying context.
+func (r *readOnlyContext) Len() int {
+	return r.cxt.Len()
+}
+
+// Copy makes a shallow copy of the underlying context, and then wraps it in a
+// new read-only context.
+func (r *readOnlyContext) Copy() Context {
+	return ReadOnlyContext(r.cxt.Copy())
+}
+
+// DeepCopy makes a deep copy of the underlying context, and then wraps it in a
+// new read-only context.
+func (r *readOnlyContext) DeepCopy() Context {
+	return ReadOnlyContext(r.cxt.DeepCopy())
+}
+
+// Bind binds the values of the underlying context into a struct.
+func (r *readOnlyContext) Bind(target interface{}) error {
+	return r.cxt.Bind(target)
+}
+
+// AsMap returns a copy of the values in the underlying context.
+//
+// The map is copied so that changes to it are not reflected in the
+// underlying context.
+func (r *readOnlyContext) AsMap() map[string]ContextValue {
+	orig := r.cxt.AsMap()
+	vals := make(map[string]ContextValue, len(orig))
+	for k, v := range orig {
+		vals[k] = v
+	}
+	return vals
+}
+
+// Logger gets a logger from the underlying context.
+func (r *readOnlyContext) Logger(name string) (io.Writer, bool) {
+	return r.cxt.Logger(name)
+}
+
+// AddLogger is ignored.
+func (r *readOnlyContext) AddLogger(name string, logger io.Writer) {
+	r.cxt.Logf("warn", "Ignoring attempt to add logger '%s' to a read-only context.", name)
+}
+
+// RemoveLogger is ignored.
+func (r *readOnlyContext) RemoveLogger(name string) {
+	r.cxt.Logf("warn", "Ignoring attempt to remove logger '%s' from a read-only context.", name)
+}
+
+// LogHandler gets the underlying LogHandler.
+func (r *readOnlyContext) LogHandler() LogHandler {
+	return logHandler(r.cxt)
+}
+
+// SetLogHandler is ignored.
+func (r *readOnlyContext) SetLogHandler(h LogHandler) {
+	r.cxt.Logf("warn", "Ignoring attempt to set the log handler of a read-only context.")
+}
+
+// skipped checks whether the underlying context ignores log messages with
+// the prefix.
+func (r *readOnlyContext) skipped(prefix string) bool {
+	return logSkipped(r.cxt, prefix)
+}
+
+// Log sends a message to the underlying logger.
+//
+// Logging is not considered a modification of the context.
+func (r *readOnlyContext) Log(prefix string, v ...interface{}) {
+	r.cxt.Log(prefix, v...)
+}
+
+// Logf formats a message and sends it to the underlying logger.
+func (r *readOnlyContext) Logf(prefix, format string, v ...interface{}) {
+	r.cxt.Logf(prefix, format, v...)
+}
+
+// NewChild creates a child of the read-only context. See NewChildContext.
+//
+// The child may be written to, but the writes are never passed on to the
+// read-only context.
+func (r *readOnlyContext) NewChild() Context {
+	return NewChildContext(r)
+}
+
+// GoContext returns the Go context of the underlying context.
+func (r *readOnlyContext) GoContext() context.Context {
+	return r.cxt.GoContext()
+}
+
+// Keys returns the sorted names of the values in the underlying context.
+func (r *readOnlyContext) Keys() []string {
+	return r.cxt.Keys()
+}
+
+// MarshalJSON encodes the context as JSON for debugging. See DumpContext.
+func (r *readOnlyContext) MarshalJSON() ([]byte, error) {
+	return marshalContext(r.cxt)
+}
+
+// SetGoContext is ignored.
+func (r *readOnlyContext) SetGoContext(ctx context.Context) {
+	r.cxt.Logf("warn", "Ignoring attempt to set the Go context of a read-only context.")
+}
@@ -0,0 +1,66 @@
+package cookoo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadOnlyContext(t *testing.T) {
+	c := NewContext()
+	logger := new(bytes.Buffer)
+	c.AddLogger("test", logger)
+	c.Put("a", "Hello")
+	c.AddDatasource("foo", new(ExampleDatasource))
+
+	ro := ReadOnlyContext(c)
+
+	if v := ro.Get("a", nil); v != "Hello" {
+		t.Errorf("! Expected Hello, got %v", v)
+	}
+	if _, ok := ro.Has("a"); !ok {
+		t.Error("! Expected to find 'a'")
+	}
+	if _, ok := ro.HasDatasource("foo"); !ok {
+		t.Error("! Expected to find datasource 'foo'")
+	}
+
+	ro.Put("a", "Goodbye")
+	ro.Put("b", "World")
+	ro.AddDatasource("bar", new(ExampleDatasource))
+	ro.RemoveDatasource("foo")
+	ro.AsMap()["c"] = "Sneaky"
+
+	if v := c.Get("a", nil); v != "Hello" {
+		t.Errorf("! Expected 'a' to be unchanged, got %v", v)
+	}
+	if c.Len() != 1 || ro.Len() != 1 {
+		t.Errorf("! Expected one value in the context, got %d", c.Len())
+	}
+	if _, ok := c.HasDatasource("bar"); ok {
+		t.Error("! Datasource 'bar' should not have been added.")
+	}
+	if _, ok := c.HasDatasource("foo"); !ok {
+		t.Error("! Datasource 'foo' should not have been removed.")
+	}
+
+	if !strings.Contains(logger.String(), "read-only context") {
+		t.Error("! Expected a warning to be logged.")
+	}
+}
+
+func TestReadOnlyContextRoute(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	reg.Route("test", "Testing.").
+		Does(AddToContext, "add").
+		Using("foo").WithDefault("bar")
+
+	ro := ReadOnlyContext(cxt)
+	if e := router.HandleRequest("test", ro, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+
+	if _, ok := cxt.Has("foo"); ok {
+		t.Error("! Route should not have been able to write to the context.")
+	}
+}
@@ -4,7 +4,10 @@ package cookoo
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 )
 
 // A Registry contains the the callback routes and the commands each
@@ -48,6 +51,49 @@ func (r *Registry) Route(name, description string) *Registry {
 	return r
 }
 
+// DoesFunc adds an inline function as a command to the end of the chain of
+// commands for the current route.
+//
+// This is the same as Does, with the name first. It reads more naturally
+// when the command is an anonymous function:
+//
+// 	reg.Route("hello", "Say hello").
+// 		DoesFunc("greeting", func(c Context, p *Params) (interface{}, Interrupt) {
+// 			return "Hello " + GetString("name", "World", p), nil
+// 		}).
+// 			Using("name").From("cxt:name")
+//
+// Like any other command, the function is run under its name: it is stored in
+// `command.Name` while it runs, and its result is stored under that name.
+func (r *Registry) DoesFunc(commandName string, fn Command) *Registry {
+	return r.Does(fn, commandName)
+}
+
+// Input declares the context values that the current (most recently
+// specified) route requires.
+//
+// The schema maps context names to the kind of value expected. Before the
+// first command of the route is run, the router checks that every value
+// is present and of the right kind. If any are not, the route is not run,
+// and a FatalError describing every violation is returned.
+//
+// Only the context's own values are checked. Values that commands read from
+// other sources with From, such as `path:id` or a datasource, are not seen,
+// so they should not be listed in the schema.
+//
+// Example:
+//
+// 	reg.Route("adduser", "Add a user").
+// 		Input(map[string]reflect.Kind{
+// 			"username": reflect.String,
+// 			"age":      reflect.Int,
+// 		}).
+// 		Does(AddUser, "user")
+func (r *Registry) Input(schema map[string]reflect.Kind) *Registry {
+	r.currentRoute.input = schema
+	return r
+}
+
 // Does adds a command to the end of the chain of commands for the current
 // (most recently specified) route.
 func (r *Registry) Does(cmd Command, commandName string) *Registry {
@@ -63,6 +109,174 @@ func (r *Registry) Does(cmd Command, commandName string) *Registry {
 	return r
 }
 
+// Priority sets the priority of the most recently specified command as set
+// by Does.
+//
+// Before a route is run, its commands are sorted by priority. Commands with
+// a higher priority are run first. Commands with the same priority (the
+// default is 0) are run in the order in which they were added. This makes
+// it possible to declare steps (like authentication) that must run first,
+// regardless of where they appear in the route.
+//
+// Example:
+//
+// 	reg.Route("GET /secret", "A protected page").
+// 		Includes("@commonSetup").
+// 		Does(ShowSecret, "secret").
+// 		Does(auth.Basic, "auth").Priority(10)
+//
+// In the example above, "auth" will be run before any other command.
+func (r *Registry) Priority(n int) *Registry {
+	r.lastCommandAdded().priority = n
+	return r
+}
+
+// DoesIf adds a command that is only run if the predicate returns true. It
+// is the same as Does followed by When.
+func (r *Registry) DoesIf(pred Predicate, cmd Command, commandName string) *Registry {
+	return r.Does(cmd, commandName).When(pred)
+}
+
+// When makes the most recently specified command conditional, as set by Does.
+//
+// Before the command is run, its params are resolved and passed, along with
+// the context, to the predicate. If the predicate returns false, the command
+// is skipped: it is not run, and nothing is stored under its name. The rest
+// of the route carries on as usual. If When is called more than once, every
+// predicate must return true.
+//
+// This keeps small branches in the route that they belong to, instead of
+// rerouting to separate routes:
+//
+// 	reg.Route("POST /signup", "Sign up").
+// 		Does(CreateAccount, "account").
+// 		Does(SendWelcomeEmail, "welcome").
+// 			Using("account").From("cxt:account").
+// 			When(cookoo.IsSet("account.Email")).
+// 		DoesIf(cookoo.Equals("plan", "pro"), StartTrial, "trial")
+func (r *Registry) When(pred Predicate) *Registry {
+	cmd := r.lastCommandAdded()
+	cmd.conditions = append(cmd.conditions, pred)
+	return r
+}
+
+// Unless makes the most recently specified command conditional, as When
+// does, except that the command is skipped if the predicate returns true.
+func (r *Registry) Unless(pred Predicate) *Registry {
+	return r.When(Not(pred))
+}
+
+// Timeout limits how long the most recently specified command may run, as
+// set by Does.
+//
+// If the command has not returned when the timeout passes, the router stops
+// waiting for it. A TimeoutError is stored in the context as `NAME.Timeout`,
+// where NAME is the command's name, and the route ends with that error,
+// unless ContinueOnTimeout is set. The Go context that the command gets from
+// cxt.GoContext() is cancelled, so commands that pass it on to slow calls
+// are stopped too. If the command retries, each attempt gets the full
+// timeout.
+//
+// Example:
+//
+// 	reg.Route("GET /weather", "Show the weather").
+// 		Does(FetchForecast, "forecast").Timeout(2 * time.Second).ContinueOnTimeout().
+// 		Does(RenderWeather, "page").
+// 			Using("forecast").From("cxt:forecast")
+func (r *Registry) Timeout(d time.Duration) *Registry {
+	r.lastCommandAdded().timeout = d
+	return r
+}
+
+// ContinueOnTimeout lets the route carry on when the most recently specified
+// command times out, as if it had returned nil. The TimeoutError is still
+// stored in the context. See Timeout.
+func (r *Registry) ContinueOnTimeout() *Registry {
+	r.lastCommandAdded().continueOnTimeout = true
+	return r
+}
+
+// RouteTimeout limits how long the current (most recently specified) route
+// may run.
+//
+// The route's commands are run with a Go context that has the timeout as its
+// deadline. When it passes, the command that is running is given up on (see
+// Timeout), no more commands are run, and the route ends with a TimeoutError,
+// which is also stored in the context as `route.Timeout`. Routes that are
+// rerouted to run within the deadline of the route that rerouted to them.
+func (r *Registry) RouteTimeout(d time.Duration) *Registry {
+	r.currentRoute.timeout = d
+	return r
+}
+
+// Transform sets a function to process the output of the most recently
+// specified command as set by Does.
+//
+// The value returned by the command is passed through the transform function
+// before it is stored in the context. This makes it possible to adjust a
+// command's output at the registry level, without changing the command.
+//
+// The transform is not run if the command returns an interrupt.
+//
+// Example:
+//
+// 	reg.Route("GET /name", "Get a name").
+// 		Does(fmt.Sprintf, "name").
+// 			Using("format").WithDefault("%s").
+// 			Using("0").From("query:name").
+// 			Transform(func(v interface{}) interface{} {
+// 				return strings.ToUpper(v.(string))
+// 			})
+func (r *Registry) Transform(fn func(interface{}) interface{}) *Registry {
+	r.lastCommandAdded().transform = fn
+	return r
+}
+
+// Cache caches the output of the most recently specified command as set by
+// Does.
+//
+// The command's results are cached by the registry for the given ttl, in an
+// LRUCache that holds up to 1000 results. Use CacheIn to keep them in a
+// different store. The cache is keyed by the values of keyParams, so the
+// command is run again whenever any of those params changes. If no keyParams
+// are given, a single result is cached for all requests. Results are only
+// cached when the command returns no interrupt. If ttl is zero or less,
+// nothing is cached.
+//
+// ONLY pure commands should be cached: the command must return the same
+// result for the same params, and must not rely on side effects. While the
+// result is cached, the command is not run at all.
+//
+// Example:
+//
+// 	reg.Route("GET /user", "Show a user").
+// 		Does(LoadUser, "user").
+// 			Using("id").From("query:id").
+// 			Cache(5 * time.Minute, "id")
+func (r *Registry) Cache(ttl time.Duration, keyParams ...string) *Registry {
+	return r.CacheIn(NewLRUCache(defaultCacheSize), ttl, keyParams...)
+}
+
+// CacheIn caches the output of the most recently specified command in the
+// given store. It works like Cache.
+//
+// A store can be shared by several commands, or by several instances of an
+// application if it is an external store. Results are keyed by the route
+// name and the command name as well as by keyParams, so commands do not see
+// each other's results.
+//
+// Example:
+//
+// 	results := cookoo.NewLRUCache(10000)
+// 	reg.Route("GET /user", "Show a user").
+// 		Does(LoadUser, "user").
+// 			Using("id").From("query:id").
+// 			CacheIn(results, 5 * time.Minute, "id")
+func (r *Registry) CacheIn(store CacheStore, ttl time.Duration, keyParams ...string) *Registry {
+	r.lastCommandAdded().cache = newResultCache(store, ttl, keyParams)
+	return r
+}
+
 // Using specifies a paramater to use for the most recently specified command
 // as set by Does.
 func (r *Registry) Using(name string) *Registry {
@@ -96,6 +310,25 @@ func (r *Registry) From(fromVal ...string) *Registry {
 	return r
 }
 
+// Help adds help text to the most recently specified parameter, or, if the
+// most recent command has no parameters yet, to that command.
+//
+// Help text is used to document routes, for example in CLI help:
+//
+// 	reg.Route("migrate", "Migrate the database.").
+// 		Does(Migrate, "migrate").Help("Apply pending migrations.").
+// 			Using("steps").WithDefault(0).From("cxt:steps").
+// 			Help("The number of migrations to apply. 0 applies all of them.")
+func (r *Registry) Help(text string) *Registry {
+	cmd := r.lastCommandAdded()
+	if n := len(cmd.parameters); n > 0 {
+		cmd.parameters[n-1].help = text
+	} else {
+		cmd.help = text
+	}
+	return r
+}
+
 // Get the last parameter for the last command added.
 func (r *Registry) lastParamAdded() *paramSpec {
 	cspec := r.lastCommandAdded()
@@ -119,6 +352,33 @@ func (r *Registry) Includes(route string) *Registry {
 	return r
 }
 
+// Import copies every route from another registry into this one.
+//
+// Routes are added in the order they were declared in src. The rename
+// function is given each route's name, and returns the name to use in this
+// registry. If it returns an empty string, the route is skipped. A nil rename
+// keeps the names as they are.
+//
+// Like Includes, Import does not clone commands. The imported routes share
+// them with src.
+func (r *Registry) Import(src *Registry, rename func(name string) string) *Registry {
+	for _, name := range src.orderedRouteNames {
+		newName := name
+		if rename != nil {
+			if newName = rename(name); newName == "" {
+				continue
+			}
+		}
+		route := *src.routes[name]
+		route.name = newName
+		route.commands = append([]*commandSpec{}, route.commands...)
+
+		r.routes[newName] = &route
+		r.orderedRouteNames = append(r.orderedRouteNames, newName)
+	}
+	return r
+}
+
 // RouteSpec gets a ruote cased on its name.
 func (r *Registry) RouteSpec(routeName string) (spec *routeSpec, ok bool) {
 	spec, ok = r.routes[routeName]
@@ -155,14 +415,43 @@ func (r *Registry) lastCommandAdded() *commandSpec {
 	return r.currentRoute.commands[lastIndex]
 }
 
+// RouteDetails describes a route.
 type RouteDetails interface {
 	Name() string
 	Description() string
+	// Commands describes the route's commands, in the order they run.
+	Commands() []CommandDetails
+}
+
+// CommandDetails describes a command in a route.
+type CommandDetails struct {
+	Name   string         `json:"name"`
+	Help   string         `json:"help,omitempty"`
+	Params []ParamDetails `json:"params"`
+	// Conditional is true if the command is only run when predicates, as set
+	// by When or Unless, allow it.
+	Conditional bool `json:"conditional,omitempty"`
+	// Timeout is the command's timeout, as set by Timeout.
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// ParamDetails describes a parameter of a command.
+//
+// It is encoded as JSON as DumpContext encodes values, so that defaults that
+// cannot be encoded, such as functions, are described instead.
+type ParamDetails struct {
+	Name    string      `json:"name"`
+	Help    string      `json:"help,omitempty"`
+	Default interface{} `json:"default"`
+	// From lists the sources of the parameter, such as "cxt:id".
+	From []string `json:"from"`
 }
 
 type routeSpec struct {
 	name, description string
 	commands          []*commandSpec
+	input             map[string]reflect.Kind
+	timeout           time.Duration
 }
 
 func (r *routeSpec) Name() string {
@@ -173,14 +462,92 @@ func (r *routeSpec) Description() string {
 	return r.description
 }
 
+func (r *routeSpec) Commands() []CommandDetails {
+	cmds := r.orderedCommands()
+	details := make([]CommandDetails, len(cmds))
+	for i, cmd := range cmds {
+		details[i] = CommandDetails{Name: cmd.name, Help: cmd.help, Params: []ParamDetails{}, Conditional: len(cmd.conditions) > 0, Timeout: cmd.timeout}
+		for _, p := range cmd.parameters {
+			details[i].Params = append(details[i].Params, ParamDetails{
+				Name:    p.name,
+				Help:    p.help,
+				Default: p.defaultValue,
+				From:    strings.Fields(p.from),
+			})
+		}
+	}
+	return details
+}
+
+// validateInput checks a context against the route's input schema.
+//
+// It returns a list of violations, sorted by context name. An empty list
+// means that the context is valid.
+func (r *routeSpec) validateInput(cxt Context) []string {
+	names := make([]string, 0, len(r.input))
+	for name := range r.input {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	violations := []string{}
+	for _, name := range names {
+		kind := r.input[name]
+		v, ok := cxt.Has(name)
+		if !ok {
+			violations = append(violations, fmt.Sprintf("%s is missing", name))
+			continue
+		}
+		if actual := reflect.ValueOf(v).Kind(); actual != kind {
+			violations = append(violations, fmt.Sprintf("%s is %s, not %s", name, actual, kind))
+		}
+	}
+	return violations
+}
+
+// orderedCommands returns the route's commands sorted by priority.
+//
+// The sort is stable, so commands of equal priority keep their original
+// order.
+func (r *routeSpec) orderedCommands() []*commandSpec {
+	prioritized := false
+	for _, cmd := range r.commands {
+		if cmd.priority != 0 {
+			prioritized = true
+			break
+		}
+	}
+	if !prioritized {
+		return r.commands
+	}
+
+	cmds := make([]*commandSpec, len(r.commands))
+	copy(cmds, r.commands)
+	sort.SliceStable(cmds, func(i, j int) bool {
+		return cmds[i].priority > cmds[j].priority
+	})
+	return cmds
+}
+
 type commandSpec struct {
 	name       string
+	help       string
 	command    Command
 	parameters []*paramSpec
+	priority   int
+	transform  func(interface{}) interface{}
+	cache      *resultCache
+	parallel   bool
+	conditions []Predicate
+	timeout    time.Duration
+	// continueOnTimeout lets the route carry on if the command times out.
+	continueOnTimeout bool
 }
 
 type paramSpec struct {
 	name         string
 	defaultValue interface{}
 	from         string
+	help         string
+	rules        []ParamRule
 }
@@ -176,3 +176,84 @@ func TestRouteNames(t *testing.T) {
 	}
 
 }
+
+func TestDoesFunc(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	seen := ""
+	reg.Route("hello", "Say hello").
+		DoesFunc("greeting", func(c Context, p *Params) (interface{}, Interrupt) {
+			seen = c.Get("command.Name", "").(string)
+			return "Hello " + GetString("name", "World", p), nil
+		}).
+		Using("name").From("cxt:name")
+
+	spec, _ := reg.RouteSpec("hello")
+	if len(spec.commands) != 1 || spec.commands[0].name != "greeting" {
+		t.Fatal("! Expected one command named greeting.")
+	}
+
+	cxt.Put("name", "Matt")
+	if e := router.HandleRequest("hello", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	if seen != "greeting" {
+		t.Errorf("! Expected the command to run as greeting, got %s", seen)
+	}
+	if v := cxt.Get("greeting", nil); v != "Hello Matt" {
+		t.Errorf("! Expected 'Hello Matt', got %v", v)
+	}
+}
+
+func TestImport(t *testing.T) {
+	src := NewRegistry()
+	src.Route("one", "First").Does(AddToContext, "a")
+	src.Route("two", "Second").Does(AddToContext, "b")
+	src.Route("skip", "Skipped")
+
+	reg := NewRegistry()
+	reg.Route("zero", "Already here")
+	reg.Import(src, func(name string) string {
+		if name == "skip" {
+			return ""
+		}
+		return "sub/" + name
+	})
+
+	names := reg.RouteNames()
+	if len(names) != 3 || names[1] != "sub/one" || names[2] != "sub/two" {
+		t.Fatalf("! Unexpected routes: %v", names)
+	}
+	spec, _ := reg.RouteSpec("sub/one")
+	if spec.Name() != "sub/one" || spec.Description() != "First" || len(spec.commands) != 1 {
+		t.Errorf("! Unexpected route: %+v", spec)
+	}
+	reg.Import(src, nil)
+	if _, ok := reg.RouteSpec("skip"); !ok {
+		t.Error("! Expected a nil rename to keep names.")
+	}
+}
+
+func TestRouteCommands(t *testing.T) {
+	reg := NewRegistry()
+	reg.Route("migrate", "Migrate the database.").
+		Does(AddToContext, "first").Help("Runs first.").
+		Using("steps").WithDefault(3).From("cxt:steps query:steps").Help("How many steps.").
+		Using("quiet").
+		Does(AddToContext, "second").Priority(1)
+
+	spec, _ := reg.RouteSpec("migrate")
+	cmds := spec.Commands()
+	if len(cmds) != 2 || cmds[0].Name != "second" || cmds[1].Name != "first" {
+		t.Fatalf("! Expected commands in run order, got %+v", cmds)
+	}
+	if cmds[1].Help != "Runs first." || len(cmds[1].Params) != 2 {
+		t.Fatalf("! Unexpected command: %+v", cmds[1])
+	}
+	p := cmds[1].Params[0]
+	if p.Name != "steps" || p.Help != "How many steps." || p.Default != 3 || len(p.From) != 2 || p.From[1] != "query:steps" {
+		t.Errorf("! Unexpected param: %+v", p)
+	}
+	if cmds[1].Params[1].Help != "" {
+		t.Errorf("! Expected no help for quiet, got %q", cmds[1].Params[1].Help)
+	}
+}
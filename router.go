@@ -3,6 +3,7 @@ package cookoo
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 // RequestResolver is the interface for the request resolver.
@@ -20,10 +21,30 @@ type RequestResolver interface {
 // relying on the router to execute the appropriate chain of
 // commands.
 type Router struct {
-	registry *Registry
-	resolver RequestResolver
+	registry   *Registry
+	resolver   RequestResolver
+	middleware []Middleware
+	cmdWraps   []CommandMiddleware
+	before     []routeHook
+	after      []routeHook
+	onError    []errorHook
 }
 
+// RequestHandler handles a request, as Router.HandleRequest does.
+type RequestHandler func(name string, cxt Context, taint bool) error
+
+// Middleware wraps a RequestHandler, returning a new RequestHandler.
+//
+// Middleware can do work before and after a request is handled, or refuse to
+// handle the request at all by not calling next.
+type Middleware func(next RequestHandler) RequestHandler
+
+// CommandMiddleware wraps a command, returning a new command.
+//
+// It is given the names of the route and of the command, so that it can
+// label anything it records.
+type CommandMiddleware func(route, name string, next Command) Command
+
 // BasicRequestResolver is a basic resolver that assumes that the given request
 // name *is* the route name.
 type BasicRequestResolver struct {
@@ -76,6 +97,25 @@ func (r *Router) RequestResolver() RequestResolver {
 	return r.resolver
 }
 
+// Use adds middleware to the router.
+//
+// Middleware is run on every call to HandleRequest, in the order it was added.
+// So the first middleware added is the outermost. Reroutes from within a
+// route do not run the middleware again.
+func (r *Router) Use(m ...Middleware) {
+	r.middleware = append(r.middleware, m...)
+}
+
+// UseCommand adds middleware that wraps every command the router runs.
+//
+// Command middleware is run in the order it was added, so the first
+// middleware added is the outermost. Each attempt at a command is run
+// through the middleware, including commands that are answered from a
+// cache (see Registry.Cache), and commands in a parallel group.
+func (r *Router) UseCommand(m ...CommandMiddleware) {
+	r.cmdWraps = append(r.cmdWraps, m...)
+}
+
 // ResolveRequest resolver a given string into a route name.
 func (r *Router) ResolveRequest(name string, cxt Context) (string, error) {
 	routeName, e := r.resolver.Resolve(name, cxt)
@@ -108,8 +148,25 @@ func (r *Router) ResolveRequest(name string, cxt Context) (string, error) {
 // 	route.RequestName - raw route name as passed by the client
 // 	command.Name - current command name (changed with each command)
 //
+// If the context's Go context (see Context.GoContext) is cancelled or its
+// deadline passes, no further commands are run, and the Go context's error
+// (context.Canceled or context.DeadlineExceeded) is returned. If the Go
+// context has a deadline, a command that is still running when it passes is
+// given up on, as with Registry.Timeout.
+//
+// Hooks added with Before, After, and OnError are run around the route.
+//
 // If an error occurred during processing, an error type is returned.
 func (r *Router) HandleRequest(name string, cxt Context, taint bool) error {
+	handler := r.handleRequest
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+	return handler(name, cxt, taint)
+}
+
+// handleRequest does a request without running any middleware.
+func (r *Router) handleRequest(name string, cxt Context, taint bool) error {
 
 	// Not sure why we were passing a copy of the context?
 	// baseCxt := cxt.Copy()
@@ -129,7 +186,9 @@ func (r *Router) HandleRequest(name string, cxt Context, taint bool) error {
 
 	// Let an outer routine call go HandleRequest()
 	//go r.runRoute(routeName, cxt, taint)
-	e = r.runRoute(routeName, cxt, taint)
+	e = r.runHooked(routeName, cxt, func() error {
+		return r.runRoute(routeName, cxt, taint)
+	})
 
 	return e
 }
@@ -142,6 +201,47 @@ func (r *Router) HasRoute(name string) bool {
 	return ok
 }
 
+// ResumeFrom runs a route, starting at the named step.
+//
+// This is intended for picking up a route that was interrupted, using a
+// context restored from a checkpoint (see LoadContextGob). Steps before
+// stepName are skipped, so their results are expected to already be in the
+// context. The route's Input schema is not checked, since the route has
+// already been started once.
+//
+// Like HasRoute, this expects a route name, not a request name. Resumed
+// routes are trusted, so routes beginning with `@` may be resumed.
+//
+// Otherwise, the route runs as it does for HandleRequest: through the
+// router's middleware and hooks, and under the route's timeout.
+//
+// A RouteError is returned if either the route or the step does not exist.
+func (r *Router) ResumeFrom(routeName, stepName string, cxt Context) error {
+	spec, ok := r.registry.RouteSpec(routeName)
+	if !ok {
+		return &RouteError{fmt.Sprintf("Route %s does not exist.", routeName)}
+	}
+
+	cmds := spec.orderedCommands()
+	for i, cmd := range cmds {
+		if cmd.name != stepName {
+			continue
+		}
+		var handler RequestHandler = func(name string, cxt Context, taint bool) error {
+			cxt.Put("route.Name", routeName)
+			cxt.Put("route.Description", spec.description)
+			return r.runHooked(routeName, cxt, func() error {
+				return r.runTimed(routeName, spec, cmds[i:], cxt)
+			})
+		}
+		for j := len(r.middleware) - 1; j >= 0; j-- {
+			handler = r.middleware[j](handler)
+		}
+		return handler(routeName, cxt, false)
+	}
+	return &RouteError{fmt.Sprintf("Route %s has no step named %s.", routeName, stepName)}
+}
+
 // PRIVATE ==========================================================
 
 // Given a router, context, and taint, run the route.
@@ -156,16 +256,64 @@ func (r *Router) runRoute(route string, cxt Context, taint bool) error {
 	if !ok {
 		return &RouteError{fmt.Sprintf("Route %s does not exist.", route)}
 	}
+	if violations := spec.validateInput(cxt); len(violations) > 0 {
+		return &FatalError{fmt.Sprintf("Invalid input for route %s: %s", route, strings.Join(violations, "; "))}
+	}
 	// fmt.Printf("Running route %s: %s\n", spec.name, spec.description)
-	for _, cmd := range spec.commands {
-		// Provide info for each run.
-		cxt.Put("command.Name", cmd.name)
+	return r.runTimed(route, spec, spec.orderedCommands(), cxt)
+}
 
-		// fmt.Printf("Command %d is %s (%T)\n", i, cmd.name, cmd.command)
-		res, irq := r.doCommand(cmd, cxt)
+// Run a list of commands from a route, handling any interrupts.
+func (r *Router) runCommands(route string, cmds []*commandSpec, cxt Context) error {
+	for i := 0; i < len(cmds); i++ {
+		cmd := cmds[i]
 
-		// This may store a nil.
-		cxt.Put(cmd.name, res)
+		// Stop if the request has been cancelled or timed out.
+		if err := cxt.GoContext().Err(); err != nil {
+			cxt.Logf("info", "Stopping route %s before %s: %s", route, cmd.name, err)
+			return err
+		}
+
+		var irq Interrupt
+		if cmd.parallel {
+			// Run this command and the parallel ones after it as a group.
+			end := i + 1
+			for end < len(cmds) && cmds[end].parallel {
+				end++
+			}
+			group := make([]*commandSpec, 0, end-i)
+			groupParams := make([]*Params, 0, end-i)
+			for _, c := range cmds[i:end] {
+				if params, ok := r.shouldRun(c, cxt); ok {
+					group = append(group, c)
+					groupParams = append(groupParams, params)
+				}
+			}
+			i = end - 1
+			if len(group) == 0 {
+				continue
+			}
+			cxt.Put("command.Name", group[0].name)
+			irq = r.runParallel(route, group, groupParams, cxt)
+		} else {
+			params, ok := r.shouldRun(cmd, cxt)
+			if !ok {
+				continue
+			}
+			// Provide info for each run.
+			cxt.Put("command.Name", cmd.name)
+
+			// fmt.Printf("Command %d is %s (%T)\n", i, cmd.name, cmd.command)
+			var res interface{}
+			res, irq = r.doCommand(route, cmd, params, cxt)
+
+			if irq == nil && cmd.transform != nil {
+				res = cmd.transform(res)
+			}
+
+			// This may store a nil.
+			cxt.Put(cmd.name, res)
+		}
 
 		// Handle interrupts.
 		if irq != nil {
@@ -203,12 +351,90 @@ func (r *Router) runRoute(route string, cxt Context, taint bool) error {
 	return nil
 }
 
-// Do an individual command.
-func (r *Router) doCommand(cmd *commandSpec, cxt Context) (interface{}, Interrupt) {
+// shouldRun checks a command's conditions, as set by Registry.When.
+//
+// If the command has conditions, the params resolved to check them are
+// returned, so that the command can be called with them instead of
+// resolving them again. Otherwise, the params are nil.
+func (r *Router) shouldRun(cmd *commandSpec, cxt Context) (*Params, bool) {
+	if len(cmd.conditions) == 0 {
+		return nil, true
+	}
 	params := r.resolveParams(cmd, cxt)
+	for _, pred := range cmd.conditions {
+		if !pred(cxt, params) {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// Do an individual command, retrying it if it returns a Retry.
+//
+// If params is not nil, it is used for the first attempt. Params are
+// resolved again for each retry.
+func (r *Router) doCommand(route string, cmd *commandSpec, params *Params, cxt Context) (interface{}, Interrupt) {
+	for attempt := 1; ; attempt++ {
+		// This is set for every command, so that it never holds the attempt
+		// of an earlier command.
+		cxt.Put("command.Attempt", attempt)
+		res, irq := r.callTimed(route, cmd, params, cxt)
+		params = nil
+
+		retry, ok := irq.(*Retry)
+		if !ok {
+			if attempt > 1 {
+				cxt.Put(cmd.name+".Attempts", attempt)
+			}
+			return res, irq
+		}
+		if attempt > retry.Max {
+			cxt.Put(cmd.name+".Attempts", attempt)
+			return res, &FatalError{fmt.Sprintf("Command %s failed after %d attempts: %s", cmd.name, attempt, retry.Message)}
+		}
 
-	ret, irq := cmd.command(cxt, params)
-	return ret, irq
+		delay := retry.delay(attempt)
+		cxt.Logf("info", "Retrying command %s in %s (attempt %d): %s", cmd.name, delay, attempt, retry.Message)
+		select {
+		case <-time.After(delay):
+		case <-cxt.GoContext().Done():
+			return nil, cxt.GoContext().Err()
+		}
+	}
+}
+
+// Call an individual command through the command middleware, using the cache
+// if it has one. The command is not called if its params are invalid.
+//
+// Params are resolved unless they are given.
+func (r *Router) callCommand(route string, cmd *commandSpec, params *Params, cxt Context) (interface{}, Interrupt) {
+	if params == nil {
+		params = r.resolveParams(cmd, cxt)
+	}
+	if err := cmd.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	command := cmd.command
+	if cmd.cache != nil {
+		command = func(cxt Context, params *Params) (interface{}, Interrupt) {
+			key := cmd.cache.key(route, cmd.name, params)
+			if ret, ok := cmd.cache.get(key); ok {
+				return ret, nil
+			}
+			ret, irq := cmd.command(cxt, params)
+			if irq == nil {
+				if err := cmd.cache.set(key, ret); err != nil {
+					cxt.Logf("warn", "Could not cache a result: %s", err)
+				}
+			}
+			return ret, irq
+		}
+	}
+	for i := len(r.cmdWraps) - 1; i >= 0; i-- {
+		command = r.cmdWraps[i](route, cmd.name, command)
+	}
+	return command(cxt, params)
 }
 
 // Get the appropriate values for each param.
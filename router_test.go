@@ -1,7 +1,12 @@
 package cookoo
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 )
 
 // Mock resolver
@@ -361,8 +366,10 @@ func TestHandleRequest(t *testing.T) {
 
 	context = NewContext()
 	router.HandleRequest("Several", context, false)
-	if context.Len() != 7 {
-		t.Errorf("! Expected three items in the context, got %d", context.Len())
+	// The three results, plus the route and command info, including
+	// command.Attempt.
+	if context.Len() != 8 {
+		t.Errorf("! Expected eight items in the context, got %d", context.Len())
 	}
 
 	e = router.HandleRequest("", context, true)
@@ -447,3 +454,294 @@ func TestStop(t *testing.T) {
 		t.Error("! Expected fake2 to not get executed.")
 	}
 }
+
+func TestPriority(t *testing.T) {
+	reg, router, context := Cookoo()
+	order := []string{}
+	record := func(name string) Command {
+		return func(cxt Context, params *Params) (interface{}, Interrupt) {
+			order = append(order, name)
+			return true, nil
+		}
+	}
+	reg.
+		Route("TEST", "A test route").
+		Does(record("first"), "first").
+		Does(record("second"), "second").
+		Does(record("auth"), "auth").Priority(10).
+		Does(record("third"), "third").
+		Does(record("last"), "last").Priority(-1)
+
+	e := router.HandleRequest("TEST", context, false)
+	if e != nil {
+		t.Error("! Unexpected error executing TEST")
+	}
+
+	expecting := []string{"auth", "first", "second", "third", "last"}
+	if len(order) != len(expecting) {
+		t.Fatalf("! Expected %d commands to run, got %d", len(expecting), len(order))
+	}
+	for i, k := range expecting {
+		if k != order[i] {
+			t.Errorf("! Expecting %s at position %d; got %s", k, i, order[i])
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	reg, router, context := Cookoo()
+	reg.
+		Route("TEST", "A test route").
+		Does(AnotherCommand, "foo").
+		Transform(func(v interface{}) interface{} {
+			return v.(*FooType).test * 2
+		}).
+		Does(FatalErrorCommand, "fail").
+		Transform(func(v interface{}) interface{} {
+			return "transformed"
+		})
+
+	e := router.HandleRequest("TEST", context, false)
+	if e == nil {
+		t.Error("! Expected error executing TEST")
+	}
+
+	if v := context.Get("foo", nil); v != 10 {
+		t.Errorf("! Expected transformed value 10, got %v", v)
+	}
+	if v := context.Get("fail", nil); v != nil {
+		t.Errorf("! Expected no transform after an interrupt, got %v", v)
+	}
+}
+
+func TestRouteInput(t *testing.T) {
+	reg, router, context := Cookoo()
+	reg.
+		Route("TEST", "A test route").
+		Input(map[string]reflect.Kind{
+			"name":  reflect.String,
+			"age":   reflect.Int,
+			"email": reflect.String,
+		}).
+		Does(MockCommand, "fake")
+
+	context.Put("name", "Matt")
+	context.Put("age", 42)
+	context.Put("email", "matt@example.com")
+	if e := router.HandleRequest("TEST", context, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	if v := context.Get("fake", nil); v != true {
+		t.Error("! Expected the route to run.")
+	}
+
+	context = NewContext()
+	context.Put("age", "42")
+	e := router.HandleRequest("TEST", context, false)
+	if e == nil {
+		t.Fatal("! Expected invalid input to fail.")
+	}
+	if _, ok := e.(*FatalError); !ok {
+		t.Errorf("! Expected a FatalError, got %T", e)
+	}
+	expects := "Invalid input for route TEST: age is string, not int; email is missing; name is missing"
+	if e.Error() != expects {
+		t.Errorf("! Unexpected error message: %s", e)
+	}
+	if _, ok := context.Has("fake"); ok {
+		t.Error("! Expected the route to not run.")
+	}
+}
+
+func TestResumeFrom(t *testing.T) {
+	reg, router, context := Cookoo()
+	var ran []string
+	step := func(name string) Command {
+		return func(cxt Context, params *Params) (interface{}, Interrupt) {
+			ran = append(ran, name)
+			return name, nil
+		}
+	}
+	reg.Route("TEST", "A three-step route.").
+		Does(step("one"), "one").
+		Does(step("two"), "two").
+		Does(step("three"), "three")
+
+	// Checkpoint after the first step, then restore.
+	context.Put("one", "checkpointed")
+	var buf bytes.Buffer
+	if e := SaveContextGob(&buf, context); e != nil {
+		t.Fatalf("! Could not save context: %s", e)
+	}
+	restored, e := LoadContextGob(&buf)
+	if e != nil {
+		t.Fatalf("! Could not load context: %s", e)
+	}
+
+	if e := router.ResumeFrom("TEST", "two", restored); e != nil {
+		t.Fatalf("! Unexpected error: %s", e)
+	}
+	if !reflect.DeepEqual(ran, []string{"two", "three"}) {
+		t.Errorf("! Expected only steps two and three to run, got %v", ran)
+	}
+	if v := restored.Get("one", nil); v != "checkpointed" {
+		t.Errorf("! Expected step one's result to be preserved, got %v", v)
+	}
+	if v := restored.Get("three", nil); v != "three" {
+		t.Errorf("! Expected step three to run, got %v", v)
+	}
+
+	// Resumed routes run through middleware, hooks, and the route timeout.
+	var seen []string
+	router.Use(func(next RequestHandler) RequestHandler {
+		return func(name string, cxt Context, taint bool) error {
+			seen = append(seen, "middleware:"+name)
+			return next(name, cxt, taint)
+		}
+	})
+	router.Before("TEST", func(cxt Context, route string) Interrupt {
+		seen = append(seen, "before")
+		return nil
+	})
+	router.After("TEST", func(cxt Context, route string) Interrupt {
+		seen = append(seen, "after")
+		return nil
+	})
+	reg.Route("SLOW", "A route that runs out of time.").
+		Does(step("one"), "one").
+		Does(Command(func(cxt Context, params *Params) (interface{}, Interrupt) {
+			<-cxt.GoContext().Done()
+			return nil, cxt.GoContext().Err()
+		}), "two").
+		RouteTimeout(time.Millisecond)
+	if e := router.ResumeFrom("TEST", "three", restored); e != nil {
+		t.Fatalf("! Unexpected error: %s", e)
+	}
+	if !reflect.DeepEqual(seen, []string{"middleware:TEST", "before", "after"}) {
+		t.Errorf("! Expected middleware and hooks to run, got %v", seen)
+	}
+	var te *TimeoutError
+	if e := router.ResumeFrom("SLOW", "two", restored); !errors.As(e, &te) {
+		t.Errorf("! Expected a route timeout, got %v", e)
+	}
+
+	if e := router.ResumeFrom("TEST", "four", restored); e == nil {
+		t.Error("! Expected an error for a missing step.")
+	} else if _, ok := e.(*RouteError); !ok {
+		t.Errorf("! Expected a RouteError, got %T", e)
+	}
+	if e := router.ResumeFrom("NOPE", "two", restored); e == nil {
+		t.Error("! Expected an error for a missing route.")
+	}
+}
+
+func TestCancelRoute(t *testing.T) {
+	reg, router, _ := Cookoo()
+	ctx, cancel := context.WithCancel(context.Background())
+	cxt := WithGoContext(ctx)
+
+	reg.Route("TEST", "Cancel partway through.").
+		Does(AddToContext, "first").Using("first").WithDefault(true).
+		Does(Command(func(c Context, p *Params) (interface{}, Interrupt) {
+			cancel()
+			return nil, nil
+		}), "cancel").
+		Does(AddToContext, "third").Using("third").WithDefault(true)
+
+	e := router.HandleRequest("TEST", cxt, false)
+	if e != context.Canceled {
+		t.Errorf("! Expected context.Canceled, got %v", e)
+	}
+	if _, ok := cxt.Has("first"); !ok {
+		t.Error("! Expected the first command to run.")
+	}
+	if _, ok := cxt.Has("third"); ok {
+		t.Error("! Expected the route to stop after cancellation.")
+	}
+
+	// A deadline that has already passed stops the route before it starts.
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	cxt = NewContext()
+	cxt.SetGoContext(ctx)
+	if e := router.HandleRequest("TEST", cxt, false); e != context.DeadlineExceeded {
+		t.Errorf("! Expected context.DeadlineExceeded, got %v", e)
+	}
+	if _, ok := cxt.Has("first"); ok {
+		t.Error("! Expected no commands to run.")
+	}
+
+	// Children can have their own Go context.
+	child := NewContext().NewChild()
+	child.SetGoContext(ctx)
+	if child.GoContext() != ctx || child.Copy().GoContext() != ctx {
+		t.Error("! Expected the child's Go context to be used.")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	calls := 0
+	flaky := func(cxt Context, params *Params) (interface{}, Interrupt) {
+		calls++
+		if calls < 3 {
+			return nil, &Retry{Message: "not yet", Max: params.Get("max", 0).(int), Delay: time.Millisecond}
+		}
+		return cxt.Get("command.Attempt", 0), nil
+	}
+	reg.Route("retry", "Test retries.").
+		Does(flaky, "flaky").Using("max").WithDefault(2).
+		Does(FetchParams, "after")
+	reg.Route("exhausted", "Test running out of retries.").
+		Does(flaky, "flaky").Using("max").WithDefault(1)
+
+	if err := router.HandleRequest("retry", cxt, false); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	if v := cxt.Get("flaky", nil); v != 3 {
+		t.Errorf("! Expected the command to see attempt 3, got %v", v)
+	}
+	if v := cxt.Get("flaky.Attempts", nil); v != 3 {
+		t.Errorf("! Expected 3 attempts to be recorded, got %v", v)
+	}
+	if v := cxt.Get("command.Attempt", nil); v != 1 {
+		t.Errorf("! Expected the next command to be on attempt 1, got %v", v)
+	}
+
+	// A command in a fresh request does not see an earlier command's attempt.
+	fresh := NewContext()
+	reg.Route("first", "Test the first attempt.").
+		DoesFunc("attempt", func(c Context, p *Params) (interface{}, Interrupt) {
+			return c.Get("command.Attempt", nil), nil
+		})
+	if err := router.HandleRequest("first", fresh, false); err != nil || fresh.Get("attempt", nil) != 1 {
+		t.Errorf("! Expected attempt 1, got %v (%v)", fresh.Get("attempt", nil), err)
+	}
+
+	calls = 0
+	err := router.HandleRequest("exhausted", cxt, false)
+	if _, ok := err.(*FatalError); !ok {
+		t.Fatalf("! Expected a FatalError, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("! Expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	fixed := &Retry{Delay: time.Second}
+	if d := fixed.delay(3); d != time.Second {
+		t.Errorf("! Expected a fixed delay, got %s", d)
+	}
+
+	exp := &Retry{Delay: time.Second, Exponential: true, MaxDelay: 5 * time.Second}
+	expect := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, e := range expect {
+		if d := exp.delay(i + 1); d != e {
+			t.Errorf("! Expected retry %d to wait %s, got %s", i+1, e, d)
+		}
+	}
+	if d := (&Retry{Delay: time.Second, Exponential: true}).delay(100); d <= 0 {
+		t.Errorf("! Expected a long delay not to overflow, got %s", d)
+	}
+}
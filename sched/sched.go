@@ -0,0 +1,283 @@
+/* Package sched runs Cookoo routes on a schedule.
+
+Register routes with cron expressions or intervals, then start the scheduler:
+
+	s := sched.New(router, cxt)
+	s.Every("@refresh-cache", 5*time.Minute, sched.Skip)
+	if _, err := s.Cron("@nightly-report", "30 2 * * *", sched.Queue); err != nil {
+		log.Fatal(err)
+	}
+	s.Start()
+	defer s.Stop(context.Background())
+
+Each run gets a fresh copy of the base context, so runs do not share values.
+The copy has `sched.Route` and `sched.Time` (the time the run was scheduled
+for) added to it. Since routes are run by the application and not by a
+client, internal routes (whose names start with "@") can be scheduled.
+
+A run that starts while an earlier run of the same job is still going is
+handled by the job's Overlap policy.
+
+Failed runs are logged to the base context at the "error" level, or passed
+to the scheduler's OnError function if it is set.
+*/
+package sched
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// Overlap says what to do when a job is due while it is still running.
+type Overlap int
+
+const (
+	// Skip drops the run.
+	Skip Overlap = iota
+	// Queue runs the job again as soon as the current run finishes. Only one
+	// run is queued, however many are missed.
+	Queue
+	// Concurrent starts the run anyway, alongside the one that is running.
+	Concurrent
+)
+
+// Job is a route that is run on a schedule.
+type Job struct {
+	Route    string
+	Schedule Schedule
+	Overlap  Overlap
+
+	mu      sync.Mutex
+	running int
+	queued  bool
+	next    time.Time
+}
+
+// Next returns the time when the job will next run, or a zero time if it is
+// not scheduled to run.
+func (j *Job) Next() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.next
+}
+
+// Running returns the number of runs of the job that are in progress.
+func (j *Job) Running() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.running
+}
+
+// Scheduler runs routes on a schedule.
+type Scheduler struct {
+	// OnError is called when a run fails. If it is nil, the error is logged
+	// to the base context.
+	OnError func(route string, err error)
+
+	router *cookoo.Router
+	base   cookoo.Context
+
+	mu      sync.Mutex
+	jobs    []*Job
+	started bool
+	stop    chan struct{}
+	// runCtx is the Go context of every run. It is cancelled if Stop gives
+	// up waiting for runs to finish.
+	runCtx context.Context
+	cancel context.CancelFunc
+	ticks  sync.WaitGroup
+	runs   sync.WaitGroup
+}
+
+// New creates a new scheduler that runs routes with the router. Each run gets
+// a copy of the base context.
+func New(router *cookoo.Router, base cookoo.Context) *Scheduler {
+	return &Scheduler{router: router, base: base}
+}
+
+// Add schedules a route. If the scheduler has been started, the job starts
+// right away.
+func (s *Scheduler) Add(route string, schedule Schedule, overlap Overlap) *Job {
+	j := &Job{Route: route, Schedule: schedule, Overlap: overlap}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, j)
+	if s.started {
+		s.startJob(j)
+	}
+	return j
+}
+
+// Every schedules a route to run at a fixed interval. See Every.
+func (s *Scheduler) Every(route string, d time.Duration, overlap Overlap) *Job {
+	return s.Add(route, Every(d), overlap)
+}
+
+// Cron schedules a route with a cron expression. See Cron.
+func (s *Scheduler) Cron(route, expr string, overlap Overlap) (*Job, error) {
+	schedule, err := Cron(expr)
+	if err != nil {
+		return nil, err
+	}
+	return s.Add(route, schedule, overlap), nil
+}
+
+// Jobs returns the scheduled jobs, in the order they were added.
+func (s *Scheduler) Jobs() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Job{}, s.jobs...)
+}
+
+// Start starts running jobs. Calling Start on a running scheduler has no
+// effect.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	s.stop = make(chan struct{})
+	s.runCtx, s.cancel = context.WithCancel(context.Background())
+	for _, j := range s.jobs {
+		s.startJob(j)
+	}
+}
+
+// Stop stops scheduling runs, and waits for the runs in progress to finish.
+//
+// If ctx expires first, the Go context of the remaining runs is cancelled,
+// and ctx's error is returned. Queued runs are dropped. The scheduler can be
+// started again afterwards.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	s.started = false
+	close(s.stop)
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	s.ticks.Wait()
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		cancel()
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	}
+}
+
+// startJob starts the goroutine that runs a job on its schedule. The caller
+// must hold s.mu.
+func (s *Scheduler) startJob(j *Job) {
+	stop, runCtx := s.stop, s.runCtx
+	s.ticks.Add(1)
+	go func() {
+		defer s.ticks.Done()
+		for {
+			now := time.Now()
+			next := j.Schedule.Next(now)
+			j.mu.Lock()
+			j.next = next
+			j.mu.Unlock()
+			if next.IsZero() {
+				return
+			}
+
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-stop:
+				timer.Stop()
+				j.mu.Lock()
+				j.next = time.Time{}
+				j.queued = false
+				j.mu.Unlock()
+				return
+			case <-timer.C:
+				s.due(j, next, stop, runCtx)
+			}
+		}
+	}()
+}
+
+// due starts a run of a job, following its overlap policy.
+func (s *Scheduler) due(j *Job, at time.Time, stop chan struct{}, runCtx context.Context) {
+	j.mu.Lock()
+	if j.running > 0 {
+		switch j.Overlap {
+		case Skip:
+			j.mu.Unlock()
+			s.base.Logf("info", "Skipping scheduled route %s: the previous run has not finished.", j.Route)
+			return
+		case Queue:
+			j.queued = true
+			j.mu.Unlock()
+			return
+		}
+	}
+	j.running++
+	j.mu.Unlock()
+
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		for {
+			s.run(j, at, runCtx)
+
+			j.mu.Lock()
+			again := j.queued
+			j.queued = false
+			select {
+			case <-stop:
+				again = false
+			default:
+			}
+			if !again {
+				j.running--
+				j.mu.Unlock()
+				return
+			}
+			j.mu.Unlock()
+			at = time.Now()
+		}
+	}()
+}
+
+// run runs a job's route once, with a fresh context.
+func (s *Scheduler) run(j *Job, at time.Time, runCtx context.Context) {
+	cxt := s.base.Copy()
+	cxt.SetGoContext(runCtx)
+	cxt.Put("sched.Route", j.Route)
+	cxt.Put("sched.Time", at)
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return s.router.HandleRequest(j.Route, cxt, false)
+	}()
+	if err == nil {
+		return
+	}
+	if s.OnError != nil {
+		s.OnError(j.Route, err)
+		return
+	}
+	s.base.Logf("error", "Scheduled route %s failed: %s", j.Route, err)
+}
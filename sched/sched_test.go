@@ -0,0 +1,166 @@
+package sched
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+func TestCron(t *testing.T) {
+	base := time.Date(2024, time.March, 15, 10, 7, 30, 0, time.UTC) // A Friday.
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 3, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 3, 15, 10, 15, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, 3, 16, 2, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 3, 15, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * mon", time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,20 * mon", time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", base.Add(90 * time.Second)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := Cron(tt.expr)
+		if err != nil {
+			t.Errorf("! Could not parse %q: %s", tt.expr, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(tt.want) {
+			t.Errorf("! Expected %q to run next at %s, got %s", tt.expr, tt.want, got)
+		}
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "@every -1s", "x * * * *"} {
+		if _, err := Cron(expr); err == nil {
+			t.Errorf("! Expected an error for %q", expr)
+		}
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	var runs int32
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	reg.Route("@tick", "Count ticks.").
+		Does(cookoo.Command(func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			if _, ok := c.Has("leftover"); ok {
+				t.Error("! Expected a fresh context for each run.")
+			}
+			c.Put("leftover", true)
+			mu.Lock()
+			seen[c.Get("sched.Route", "").(string)] = true
+			mu.Unlock()
+			atomic.AddInt32(&runs, 1)
+			return nil, nil
+		}), "tick")
+	reg.Route("@fail", "Fail.").
+		Does(cookoo.Command(func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			return nil, &cookoo.FatalError{Message: "Failed"}
+		}), "fail")
+
+	s := New(router, cxt)
+	failed := make(chan string, 10)
+	s.OnError = func(route string, err error) { failed <- route }
+	s.Every("@tick", 5*time.Millisecond, Skip)
+	s.Start()
+	s.Every("@fail", 5*time.Millisecond, Skip)
+
+	select {
+	case route := <-failed:
+		if route != "@fail" {
+			t.Errorf("! Unexpected failed route %s", route)
+		}
+	case <-time.After(time.Second):
+		t.Error("! Expected @fail to be reported.")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	n := atomic.LoadInt32(&runs)
+	if n < 2 || !seen["@tick"] {
+		t.Errorf("! Expected @tick to run several times, ran %d times", n)
+	}
+	if !s.Jobs()[0].Next().IsZero() {
+		t.Error("! Expected no next run after Stop.")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if atomic.LoadInt32(&runs) != n {
+		t.Error("! Expected no runs after Stop.")
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	for _, tt := range []struct {
+		overlap     Overlap
+		maxParallel int32
+	}{{Skip, 1}, {Queue, 1}, {Concurrent, 2}} {
+		reg, router, cxt := cookoo.Cookoo()
+		var running, maxRunning, runs int32
+		reg.Route("slow", "A slow route.").
+			Does(cookoo.Command(func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				time.Sleep(25 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				atomic.AddInt32(&runs, 1)
+				return nil, nil
+			}), "slow")
+
+		s := New(router, cxt)
+		s.Every("slow", 5*time.Millisecond, tt.overlap)
+		s.Start()
+		time.Sleep(60 * time.Millisecond)
+		s.Stop(context.Background())
+
+		if m := atomic.LoadInt32(&maxRunning); (tt.maxParallel == 1 && m != 1) || (tt.maxParallel > 1 && m < 2) {
+			t.Errorf("! Overlap %d: expected at most %d runs at once, got %d", tt.overlap, tt.maxParallel, m)
+		}
+		if atomic.LoadInt32(&running) != 0 {
+			t.Errorf("! Overlap %d: expected Stop to wait for runs.", tt.overlap)
+		}
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	cancelled := make(chan bool, 1)
+	reg.Route("stuck", "Wait to be cancelled.").
+		Does(cookoo.Command(func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			<-c.GoContext().Done()
+			cancelled <- true
+			return nil, nil
+		}), "stuck")
+
+	s := New(router, cxt)
+	j := s.Every("stuck", time.Millisecond, Skip)
+	s.Start()
+	for j.Running() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); err != context.DeadlineExceeded {
+		t.Errorf("! Expected a deadline error, got %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("! Expected the run's Go context to be cancelled.")
+	}
+}
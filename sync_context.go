@@ -1,12 +1,17 @@
 package cookoo
 
 import (
+	"context"
 	"sync"
 	"io"
+	"time"
 )
 
 // SyncContext wraps a context, syncronizing access to it.
 //
+// ExecutionContext is already safe for concurrent use. SyncContext is useful
+// for wrapping other Context implementations.
+//
 // This uses a read/write mutex which allows multiple reads at a time, but
 // locks both reading and writing for writes.
 //
@@ -107,6 +112,22 @@ func (s *synchronizedContext) Len() int {
 func (s *synchronizedContext) Copy() Context {
 	return SyncContext(s.cxt.Copy())
 }
+
+// DeepCopy read-locks the context, makes a deep copy of it, and then wraps the
+// copy in a new synchronizer.
+func (s *synchronizedContext) DeepCopy() Context {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return SyncContext(s.cxt.DeepCopy())
+}
+
+// Bind read-locks the context, and binds its values into a struct.
+func (s *synchronizedContext) Bind(target interface{}) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.cxt.Bind(target)
+}
+
 // AsMap returns an unsynchronized map of the values in this context.
 //
 // This will give you access to the values, not the datasources or logger.
@@ -146,6 +167,28 @@ func (s *synchronizedContext) RemoveLogger(name string) {
 	s.cxt.RemoveLogger(name)
 }
 
+// LogHandler read-locks the context and gets the underlying LogHandler.
+func (s *synchronizedContext) LogHandler() LogHandler {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return logHandler(s.cxt)
+}
+
+// SetLogHandler locks the context and sets the underlying LogHandler.
+func (s *synchronizedContext) SetLogHandler(h LogHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if lc, ok := s.cxt.(LogHandlerContext); ok {
+		lc.SetLogHandler(h)
+	}
+}
+
+// skipped checks whether the underlying context ignores log messages with
+// the prefix.
+func (s *synchronizedContext) skipped(prefix string) bool {
+	return logSkipped(s.cxt, prefix)
+}
+
 // Log sends a message to the underlying logger.
 //
 // This method is not synchronized. It is expected that the underlying
@@ -161,5 +204,43 @@ func (s *synchronizedContext) Logf(prefix, format string, v ...interface{}) {
 	s.cxt.Logf(prefix, format, v...)
 }
 
+// NewChild creates a child of the synchronized context. See NewChildContext.
+func (s *synchronizedContext) NewChild() Context {
+	return NewChildContext(s)
+}
+
+// GoContext returns the Go context of the underlying context.
+func (s *synchronizedContext) GoContext() context.Context {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.cxt.GoContext()
+}
 
+// Keys read-locks the context and returns the sorted names of its values.
+func (s *synchronizedContext) Keys() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.cxt.Keys()
+}
 
+// MarshalJSON read-locks the context and encodes it as JSON for debugging.
+// See DumpContext.
+func (s *synchronizedContext) MarshalJSON() ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return marshalContext(s.cxt)
+}
+
+// AddWithTTL locks the context and then inserts an expiring value.
+func (s *synchronizedContext) AddWithTTL(key string, val ContextValue, ttl time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cxt.AddWithTTL(key, val, ttl)
+}
+
+// SetGoContext locks the context and then sets the Go context.
+func (s *synchronizedContext) SetGoContext(ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cxt.SetGoContext(ctx)
+}
@@ -0,0 +1,50 @@
+package cookoo
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSyncContextConcurrency(t *testing.T) {
+	c := SyncContext(NewContext())
+	c.AddDatasource("foo", new(ExampleDatasource))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key%d", i)
+			for j := 0; j < 100; j++ {
+				c.Add(key, j)
+				c.AddWithTTL("ttl", j, time.Hour)
+				if _, ok := c.Has(key); !ok {
+					t.Errorf("! Expected to find %s", key)
+				}
+				c.Get("ttl", nil)
+				c.Keys()
+				c.Len()
+				c.Datasource("foo")
+				c.HasDatasource(key)
+				c.AddDatasource(key, new(ExampleDatasource))
+				c.RemoveDatasource(key)
+				c.Copy()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if c.Len() != 51 {
+		t.Errorf("! Expected 51 values, got %d", c.Len())
+	}
+	for i := 0; i < 50; i++ {
+		if v := c.Get(fmt.Sprintf("key%d", i), nil); v != 99 {
+			t.Errorf("! Expected key%d to be 99, got %v", i, v)
+		}
+	}
+	if len(c.Datasources()) != 1 {
+		t.Errorf("! Expected one datasource, got %d", len(c.Datasources()))
+	}
+}
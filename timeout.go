@@ -0,0 +1,121 @@
+package cookoo
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is returned when a command or a route runs out of time.
+//
+// See Registry.Timeout and Registry.RouteTimeout.
+type TimeoutError struct {
+	Route string
+	// Command is the command that timed out. It is empty if the route as a
+	// whole timed out.
+	Command string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Command == "" {
+		return fmt.Sprintf("Route %s timed out after %s", e.Route, e.Timeout)
+	}
+	return fmt.Sprintf("Command %s on route %s timed out after %s", e.Command, e.Route, e.Timeout)
+}
+
+// Unwrap returns context.DeadlineExceeded, so that a TimeoutError can be
+// checked with errors.Is like any other deadline.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// runTimed runs some of a route's commands under the route's timeout, if it
+// has one.
+func (r *Router) runTimed(route string, spec *routeSpec, cmds []*commandSpec, cxt Context) error {
+	if spec.timeout <= 0 {
+		return r.runCommands(route, cmds, cxt)
+	}
+
+	parent := cxt.GoContext()
+	goCxt, cancel := context.WithTimeout(parent, spec.timeout)
+	cxt.SetGoContext(goCxt)
+	defer func() {
+		cancel()
+		cxt.SetGoContext(parent)
+	}()
+
+	err := r.runCommands(route, cmds, cxt)
+	if err != nil && goCxt.Err() == context.DeadlineExceeded && parent.Err() == nil {
+		terr := &TimeoutError{Route: route, Timeout: spec.timeout}
+		cxt.Put("route.Timeout", terr)
+		return terr
+	}
+	return err
+}
+
+// callTimed calls a command, giving up on it if its timeout, or the deadline
+// of the context's Go context, passes first.
+//
+// A command that is given up on is not stopped, since Go has no way to do
+// that. It keeps running in the background until it returns, and its result
+// is thrown away. Commands that may be slow should use the Go context from
+// cxt.GoContext(), which is cancelled when the timeout passes.
+func (r *Router) callTimed(route string, cmd *commandSpec, params *Params, cxt Context) (interface{}, Interrupt) {
+	parent := cxt.GoContext()
+	_, hasDeadline := parent.Deadline()
+	if cmd.timeout <= 0 && !hasDeadline {
+		return r.callCommand(route, cmd, params, cxt)
+	}
+
+	goCxt, cancel := parent, context.CancelFunc(func() {})
+	if cmd.timeout > 0 {
+		goCxt, cancel = context.WithTimeout(parent, cmd.timeout)
+		// A parallel group shares one Go context, so a command in a group
+		// is timed, but its Go context is the group's.
+		if !cmd.parallel {
+			cxt.SetGoContext(goCxt)
+			defer cxt.SetGoContext(parent)
+		}
+	}
+	defer cancel()
+
+	type result struct {
+		res      interface{}
+		irq      Interrupt
+		panicked bool
+		p        interface{}
+	}
+	done := make(chan result, 1)
+	go func() {
+		var out result
+		defer func() {
+			if p := recover(); p != nil {
+				out.panicked, out.p = true, p
+			}
+			done <- out
+		}()
+		out.res, out.irq = r.callCommand(route, cmd, params, cxt)
+	}()
+
+	select {
+	case out := <-done:
+		// Panics are passed on, as if the command had run in this goroutine.
+		if out.panicked {
+			panic(out.p)
+		}
+		return out.res, out.irq
+	case <-goCxt.Done():
+	}
+
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	terr := &TimeoutError{Route: route, Command: cmd.name, Timeout: cmd.timeout}
+	cxt.Put(cmd.name+".Timeout", terr)
+	if cmd.continueOnTimeout {
+		cxt.Logf("warn", "%s. Continuing.", terr)
+		return nil, nil
+	}
+	return nil, terr
+}
@@ -0,0 +1,96 @@
+package cookoo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// sleeper sleeps for "d", or until its Go context is done.
+func sleeper(cxt Context, params *Params) (interface{}, Interrupt) {
+	select {
+	case <-time.After(params.Get("d", time.Second).(time.Duration)):
+		return "awake", nil
+	case <-cxt.GoContext().Done():
+		return nil, cxt.GoContext().Err()
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	reg.Route("slow", "A slow route.").
+		Does(sleeper, "nap").Using("d").WithDefault(time.Millisecond).Timeout(time.Second).
+		Does(sleeper, "sleep").Timeout(10*time.Millisecond).
+		Does(AddToContext, "after").Using("ran").WithDefault(true)
+	reg.Route("tolerant", "Carry on after a timeout.").
+		Does(sleeper, "sleep").Timeout(10*time.Millisecond).ContinueOnTimeout().
+		Does(AddToContext, "after").Using("ran").WithDefault(true)
+
+	err := router.HandleRequest("slow", cxt, false)
+	var terr *TimeoutError
+	if !errors.As(err, &terr) || terr.Command != "sleep" || terr.Route != "slow" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("! Expected a TimeoutError for sleep, got %v", err)
+	}
+	if cxt.Get("nap", nil) != "awake" {
+		t.Error("! Expected a fast command to finish within its timeout.")
+	}
+	if _, ok := cxt.Has("sleep.Timeout"); !ok {
+		t.Error("! Expected the timeout to be recorded.")
+	}
+	if _, ok := cxt.Has("ran"); ok {
+		t.Error("! Expected the route to stop after the timeout.")
+	}
+
+	cxt = NewContext()
+	if err := router.HandleRequest("tolerant", cxt, false); err != nil {
+		t.Fatalf("! Expected the route to continue, got %v", err)
+	}
+	if _, ok := cxt.Has("sleep.Timeout"); !ok || cxt.Get("ran", false) != true {
+		t.Error("! Expected the timeout to be recorded and the route to carry on.")
+	}
+}
+
+func TestRouteTimeout(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	reg.Route("slow", "A slow route.").
+		RouteTimeout(20*time.Millisecond).
+		Does(sleeper, "one").Using("d").WithDefault(time.Millisecond).
+		Does(sleeper, "two").Using("d").WithDefault(time.Minute).
+		Does(AddToContext, "after").Using("ran").WithDefault(true)
+
+	start := time.Now()
+	err := router.HandleRequest("slow", cxt, false)
+	var terr *TimeoutError
+	if !errors.As(err, &terr) || terr.Command != "" || terr.Timeout != 20*time.Millisecond {
+		t.Fatalf("! Expected a route TimeoutError, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("! Expected the route to be given up on promptly.")
+	}
+	if _, ok := cxt.Has("route.Timeout"); !ok {
+		t.Error("! Expected the timeout to be recorded.")
+	}
+	if _, ok := cxt.Has("ran"); ok {
+		t.Error("! Expected no commands after the deadline.")
+	}
+	if cxt.GoContext().Err() != nil {
+		t.Error("! Expected the route's Go context to be removed afterwards.")
+	}
+}
+
+func TestTimeoutPanics(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	reg.Route("panic", "Panic in a timed command.").
+		DoesFunc("boom", func(c Context, p *Params) (interface{}, Interrupt) {
+			panic("boom")
+		}).Timeout(time.Second)
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("! Expected the panic to be passed on, got %v", p)
+		}
+	}()
+	router.HandleRequest("panic", cxt, false)
+	t.Error("! Expected a panic.")
+}
@@ -0,0 +1,240 @@
+/* Package tracing traces Cookoo requests and the commands they run.
+
+Instrument a router once, at startup:
+
+	tracing.Instrument(router, tracer)
+
+Each call to HandleRequest then starts a span, and each command the router
+runs starts a child span. Spans record the request, route, and command names
+as attributes.
+
+Params are not recorded by default, since they may hold passwords or
+tokens. To record some of them, name them when instrumenting:
+
+	tracing.Instrument(router, tracer, "id", "page")
+
+The request's span is carried in the Go context of the cookoo.Context (see
+cookoo.Context.GoContext). So commands and datasources that start their own
+spans from cxt.GoContext(), and any routes reached by a Reroute, stay in the
+same trace.
+
+The Tracer interface is small, so that it can be backed by any tracing
+system. For example, an OpenTelemetry tracer can be adapted like this:
+
+	type otelTracer struct{ trace.Tracer }
+
+	func (t otelTracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, tracing.Span) {
+		ctx, span := t.Tracer.Start(ctx, name)
+		s := otelSpan{span}
+		for k, v := range attrs {
+			s.SetAttribute(k, v)
+		}
+		return ctx, s
+	}
+
+	type otelSpan struct{ trace.Span }
+
+	func (s otelSpan) SetAttribute(k, v string) { s.SetAttributes(attribute.String(k, v)) }
+	func (s otelSpan) SetError(err error)       { s.RecordError(err); s.SetStatus(codes.Error, err.Error()) }
+	func (s otelSpan) End()                     { s.Span.End() }
+
+A Recorder, which keeps spans in memory, is included for tests and
+debugging.
+*/
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// Tracer starts spans.
+//
+// Start begins a span as a child of any span in ctx, and returns a context
+// that carries the new span. Tracers must be safe for concurrent use.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span)
+}
+
+// Span is a unit of work in a trace.
+type Span interface {
+	SetAttribute(key, value string)
+	// SetError marks the span as failed.
+	SetError(err error)
+	End()
+}
+
+// MaxParamLength is the most bytes of a param value that are recorded.
+// Longer values are truncated.
+const MaxParamLength = 256
+
+// Instrument adds middleware to a router that traces every request and
+// command. The named params are recorded on command spans. See
+// CommandMiddleware.
+func Instrument(router *cookoo.Router, t Tracer, params ...string) {
+	router.Use(RouteMiddleware(t))
+	router.UseCommand(CommandMiddleware(t, params...))
+}
+
+// RouteMiddleware creates middleware that starts a span for each request.
+//
+// The span is named after the request, and has the attributes
+// "cookoo.request" and, once the request is resolved, "cookoo.route". While
+// the request runs, the context's Go context carries the span.
+func RouteMiddleware(t Tracer) cookoo.Middleware {
+	return func(next cookoo.RequestHandler) cookoo.RequestHandler {
+		return func(name string, cxt cookoo.Context, taint bool) error {
+			parent := cxt.GoContext()
+			ctx, span := t.Start(parent, name, map[string]string{"cookoo.request": name})
+			cxt.SetGoContext(ctx)
+			defer func() {
+				cxt.SetGoContext(parent)
+				span.End()
+			}()
+
+			err := next(name, cxt, taint)
+			if route, ok := cookoo.HasString("route.Name", cookoo.GettableCxt(cxt)); ok {
+				span.SetAttribute("cookoo.route", route)
+			}
+			if err != nil {
+				span.SetError(err)
+			}
+			return err
+		}
+	}
+}
+
+// CommandMiddleware creates command middleware that starts a span for each
+// command.
+//
+// The span is named after the command, and has the attributes
+// "cookoo.route" and "cookoo.command". Each of the named params that the
+// command has is also recorded, as "cookoo.param.NAME". Values are
+// formatted with fmt.Sprint, and cut off at MaxParamLength bytes. No params
+// are recorded unless they are named.
+//
+// Command spans are children of the request's span. They are not put into
+// the context, since commands in a parallel group share it.
+func CommandMiddleware(t Tracer, params ...string) cookoo.CommandMiddleware {
+	return func(route, name string, next cookoo.Command) cookoo.Command {
+		return func(cxt cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			attrs := map[string]string{
+				"cookoo.route":   route,
+				"cookoo.command": name,
+			}
+			for _, k := range params {
+				if v, ok := p.Has(k); ok {
+					attrs["cookoo.param."+k] = truncate(fmt.Sprint(v), MaxParamLength)
+				}
+			}
+
+			_, span := t.Start(cxt.GoContext(), name, attrs)
+			defer span.End()
+
+			res, irq := next(cxt, p)
+			switch irq := irq.(type) {
+			case nil, *cookoo.Reroute, *cookoo.Stop:
+			case error:
+				span.SetError(irq)
+			default:
+				span.SetError(fmt.Errorf("%v", irq))
+			}
+			return res, irq
+		}
+	}
+}
+
+// truncate cuts s off at n bytes, without splitting a UTF-8 character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
+
+// RecordedSpan is a span kept by a Recorder.
+type RecordedSpan struct {
+	ID, ParentID int
+	Name         string
+	Attributes   map[string]string
+	Err          error
+	Start, End   time.Time
+}
+
+// Recorder is a Tracer that keeps finished spans in memory.
+//
+// Span IDs start at 1. A span with no parent has a ParentID of 0.
+type Recorder struct {
+	mu     sync.Mutex
+	lastID int
+	spans  []*RecordedSpan
+}
+
+// NewRecorder creates a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+type recorderKey struct{}
+
+// Start starts a span.
+func (r *Recorder) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span) {
+	r.mu.Lock()
+	r.lastID++
+	span := &recorderSpan{r: r, s: &RecordedSpan{
+		ID:         r.lastID,
+		Name:       name,
+		Attributes: map[string]string{},
+		Start:      time.Now(),
+	}}
+	r.mu.Unlock()
+
+	if parent, ok := ctx.Value(recorderKey{}).(*recorderSpan); ok && parent.r == r {
+		span.s.ParentID = parent.s.ID
+	}
+	for k, v := range attrs {
+		span.s.Attributes[k] = v
+	}
+	return context.WithValue(ctx, recorderKey{}, span), span
+}
+
+// Spans returns the spans that have ended, in the order they ended.
+func (r *Recorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]*RecordedSpan, len(r.spans))
+	copy(spans, r.spans)
+	return spans
+}
+
+type recorderSpan struct {
+	r *Recorder
+	s *RecordedSpan
+}
+
+func (s *recorderSpan) SetAttribute(key, value string) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.s.Attributes[key] = value
+}
+
+func (s *recorderSpan) SetError(err error) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.s.Err = err
+}
+
+func (s *recorderSpan) End() {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.s.End = time.Now()
+	s.r.spans = append(s.r.spans, s.s)
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Masterminds/cookoo"
+)
+
+func TestInstrument(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	rec := NewRecorder()
+	var inCommand context.Context
+
+	reg.Route("start", "Test tracing.").
+		Does(cookoo.AddToContext, "add").Using("a").WithDefault(1).Using("password").WithDefault("secret").
+		Does(cookoo.ForwardTo, "fwd").Using("route").WithDefault("next")
+	reg.Route("next", "Test tracing a reroute.").
+		DoesFunc("capture", func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			inCommand = c.GoContext()
+			return nil, &cookoo.FatalError{Message: "Oops"}
+		})
+
+	Instrument(router, rec, "a")
+	if err := router.HandleRequest("start", cxt, false); err == nil {
+		t.Fatal("! Expected the route to fail.")
+	}
+
+	spans := rec.Spans()
+	if len(spans) != 4 {
+		t.Fatalf("! Expected 4 spans, got %d", len(spans))
+	}
+	add, fwd, capture, req := spans[0], spans[1], spans[2], spans[3]
+
+	if req.Name != "start" || req.ParentID != 0 {
+		t.Errorf("! Expected a root span for the request, got %s with parent %d", req.Name, req.ParentID)
+	}
+	if req.Attributes["cookoo.route"] != "start" {
+		t.Errorf("! Expected the route to be recorded, got %s", req.Attributes["cookoo.route"])
+	}
+	if req.Err == nil || capture.Err == nil {
+		t.Error("! Expected the error to be recorded.")
+	}
+	for _, s := range []*RecordedSpan{add, fwd, capture} {
+		if s.ParentID != req.ID {
+			t.Errorf("! Expected %s to be a child of the request span.", s.Name)
+		}
+	}
+	if add.Attributes["cookoo.command"] != "add" || add.Attributes["cookoo.param.a"] != "1" {
+		t.Errorf("! Unexpected attributes: %v", add.Attributes)
+	}
+	if _, ok := add.Attributes["cookoo.param.password"]; ok {
+		t.Error("! Expected params that were not named to be left out.")
+	}
+	if capture.Attributes["cookoo.route"] != "next" {
+		t.Errorf("! Expected the rerouted route name, got %s", capture.Attributes["cookoo.route"])
+	}
+
+	if s, ok := inCommand.Value(recorderKey{}).(*recorderSpan); !ok || s.s.ID != req.ID {
+		t.Error("! Expected the Go context to carry the request span.")
+	}
+	if cxt.GoContext().Value(recorderKey{}) != nil {
+		t.Error("! Expected the Go context to be restored.")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if s := truncate("hello", 10); s != "hello" {
+		t.Errorf("! Expected a short value to be kept, got %q", s)
+	}
+	if s := truncate("héllo", 2); s != "h..." {
+		t.Errorf("! Expected a whole character to be cut, got %q", s)
+	}
+}
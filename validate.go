@@ -0,0 +1,338 @@
+package cookoo
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ParamRule checks, and may convert, the value of a param.
+//
+// A rule gets the current value, and returns the value to use in its place.
+// If the value is not valid, the rule returns an error describing why. Rules
+// are usually chained, so a value may be converted by one rule and then
+// checked by the next.
+//
+// Only Required checks missing values. The other rules pass a nil value
+// through untouched, so optional params can still be validated.
+type ParamRule func(value interface{}) (interface{}, error)
+
+// Validate adds rules to the most recently specified parameter, as set by
+// Using.
+//
+// Before the command is run, the router resolves its params and runs each
+// param's rules, in order. If any param is invalid, the command is not run,
+// and the route fails with a ParamsError listing every invalid param.
+// Converted values are passed on to the command, so it can use them
+// without casting.
+//
+// Example:
+//
+// 	reg.Route("GET /list", "Show a page of items").
+// 		Does(ListItems, "items").
+// 			Using("page").From("query:page").WithDefault("1").
+// 				Validate(cookoo.Coerce("int"), cookoo.Min(1)).
+// 			Using("sort").From("query:sort").
+// 				Validate(cookoo.Required, cookoo.OneOf("name", "date")).
+// 			Using("code").From("query:code").
+// 				Validate(cookoo.Matches(`^[A-Z]{3}$`))
+//
+// In the example above, ListItems can read `p.Get("page", 1).(int)`.
+func (r *Registry) Validate(rules ...ParamRule) *Registry {
+	param := r.lastParamAdded()
+	param.rules = append(param.rules, rules...)
+	return r
+}
+
+// Check runs rules against a param, and stores the value they return.
+//
+// This lets a command validate params on its own, with the same rules that
+// Registry.Validate uses. If the value is not valid, the param is left as
+// it was, and a *ParamError is returned.
+//
+// 	if err := p.Check("limit", cookoo.Coerce("int"), cookoo.Max(100)); err != nil {
+// 		return nil, &cookoo.FatalError{Message: err.Error()}
+// 	}
+func (p *Params) Check(name string, rules ...ParamRule) error {
+	val, err := applyRules(name, p.storage[name], rules)
+	if err != nil {
+		return err
+	}
+	if val != nil || p.storage[name] != nil {
+		p.storage[name] = val
+	}
+	return nil
+}
+
+// ParamError describes a single invalid param.
+type ParamError struct {
+	// Param is the name of the param.
+	Param string
+	// Value is the value that failed, before any rules were applied.
+	Value interface{}
+	// Err is the error from the rule that failed.
+	Err error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("%s %s", e.Param, e.Err)
+}
+
+// ParamsError is returned when a command's params fail validation. See
+// Registry.Validate.
+type ParamsError struct {
+	// Command is the name of the command whose params were invalid.
+	Command string
+	// Errors has an error for each invalid param, in the order the params
+	// were declared.
+	Errors []*ParamError
+}
+
+// Error returns all of the error messages.
+func (e *ParamsError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("Invalid params for command %s: %s", e.Command, strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the error for each param.
+func (e *ParamsError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Required fails if a param is missing, nil, or an empty string.
+func Required(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, fmt.Errorf("is required")
+	}
+	if s, ok := value.(string); ok && len(s) == 0 {
+		return nil, fmt.Errorf("is required")
+	}
+	return value, nil
+}
+
+// Coerce converts a param to another type.
+//
+// Supported types are "string", "int", "int64", "float64", "bool", and
+// "duration". Strings are parsed, using time.ParseDuration for durations.
+// Numbers may be converted to other kinds of numbers, as long as no
+// information is lost.
+func Coerce(to string) ParamRule {
+	return func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		return coerce(value, to)
+	}
+}
+
+// Matches fails unless a param is a string that matches a regular
+// expression.
+//
+// Like regexp.MustCompile, it panics if the pattern is invalid, so that
+// mistakes are caught when routes are registered.
+func Matches(pattern string) ParamRule {
+	re := regexp.MustCompile(pattern)
+	return func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("is %T, not a string", value)
+		}
+		if !re.MatchString(s) {
+			return nil, fmt.Errorf("does not match %s", pattern)
+		}
+		return value, nil
+	}
+}
+
+// OneOf fails unless a param is equal to one of the given values.
+func OneOf(allowed ...interface{}) ParamRule {
+	return func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		for _, a := range allowed {
+			if reflect.DeepEqual(value, a) {
+				return value, nil
+			}
+		}
+		strs := make([]string, len(allowed))
+		for i, a := range allowed {
+			strs[i] = fmt.Sprint(a)
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(strs, ", "))
+	}
+}
+
+// Min fails if a param is less than n.
+//
+// Numbers and durations are compared by value. Strings, slices, and maps
+// are compared by length.
+func Min(n float64) ParamRule {
+	return func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		size, what, err := measure(value)
+		if err != nil {
+			return nil, err
+		}
+		if size < n {
+			return nil, fmt.Errorf("must be at least %v%s", n, what)
+		}
+		return value, nil
+	}
+}
+
+// Max fails if a param is greater than n. See Min.
+func Max(n float64) ParamRule {
+	return func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		size, what, err := measure(value)
+		if err != nil {
+			return nil, err
+		}
+		if size > n {
+			return nil, fmt.Errorf("must be at most %v%s", n, what)
+		}
+		return value, nil
+	}
+}
+
+// validateParams runs the rules for each of a command's params, replacing
+// the values with the ones the rules return.
+func (c *commandSpec) validateParams(params *Params) error {
+	var errs []*ParamError
+	for _, ps := range c.parameters {
+		if len(ps.rules) == 0 {
+			continue
+		}
+		if err := params.Check(ps.name, ps.rules...); err != nil {
+			errs = append(errs, err.(*ParamError))
+		}
+	}
+	if len(errs) > 0 {
+		return &ParamsError{Command: c.name, Errors: errs}
+	}
+	return nil
+}
+
+// applyRules runs rules on a value in order, stopping at the first failure.
+func applyRules(name string, value interface{}, rules []ParamRule) (interface{}, error) {
+	val := value
+	for _, rule := range rules {
+		var err error
+		if val, err = rule(val); err != nil {
+			return nil, &ParamError{Param: name, Value: value, Err: err}
+		}
+	}
+	return val, nil
+}
+
+// measure gets the size of a value for Min and Max, and a description of
+// the unit when it is not the value itself.
+func measure(value interface{}) (float64, string, error) {
+	if d, ok := value.(time.Duration); ok {
+		return d.Seconds(), " seconds", nil
+	}
+	if s, ok := value.(string); ok {
+		return float64(utf8.RuneCountInString(s)), " characters", nil
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case isInt(v.Kind()):
+		return float64(v.Int()), "", nil
+	case isUint(v.Kind()):
+		return float64(v.Uint()), "", nil
+	case isFloat(v.Kind()):
+		return v.Float(), "", nil
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items", nil
+	}
+	return 0, "", fmt.Errorf("is %T, which has no size", value)
+}
+
+// coerce converts a value for Coerce.
+func coerce(value interface{}, to string) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		var (
+			res interface{}
+			err error
+		)
+		switch to {
+		case "string":
+			return s, nil
+		case "int":
+			res, err = strconv.Atoi(s)
+		case "int64":
+			res, err = strconv.ParseInt(s, 10, 64)
+		case "float64":
+			res, err = strconv.ParseFloat(s, 64)
+		case "bool":
+			res, err = strconv.ParseBool(s)
+		case "duration":
+			res, err = time.ParseDuration(s)
+		default:
+			return nil, fmt.Errorf("cannot be converted to unknown type %s", to)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("is not a valid %s: %q", to, s)
+		}
+		return res, nil
+	}
+
+	if to == "string" {
+		if b, ok := value.([]byte); ok {
+			return string(b), nil
+		}
+		return fmt.Sprint(value), nil
+	}
+
+	var target reflect.Type
+	switch to {
+	case "int":
+		target = reflect.TypeOf(0)
+	case "int64":
+		target = reflect.TypeOf(int64(0))
+	case "float64":
+		target = reflect.TypeOf(float64(0))
+	case "bool":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("is %T, not a bool", value)
+	case "duration":
+		if d, ok := value.(time.Duration); ok {
+			return d, nil
+		}
+		return nil, fmt.Errorf("is %T, not a duration", value)
+	default:
+		return nil, fmt.Errorf("cannot be converted to unknown type %s", to)
+	}
+
+	if !isNumber(reflect.ValueOf(value).Kind()) {
+		return nil, fmt.Errorf("is %T, which cannot be converted to %s", value, to)
+	}
+	res, ok := convertValue(value, target)
+	if !ok {
+		return nil, fmt.Errorf("cannot be converted to %s without losing information: %v", to, value)
+	}
+	return res.Interface(), nil
+}
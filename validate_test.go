@@ -0,0 +1,110 @@
+package cookoo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	reg, router, cxt := Cookoo()
+	reg.Route("test", "Test param validation.").
+		Does(FetchParams, "params").
+		Using("page").From("cxt:page").WithDefault("1").
+		Validate(Coerce("int"), Min(1)).
+		Using("wait").From("cxt:wait").
+		Validate(Coerce("duration"), Max(60)).
+		Using("sort").From("cxt:sort").
+		Validate(Required, OneOf("name", "date")).
+		Using("code").From("cxt:code").
+		Validate(Matches(`^[A-Z]{3}$`)).
+		Using("debug").From("cxt:debug").
+		Validate(Coerce("bool"))
+
+	cxt.Put("page", "3")
+	cxt.Put("wait", "1m")
+	cxt.Put("sort", "name")
+	cxt.Put("debug", "true")
+	if err := router.HandleRequest("test", cxt, false); err != nil {
+		t.Fatalf("! Unexpected error: %s", err)
+	}
+	p := cxt.Get("params", nil).(*Params)
+	if v := p.Get("page", nil); v != 3 {
+		t.Errorf("! Expected page to be the int 3, got %#v", v)
+	}
+	if v := p.Get("wait", nil); v != time.Minute {
+		t.Errorf("! Expected a minute, got %#v", v)
+	}
+	if v := p.Get("debug", nil); v != true {
+		t.Errorf("! Expected debug to be true, got %#v", v)
+	}
+	if _, ok := p.Has("code"); ok {
+		t.Error("! Expected the optional code to stay missing.")
+	}
+
+	cxt = NewContext()
+	cxt.Put("page", "0")
+	cxt.Put("wait", "2m")
+	cxt.Put("code", "abc")
+	cxt.Put("debug", "maybe")
+	err := router.HandleRequest("test", cxt, false)
+	perr, ok := err.(*ParamsError)
+	if !ok {
+		t.Fatalf("! Expected a ParamsError, got %T: %v", err, err)
+	}
+	if v := cxt.Get("params", nil); v != nil {
+		t.Error("! Expected the command not to run.")
+	}
+	if perr.Command != "params" || len(perr.Errors) != 5 {
+		t.Fatalf("! Unexpected error: %s", perr)
+	}
+	expect := []string{
+		"page must be at least 1",
+		"wait must be at most 60 seconds",
+		"sort is required",
+		"code does not match ^[A-Z]{3}$",
+		`debug is not a valid bool: "maybe"`,
+	}
+	for i, msg := range expect {
+		if perr.Errors[i].Error() != msg {
+			t.Errorf("! Expected %q, got %q", msg, perr.Errors[i])
+		}
+	}
+	if perr.Errors[0].Value != "0" {
+		t.Errorf("! Expected the original value, got %#v", perr.Errors[0].Value)
+	}
+}
+
+func TestParamsCheck(t *testing.T) {
+	p := NewParamsWithValues(map[string]interface{}{
+		"limit": 20.0,
+		"ratio": 0.5,
+		"tags":  []string{"a", "b", "c"},
+		"name":  "",
+	})
+
+	if err := p.Check("limit", Coerce("int"), Max(100)); err != nil {
+		t.Errorf("! Unexpected error: %s", err)
+	}
+	if v := p.Get("limit", nil); v != 20 {
+		t.Errorf("! Expected the int 20, got %#v", v)
+	}
+	if err := p.Check("ratio", Coerce("int")); err == nil || !strings.Contains(err.Error(), "losing information") {
+		t.Errorf("! Expected a lossy conversion to fail, got %v", err)
+	}
+	if v := p.Get("ratio", nil); v != 0.5 {
+		t.Errorf("! Expected ratio to be unchanged, got %#v", v)
+	}
+	if err := p.Check("tags", Max(2)); err == nil || err.Error() != "tags must be at most 2 items" {
+		t.Errorf("! Expected too many tags, got %v", err)
+	}
+	if err := p.Check("name", Required); err == nil {
+		t.Error("! Expected an empty name to be missing.")
+	}
+	if err := p.Check("missing", Coerce("int"), Min(1)); err != nil {
+		t.Errorf("! Expected a missing value to pass, got %s", err)
+	}
+	if p.Len() != 4 {
+		t.Errorf("! Expected no params to be added, got %d", p.Len())
+	}
+}
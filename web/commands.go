@@ -2,6 +2,7 @@ package web
 
 import (
 	"bytes"
+	"crypto/x509"
 	"fmt"
 	"github.com/Masterminds/cookoo"
 	"html/template"
@@ -11,6 +12,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 )
 
 // Common web-oriented commands
@@ -169,6 +171,8 @@ func ServerInfo(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.
 
 // ServeFiles is a cookoo command to serve files from a set of filesystem directories.
 //
+// For ETags, compression, and directory indexes, see ServeStatic.
+//
 // If no writer is specified, this will attempt to write to whatever is in the
 // Context with the key "http.ResponseWriter". If no suitable writer is found, it will
 // not write to anything at all.
@@ -234,3 +238,212 @@ func ServeFiles(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.
 	}
 	return nil, &cookoo.Reroute{"@404"}
 }
+
+// MaxBodySize limits the size of the request body.
+//
+// The request body is wrapped in an `http.MaxBytesReader`, so any command
+// that reads past the limit will get an error instead of the rest of the body.
+// When the client sends a Content-Length that is larger than the limit, the
+// request is rejected immediately. This should be placed before any commands
+// that read the body, since it protects them from oversized uploads.
+//
+// Params:
+// 	- size (int64): The maximum number of bytes allowed in the body. This
+// 	  is required. An int is also accepted.
+// 	- writer: A ResponseWriter. This will use the HTTP response if no writer
+// 	  is specified.
+// 	- request: A request. This will use the HTTP request if no request
+// 	  is specified.
+//
+// Returns:
+// 	- boolean true
+//
+// If the body is too large, a FatalError is returned. Its message begins with
+// the text of `http.StatusRequestEntityTooLarge` (413).
+func MaxBodySize(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	var size int64
+	switch n := params.Get("size", nil).(type) {
+	case int64:
+		size = n
+	case int:
+		size = int64(n)
+	default:
+		return nil, &cookoo.FatalError{Message: "Expected 'size' to be an int64"}
+	}
+
+	writer, ok := params.Has("writer")
+	if !ok {
+		writer, ok = cxt.Has("http.ResponseWriter")
+		if !ok {
+			return nil, &cookoo.FatalError{Message: "No http.ResponseWriter found."}
+		}
+	}
+	req, ok := params.Has("request")
+	if !ok {
+		req, ok = cxt.Has("http.Request")
+		if !ok {
+			return nil, &cookoo.FatalError{Message: "No http.Request found."}
+		}
+	}
+	out := writer.(http.ResponseWriter)
+	in := req.(*http.Request)
+
+	if in.ContentLength > size {
+		msg := fmt.Sprintf("%s: body is %d bytes, limit is %d", http.StatusText(http.StatusRequestEntityTooLarge), in.ContentLength, size)
+		return nil, &cookoo.FatalError{Message: msg}
+	}
+
+	if in.Body != nil {
+		in.Body = http.MaxBytesReader(out, in.Body, size)
+	}
+	return true, nil
+}
+
+// ServeSSE sends events from a channel to the client as Server-Sent Events.
+//
+// Each value received from the channel is written as a `data:` frame and
+// flushed immediately. Values are formatted the same way as in Flush: a
+// []byte is sent unchanged, and anything else is converted with fmt's `%v`.
+// Multi-line values are sent as multiple `data:` lines in a single event.
+// To set an event's ID, name, or retry time, send an Event.
+//
+// This command blocks until the channel is closed or the client goes away
+// (the request's context is cancelled).
+//
+// Params:
+// 	- events: A `<-chan interface{}` (or `chan interface{}`) of events. This is
+// 	  required, and is usually passed in with From("cxt:...").
+// 	- writer: A ResponseWriter. This will use the HTTP response if no writer
+// 	  is specified. It must implement http.Flusher.
+// 	- request: A request. This will use the HTTP request if no request
+// 	  is specified.
+// 	- heartbeat (time.Duration): If set, a comment is sent whenever no event
+// 	  has been sent for this long. This keeps proxies from closing idle
+// 	  streams.
+//
+// Returns:
+// 	- The number of events sent, as an int.
+func ServeSSE(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	var events <-chan interface{}
+	switch ch := params.Get("events", nil).(type) {
+	case <-chan interface{}:
+		events = ch
+	case chan interface{}:
+		events = ch
+	default:
+		return 0, &cookoo.FatalError{Message: "Expected 'events' to be a <-chan interface{}"}
+	}
+
+	writer, ok := params.Has("writer")
+	if !ok {
+		writer, ok = cxt.Has("http.ResponseWriter")
+		if !ok {
+			return 0, &cookoo.FatalError{Message: "No http.ResponseWriter found."}
+		}
+	}
+	out := writer.(http.ResponseWriter)
+	flusher, ok := out.(http.Flusher)
+	if !ok {
+		return 0, &cookoo.FatalError{Message: "The ResponseWriter does not support flushing."}
+	}
+
+	// Without a request, there's no way to know the client has gone away.
+	var done <-chan struct{}
+	req, ok := params.Has("request")
+	if !ok {
+		req, ok = cxt.Has("http.Request")
+	}
+	if ok {
+		done = req.(*http.Request).Context().Done()
+	}
+
+	header := out.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	out.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var (
+		ticker *time.Ticker
+		beat   <-chan time.Time
+	)
+	heartbeat, _ := params.Get("heartbeat", time.Duration(0)).(time.Duration)
+	if heartbeat > 0 {
+		ticker = time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		beat = ticker.C
+	}
+
+	sent := 0
+	for {
+		select {
+		case <-done:
+			cxt.Logf("info", "Client closed the event stream after %d events.", sent)
+			return sent, nil
+		case <-beat:
+			io.WriteString(out, ":\n\n")
+			flusher.Flush()
+		case ev, ok := <-events:
+			if !ok {
+				return sent, nil
+			}
+			writeEvent(out, ev)
+			flusher.Flush()
+			sent++
+			if ticker != nil {
+				// Heartbeats are only needed while the stream is idle.
+				ticker.Reset(heartbeat)
+			}
+		}
+	}
+}
+
+// RequireClientCert requires a verified TLS client certificate.
+//
+// This is used for mutual TLS, where clients identify themselves with a
+// certificate. The server must be configured to request client certificates
+// (see `tls.Config.ClientAuth`). This command then checks the peer
+// certificate of the request, and passes it to the `verify` function for
+// application-specific checks, such as checking the subject against a list
+// of allowed services.
+//
+// Params:
+// 	- verify (func(*x509.Certificate) bool): A function that returns true if
+// 	  the certificate is acceptable. If not specified, any certificate that
+// 	  passed the TLS handshake is accepted.
+// 	- request: A request. This will use the HTTP request if no request
+// 	  is specified.
+//
+// Returns:
+// 	- The subject's common name (CN) as a string.
+//
+// If there is no client certificate, or if verify rejects it, a FatalError is
+// returned.
+func RequireClientCert(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	req, ok := params.Has("request")
+	if !ok {
+		req, ok = cxt.Has("http.Request")
+		if !ok {
+			return nil, &cookoo.FatalError{Message: "No http.Request found."}
+		}
+	}
+	in := req.(*http.Request)
+
+	if in.TLS == nil || len(in.TLS.PeerCertificates) == 0 {
+		return nil, &cookoo.FatalError{Message: "No client certificate presented."}
+	}
+	cert := in.TLS.PeerCertificates[0]
+
+	if v, ok := params.Has("verify"); ok {
+		verify, ok := v.(func(*x509.Certificate) bool)
+		if !ok {
+			return nil, &cookoo.FatalError{Message: "Expected 'verify' to be a func(*x509.Certificate) bool"}
+		}
+		if !verify(cert) {
+			return nil, &cookoo.FatalError{Message: fmt.Sprintf("Client certificate for '%s' was rejected.", cert.Subject.CommonName)}
+		}
+	}
+
+	return cert.Subject.CommonName, nil
+}
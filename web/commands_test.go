@@ -0,0 +1,224 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/Masterminds/cookoo"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	reg.Route("test", "Test body size.").
+		Does(MaxBodySize, "limit").
+		Using("size").WithDefault(int64(10))
+
+	// Within the limit.
+	req, _ := http.NewRequest("POST", "http://example.com/upload", strings.NewReader("0123456789"))
+	cxt.Put("http.Request", req)
+	cxt.Put("http.ResponseWriter", httptest.NewRecorder())
+	if e := router.HandleRequest("test", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Errorf("! Unexpected error reading body: %s", err)
+	}
+	if string(body) != "0123456789" {
+		t.Errorf("! Unexpected body: %s", body)
+	}
+
+	// Content-Length is too large.
+	req, _ = http.NewRequest("POST", "http://example.com/upload", strings.NewReader("0123456789A"))
+	cxt.Put("http.Request", req)
+	e := router.HandleRequest("test", cxt, false)
+	if e == nil {
+		t.Fatal("! Expected an oversized body to fail.")
+	}
+	if _, ok := e.(*cookoo.FatalError); !ok {
+		t.Errorf("! Expected a FatalError, got %T", e)
+	}
+	if !strings.HasPrefix(e.Error(), "Request Entity Too Large") {
+		t.Errorf("! Unexpected error message: %s", e)
+	}
+
+	// No Content-Length, so the limit is enforced on read.
+	req, _ = http.NewRequest("POST", "http://example.com/upload", ioutil.NopCloser(strings.NewReader("0123456789A")))
+	req.ContentLength = -1
+	cxt.Put("http.Request", req)
+	if e := router.HandleRequest("test", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	if _, err := ioutil.ReadAll(req.Body); err == nil {
+		t.Error("! Expected reading past the limit to fail.")
+	}
+}
+
+func TestServeSSE(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	reg.Route("test", "Test SSE.").
+		Does(ServeSSE, "sent").
+		Using("events").From("cxt:events")
+
+	events := make(chan interface{}, 2)
+	events <- "hello"
+	events <- "two\nlines"
+	close(events)
+
+	req, _ := http.NewRequest("GET", "http://example.com/events", nil)
+	res := httptest.NewRecorder()
+	cxt.Put("http.Request", req)
+	cxt.Put("http.ResponseWriter", res)
+	cxt.Put("events", events)
+
+	if e := router.HandleRequest("test", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+
+	if v := cxt.Get("sent", 0); v != 2 {
+		t.Errorf("! Expected 2 events to be sent, got %v", v)
+	}
+	if ct := res.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("! Unexpected content type: %s", ct)
+	}
+	if !res.Flushed {
+		t.Error("! Expected the response to be flushed.")
+	}
+	expects := "data: hello\n\ndata: two\ndata: lines\n\n"
+	if body := res.Body.String(); body != expects {
+		t.Errorf("! Unexpected body: %q", body)
+	}
+}
+
+func TestRequireClientCert(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	reg.Route("test", "Test client certs.").
+		Does(RequireClientCert, "client").
+		Using("verify").WithDefault(func(cert *x509.Certificate) bool {
+		return cert.Subject.CommonName == "billing"
+	})
+
+	req, _ := http.NewRequest("GET", "https://example.com/internal", nil)
+	cxt.Put("http.Request", req)
+
+	// No TLS at all.
+	if e := router.HandleRequest("test", cxt, false); e == nil {
+		t.Error("! Expected a request without a certificate to fail.")
+	}
+
+	// An accepted certificate.
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{
+			{Subject: pkix.Name{CommonName: "billing"}},
+		},
+	}
+	if e := router.HandleRequest("test", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	if cn := cxt.Get("client", nil); cn != "billing" {
+		t.Errorf("! Expected CN billing, got %v", cn)
+	}
+
+	// A rejected certificate.
+	cxt = cookoo.NewContext()
+	req.TLS.PeerCertificates[0].Subject.CommonName = "intruder"
+	cxt.Put("http.Request", req)
+	e := router.HandleRequest("test", cxt, false)
+	if e == nil {
+		t.Fatal("! Expected a rejected certificate to fail.")
+	}
+	if _, ok := e.(*cookoo.FatalError); !ok {
+		t.Errorf("! Expected a FatalError, got %T", e)
+	}
+	if _, ok := cxt.Has("client"); ok && cxt.Get("client", nil) != nil {
+		t.Error("! Expected no CN to be stored for a rejected certificate.")
+	}
+}
+
+func TestServeSSEEvents(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	reg.Route("test", "Test SSE events.").
+		Does(ServeSSE, "sent").
+		Using("events").From("cxt:events").
+		Using("heartbeat").WithDefault(time.Millisecond)
+
+	events := make(chan interface{})
+	go func() {
+		events <- &Event{ID: "1", Event: "update", Data: "hi", Retry: 2 * time.Second}
+		time.Sleep(20 * time.Millisecond)
+		events <- Event{ID: "2\ndata: injected", Event: "up\r\ndate", Data: "a\rb"}
+		events <- Event{Data: []byte("raw")}
+		close(events)
+	}()
+
+	res := httptest.NewRecorder()
+	cxt.Put("http.ResponseWriter", res)
+	cxt.Put("events", events)
+	if e := router.HandleRequest("test", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+
+	body := res.Body.String()
+	if !strings.HasPrefix(body, "id: 1\nevent: update\nretry: 2000\ndata: hi\n\n") {
+		t.Errorf("! Unexpected body: %q", body)
+	}
+	if !strings.Contains(body, "id: 2data: injected\nevent: update\ndata: a\ndata: b\n\n") {
+		t.Errorf("! Expected line breaks to be removed from fields: %q", body)
+	}
+	if !strings.Contains(body, ":\n\n") || !strings.HasSuffix(body, "data: raw\n\n") {
+		t.Errorf("! Expected heartbeats and a raw event: %q", body)
+	}
+}
+
+func TestStream(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	reg.Route("reader", "Stream a reader.").
+		Does(Stream, "sent").
+		Using("reader").From("cxt:reader").
+		Using("chunkSize").WithDefault(4).
+		Using("contentType").WithDefault("text/csv")
+	reg.Route("chunks", "Stream a channel.").
+		Does(Stream, "sent").
+		Using("chunks").From("cxt:chunks")
+
+	res := httptest.NewRecorder()
+	cxt.Put("http.ResponseWriter", res)
+	cxt.Put("reader", strings.NewReader("a,b,c\n1,2,3\n"))
+	if e := router.HandleRequest("reader", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	if res.Body.String() != "a,b,c\n1,2,3\n" || cxt.Get("sent", nil) != int64(12) {
+		t.Errorf("! Unexpected body: %q (%v)", res.Body.String(), cxt.Get("sent", nil))
+	}
+	if ct := res.Header().Get("Content-Type"); ct != "text/csv" || !res.Flushed {
+		t.Errorf("! Expected a flushed text/csv response, got %s", ct)
+	}
+
+	chunks := make(chan interface{}, 2)
+	chunks <- "one,"
+	chunks <- []byte("two")
+	close(chunks)
+	res = httptest.NewRecorder()
+	cxt.Put("http.ResponseWriter", res)
+	cxt.Put("chunks", chunks)
+	if e := router.HandleRequest("chunks", cxt, false); e != nil {
+		t.Errorf("! Unexpected error: %s", e)
+	}
+	if res.Body.String() != "one,two" {
+		t.Errorf("! Unexpected body: %q", res.Body.String())
+	}
+
+	reg.Route("zero", "Stream with no chunk size.").
+		Does(Stream, "sent").
+		Using("reader").From("cxt:reader").
+		Using("chunkSize").WithDefault(0)
+	if e := router.HandleRequest("zero", cxt, false); e == nil {
+		t.Error("! Expected a chunk size of 0 to fail.")
+	}
+}
@@ -2,6 +2,7 @@
 package web
 
 import (
+	"github.com/Masterminds/cookoo"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -49,6 +50,16 @@ func (r *RequestHeaderDatasource) Value(name string) interface{} {
 	return v
 }
 
+// Get returns the value for name, or the default value if it is not found.
+func (r *RequestHeaderDatasource) Get(name string, defaultVal interface{}) interface{} {
+	return cookoo.DatasourceGet(r, name, defaultVal)
+}
+
+// Has returns the value for name, and a flag indicating whether it was found.
+func (r *RequestHeaderDatasource) Has(name string) (interface{}, bool) {
+	return cookoo.DatasourceHas(r, name)
+}
+
 // Access to name/value pairs in POST/PUT form data from the body.
 // This will attempt to access form data supplied in the HTTP request's body.
 // If the MIME type is not correct or if there is no POST data, no data will
@@ -70,6 +81,16 @@ func (f *FormValuesDatasource) Value(name string) interface{} {
 	return f.req.PostFormValue(name)
 }
 
+// Get returns the value for name, or the default value if it is not found.
+func (f *FormValuesDatasource) Get(name string, defaultVal interface{}) interface{} {
+	return cookoo.DatasourceGet(f, name, defaultVal)
+}
+
+// Has returns the value for name, and a flag indicating whether it was found.
+func (f *FormValuesDatasource) Has(name string) (interface{}, bool) {
+	return cookoo.DatasourceHas(f, name)
+}
+
 func (d *QueryParameterDatasource) Init(vals url.Values) *QueryParameterDatasource {
 	d.Parameters = vals
 	return d
@@ -85,6 +106,16 @@ func (d *QueryParameterDatasource) Value(name string) interface{} {
 	return v
 }
 
+// Get returns the value for name, or the default value if it is not found.
+func (d *QueryParameterDatasource) Get(name string, defaultVal interface{}) interface{} {
+	return cookoo.DatasourceGet(d, name, defaultVal)
+}
+
+// Has returns the value for name, and a flag indicating whether it was found.
+func (d *QueryParameterDatasource) Has(name string) (interface{}, bool) {
+	return cookoo.DatasourceHas(d, name)
+}
+
 func (d *URLDatasource) Init(parsedUrl *url.URL) *URLDatasource {
 	d.URL = parsedUrl
 	return d
@@ -114,6 +145,16 @@ func (d *URLDatasource) Value(name string) interface{} {
 	return nil
 }
 
+// Get returns the value for name, or the default value if it is not found.
+func (d *URLDatasource) Get(name string, defaultVal interface{}) interface{} {
+	return cookoo.DatasourceGet(d, name, defaultVal)
+}
+
+// Has returns the value for name, and a flag indicating whether it was found.
+func (d *URLDatasource) Has(name string) (interface{}, bool) {
+	return cookoo.DatasourceHas(d, name)
+}
+
 type PathDatasource struct {
 	PathParts []string
 }
@@ -131,6 +172,16 @@ func (d *PathDatasource) Value(name string) interface{} {
 	return d.PathParts[index]
 }
 
+// Get returns the value for name, or the default value if it is not found.
+func (d *PathDatasource) Get(name string, defaultVal interface{}) interface{} {
+	return cookoo.DatasourceGet(d, name, defaultVal)
+}
+
+// Has returns the value for name, and a flag indicating whether it was found.
+func (d *PathDatasource) Has(name string) (interface{}, bool) {
+	return cookoo.DatasourceHas(d, name)
+}
+
 // This provides a datasource for session data.
 //
 // Sessions differ a little from the other web datasources in that they may
@@ -2,6 +2,7 @@ package web
 
 import (
 	"fmt"
+	"github.com/Masterminds/cookoo"
 	"net/http"
 	"net/url"
 	"strings"
@@ -69,6 +70,33 @@ func TestQueryParameterDatasource(t *testing.T) {
 
 }
 
+func TestDatasourcesAreGetters(t *testing.T) {
+	testUrl, err := url.ParseRequestURI("/foo/bar?a=b")
+	if err != nil {
+		t.Fatal("! Unexpected URL parse error.")
+	}
+	query := new(QueryParameterDatasource).Init(testUrl.Query())
+	path := new(PathDatasource).Init(testUrl.Path)
+	params := cookoo.NewParamsWithValues(map[string]interface{}{"c": "d"})
+
+	if v, _ := cookoo.GetFromFirst("a", "none", query, path, params); v != "b" {
+		t.Errorf("! Expected b from the query, got %v", v)
+	}
+	if v, src := cookoo.GetFromFirst("c", "none", query, params); v != "d" || src != params {
+		t.Errorf("! Expected d from the params, got %v", v)
+	}
+	if v := cookoo.GetString("1", "none", path); v != "bar" {
+		t.Errorf("! Expected bar from the path, got %v", v)
+	}
+	if _, ok := query.Has("nope"); ok {
+		t.Error("! Expected a missing query parameter to not be found.")
+	}
+
+	var _ cookoo.Getter = new(URLDatasource)
+	var _ cookoo.Getter = new(FormValuesDatasource)
+	var _ cookoo.Getter = new(RequestHeaderDatasource)
+}
+
 func TestFormValuesDatasource(t *testing.T) {
 	method := "POST"
 	urlString := "http://example.com/form/test"
@@ -0,0 +1,134 @@
+package web
+
+import (
+	"strings"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// Group declares web routes that share a path prefix and setup commands.
+//
+// Every route declared through a group has the group's prefix added to its
+// path, and begins with the commands of the group's included routes. This
+// saves repeating the same path and the same setup on every route:
+//
+// 	reg.Route("@api", "Common API setup").
+// 		Does(auth.Basic, "auth").
+// 			Using("realm").WithDefault("api").
+// 		Does(LoadAccount, "account")
+//
+// 	api := web.NewGroup(reg, "/api/v1").Includes("@api")
+// 	api.Route("GET /users", "List users").Does(ListUsers, "users")
+// 	api.Route("GET /users/*", "Show a user").Does(ShowUser, "user")
+//
+// In the example above, the routes are named "GET /api/v1/users" and
+// "GET /api/v1/users/*", and both run "auth" and "account" first.
+type Group struct {
+	reg      *cookoo.Registry
+	prefix   string
+	includes []string
+}
+
+// NewGroup creates a new group of routes under a path prefix.
+func NewGroup(reg *cookoo.Registry, prefix string) *Group {
+	return &Group{reg: reg, prefix: strings.TrimSuffix(prefix, "/")}
+}
+
+// Includes adds routes whose commands are run at the start of every route in
+// the group. See cookoo.Registry.Includes.
+//
+// Only routes declared after Includes is called are affected.
+func (g *Group) Includes(routes ...string) *Group {
+	g.includes = append(g.includes, routes...)
+	return g
+}
+
+// Group creates a group nested inside this one.
+//
+// The nested group's prefix is added to this group's prefix, and its routes
+// include this group's routes first. As with PrefixRoute, a "/" is added
+// between the two prefixes when the nested one does not start with one.
+func (g *Group) Group(prefix string) *Group {
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	sub := NewGroup(g.reg, g.prefix+prefix)
+	sub.includes = append(sub.includes, g.includes...)
+	return sub
+}
+
+// Route declares a route in the group.
+//
+// The name is a web route name, such as "GET /users", and the group's prefix
+// is added to its path. The registry is returned so that commands can be
+// added to the route as usual.
+func (g *Group) Route(name, description string) *cookoo.Registry {
+	g.reg.Route(PrefixRoute(g.prefix, name), description)
+	for _, inc := range g.includes {
+		g.reg.Includes(inc)
+	}
+	return g.reg
+}
+
+// Mount adds every route of src to reg, under a path prefix.
+//
+// This makes it possible to build part of an application in its own
+// registry, and then serve it under a prefix:
+//
+// 	api := cookoo.NewRegistry()
+// 	api.Route("GET /users", "List users").Does(ListUsers, "users")
+//
+// 	web.Mount(reg, "/api/v1", api)
+//
+// Routes are named as with PrefixRoute. Internal routes, whose names start
+// with "@", are not mounted, since they cannot be requested. Routes that
+// were included into other routes are still run by them.
+//
+// Since the URIPathResolver matches routes in order, mounted routes are
+// matched after the routes already in reg.
+func Mount(reg *cookoo.Registry, prefix string, src *cookoo.Registry) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	reg.Import(src, func(name string) string {
+		if strings.HasPrefix(name, "@") {
+			return ""
+		}
+		return PrefixRoute(prefix, name)
+	})
+}
+
+// Mount adds every route of src to the handler's registry, under a path
+// prefix. See Mount.
+func (h *CookooHandler) Mount(prefix string, src *cookoo.Registry) {
+	Mount(h.Registry, prefix, src)
+}
+
+// PrefixRoute adds a path prefix to a web route name.
+//
+// The name may start with a verb, as in "GET /users". A path of "/" becomes
+// the prefix itself, and the "**" route, which matches anything, becomes a
+// route that matches anything under the prefix.
+//
+// Examples:
+// - PrefixRoute("/api", "GET /users") is "GET /api/users"
+// - PrefixRoute("/api", "* /") is "* /api"
+// - PrefixRoute("/api", "**") is "* /api/**"
+func PrefixRoute(prefix, name string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if name == "**" {
+		return "* " + prefix + "/**"
+	}
+
+	verb, p := "", name
+	if i := strings.Index(name, " "); i >= 0 {
+		verb, p = name[:i+1], name[i+1:]
+	}
+	switch {
+	case p == "/" && prefix != "":
+		p = prefix
+	case strings.HasPrefix(p, "/"):
+		p = prefix + p
+	default:
+		p = prefix + "/" + p
+	}
+	return verb + p
+}
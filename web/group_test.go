@@ -0,0 +1,69 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Masterminds/cookoo"
+)
+
+func TestPrefixRoute(t *testing.T) {
+	tests := map[string]string{
+		"GET /users":    "GET /api/users",
+		"* /":           "* /api",
+		"/users/*":      "/api/users/*",
+		"POST users":    "POST /api/users",
+		"GET /files/**": "GET /api/files/**",
+		"**":            "* /api/**",
+	}
+	for name, expect := range tests {
+		if got := PrefixRoute("/api/", name); got != expect {
+			t.Errorf("! Expected %s to become %s, got %s", name, expect, got)
+		}
+	}
+}
+
+func TestGroupAndMount(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	reg.Route("@setup", "Shared setup").
+		Does(cookoo.AddToContext, "setup").
+		Using("account").WithDefault("acme")
+
+	api := NewGroup(reg, "/api").Includes("@setup")
+	api.Route("GET /users", "List users").
+		Does(Flush, "out").
+		Using("content").WithDefault("users")
+	api.Group("/v2").Route("GET /users", "List users, again").
+		Does(Flush, "out").
+		Using("content").From("cxt:account")
+	api.Group("v3").Group("beta").Route("GET /users", "List users, nested").
+		Does(Flush, "out").
+		Using("content").WithDefault("beta")
+
+	admin := cookoo.NewRegistry()
+	admin.Route("@internal", "Not mounted")
+	admin.Route("GET /", "Admin home").
+		Does(Flush, "out").
+		Using("content").WithDefault("admin")
+	handler := NewCookooHandler(reg, router, cxt)
+	handler.Mount("/admin", admin)
+
+	if router.HasRoute("@internal") || router.HasRoute("/admin@internal") {
+		t.Error("! Expected internal routes not to be mounted.")
+	}
+
+	tests := map[string]string{
+		"/api/users":         "users",
+		"/api/v2/users":      "acme",
+		"/api/v3/beta/users": "beta",
+		"/admin":             "admin",
+	}
+	for path, expect := range tests {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest("GET", path, nil))
+		if res.Code != http.StatusOK || res.Body.String() != expect {
+			t.Errorf("! Expected %s to return %q, got %d %q", path, expect, res.Code, res.Body.String())
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// ShutdownHook is run when a Server shuts down, after the last request has
+// finished. It should release resources, such as database connections. The
+// Go context carries the shutdown deadline.
+type ShutdownHook func(ctx context.Context, cxt cookoo.Context) error
+
+// Server is a Cookoo web server that can be shut down gracefully.
+//
+// Serve and ServeTLS are the easiest way to run a Cookoo app. Server is
+// for apps that need to control when the server stops:
+//
+// 	srv := web.NewServer(reg, router, cxt)
+// 	srv.OnShutdown(func(ctx context.Context, cxt cookoo.Context) error {
+// 		return db.Close()
+// 	})
+// 	srv.HandleSignals()
+// 	if err := srv.ListenAndServe(); err != nil {
+// 		log.Fatal(err)
+// 	}
+//
+// When Shutdown is called, the server stops accepting connections and waits
+// for requests that are in flight to finish their routes. Then the
+// `@shutdown` route, if there is one, and the shutdown hooks are run. The
+// ListenAndServe methods return nil once all of this is done.
+type Server struct {
+	// HTTP is the underlying server. Its Addr, timeouts, and TLS settings
+	// may be changed before the server is started.
+	HTTP *http.Server
+	// Handler is the Cookoo handler that serves requests.
+	Handler *CookooHandler
+	// ShutdownTimeout limits how long HandleSignals waits for requests to
+	// finish. The default is 30 seconds.
+	ShutdownTimeout time.Duration
+	// CloseDatasources closes every datasource in the base context that is
+	// an io.Closer, after the shutdown hooks have run.
+	CloseDatasources bool
+
+	mu       sync.Mutex
+	hooks    []ShutdownHook
+	once     sync.Once
+	stopping bool
+	done     chan struct{}
+	err      error
+}
+
+// NewServer creates a new server for a Cookoo app.
+//
+// The address is taken from `server.Address` in the context, as it is for
+// Serve. The default is ":8080".
+func NewServer(reg *cookoo.Registry, router *cookoo.Router, cxt cookoo.Context) *Server {
+	handler := NewCookooHandler(reg, router, cxt)
+	return &Server{
+		HTTP:            &http.Server{Addr: cxt.Get("server.Address", ":8080").(string), Handler: handler},
+		Handler:         handler,
+		ShutdownTimeout: 30 * time.Second,
+		done:            make(chan struct{}),
+	}
+}
+
+// OnShutdown adds a hook to run when the server shuts down. Hooks run in
+// the order they were added, after the `@shutdown` route.
+func (s *Server) OnShutdown(hook ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// ListenAndServe listens on the server's address and serves requests until
+// the server is shut down.
+func (s *Server) ListenAndServe() error {
+	return s.wait(s.HTTP.ListenAndServe())
+}
+
+// ListenAndServeTLS is the same as ListenAndServe, but with SSL support.
+func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	return s.wait(s.HTTP.ListenAndServeTLS(certFile, keyFile))
+}
+
+// Serve serves requests on a listener until the server is shut down.
+func (s *Server) Serve(l net.Listener) error {
+	return s.wait(s.HTTP.Serve(l))
+}
+
+// Shutdown gracefully shuts the server down.
+//
+// It stops accepting connections, and waits for requests in flight to
+// finish. If ctx expires first, the remaining connections are closed. Either
+// way, the `@shutdown` route and the shutdown hooks are then run, with the
+// same ctx.
+//
+// The first error is returned. Calling Shutdown again has no effect, and
+// returns the same error.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.once.Do(func() {
+		defer close(s.done)
+		cxt := s.Handler.BaseContext
+		s.mu.Lock()
+		s.stopping = true
+		s.mu.Unlock()
+
+		if err := s.HTTP.Shutdown(ctx); err != nil {
+			cxt.Logf("warn", "Requests did not finish before shutdown: %s", err)
+			s.HTTP.Close()
+			s.err = err
+		}
+
+		shutdown(s.Handler.Router, cxt)
+
+		s.mu.Lock()
+		hooks := s.hooks
+		s.mu.Unlock()
+		for _, hook := range hooks {
+			if err := hook(ctx, cxt); err != nil {
+				cxt.Logf("error", "Shutdown hook failed: %s", err)
+				if s.err == nil {
+					s.err = err
+				}
+			}
+		}
+
+		if s.CloseDatasources {
+			for name, ds := range cxt.Datasources() {
+				if c, ok := ds.(io.Closer); ok {
+					if err := c.Close(); err != nil {
+						cxt.Logf("error", "Could not close datasource %s: %s", name, err)
+					}
+				}
+			}
+		}
+	})
+	return s.err
+}
+
+// HandleSignals shuts the server down when the process receives one of the
+// given signals. If none are given, SIGINT and SIGTERM are handled.
+//
+// The server waits up to ShutdownTimeout for requests to finish. After the
+// first signal, the signals are no longer trapped, so sending another one
+// stops the process immediately.
+func (s *Server) HandleSignals(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		select {
+		case sig := <-ch:
+			signal.Stop(ch)
+			s.Handler.BaseContext.Logf("info", "Received signal %s. Shutting down.", sig)
+			ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+			defer cancel()
+			s.Shutdown(ctx)
+		case <-s.done:
+			signal.Stop(ch)
+		}
+	}()
+}
+
+// wait waits for a shutdown to finish after the server stops serving.
+func (s *Server) wait(err error) error {
+	s.mu.Lock()
+	stopping := s.stopping
+	s.mu.Unlock()
+	if err != http.ErrServerClosed || !stopping {
+		return err
+	}
+	<-s.done
+	return nil
+}
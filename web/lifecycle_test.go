@@ -0,0 +1,116 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+type closingDatasource struct{ closed bool }
+
+func (d *closingDatasource) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestServerShutdown(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	started, release := make(chan bool), make(chan bool)
+	reg.Route("GET /slow", "A slow request").
+		DoesFunc("wait", func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			started <- true
+			<-release
+			return nil, nil
+		}).
+		Does(Flush, "out").Using("content").WithDefault("done")
+	reg.Route("@shutdown", "Clean up").
+		Does(cookoo.AddToContext, "cleanup").Using("shutdown.Ran").WithDefault(true)
+
+	ds := &closingDatasource{}
+	cxt.AddDatasource("closer", ds)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen: %s", err)
+	}
+	srv := NewServer(reg, router, cxt)
+	srv.CloseDatasources = true
+	hooked := false
+	srv.OnShutdown(func(ctx context.Context, c cookoo.Context) error {
+		hooked = true
+		return nil
+	})
+
+	served := make(chan error)
+	go func() { served <- srv.Serve(l) }()
+
+	body := make(chan string)
+	go func() {
+		res, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		body <- string(b)
+	}()
+	<-started
+
+	shut := make(chan error)
+	go func() { shut <- srv.Shutdown(context.Background()) }()
+	select {
+	case <-shut:
+		t.Fatal("! Expected Shutdown to wait for the request.")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if b := <-body; b != "done" {
+		t.Errorf("! Expected the request to finish, got %q", b)
+	}
+	if err := <-shut; err != nil {
+		t.Errorf("! Unexpected shutdown error: %s", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("! Expected Serve to return nil, got %s", err)
+	}
+	if v := cxt.Get("shutdown.Ran", nil); v != true || !hooked || !ds.closed {
+		t.Errorf("! Expected the @shutdown route, hooks, and datasources to run: %v %v %v", v, hooked, ds.closed)
+	}
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	started, release := make(chan bool), make(chan bool)
+	defer close(release)
+	reg.Route("GET /stuck", "A stuck request").
+		DoesFunc("wait", func(c cookoo.Context, p *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			started <- true
+			<-release
+			return nil, nil
+		})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen: %s", err)
+	}
+	srv := NewServer(reg, router, cxt)
+	go srv.Serve(l)
+	go http.Get("http://" + l.Addr().String() + "/stuck")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("! Expected the deadline to be exceeded, got %v", err)
+	}
+	if err := srv.Shutdown(context.Background()); err != context.DeadlineExceeded {
+		t.Errorf("! Expected the same error again, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// routePattern is a compiled route pattern with named path parameters.
+type routePattern struct {
+	verb     string
+	segments []patternSegment
+	// subtree is true if the pattern ends with "/**".
+	subtree bool
+}
+
+// patternSegment matches one segment of a path.
+type patternSegment struct {
+	// name is the parameter name, or empty for a literal or glob segment.
+	name string
+	// glob is matched with path.Match when name is empty.
+	glob string
+	// re, if not nil, constrains the value of a named parameter.
+	re *regexp.Regexp
+}
+
+// hasPathParams returns true if a route pattern has named parameters.
+func hasPathParams(pattern string) bool {
+	return strings.Contains(pattern, "/:")
+}
+
+// compilePattern parses a route pattern with named parameters, such as
+// "GET /users/:id([0-9]+)/posts/:post".
+func compilePattern(pattern string) (*routePattern, error) {
+	rp := &routePattern{}
+	p := pattern
+	if i := strings.Index(pattern, " "); i >= 0 {
+		rp.verb, p = pattern[:i], pattern[i+1:]
+	}
+	if strings.HasSuffix(p, "/**") {
+		rp.subtree = true
+		p = strings.TrimSuffix(p, "/**")
+	}
+
+	for _, seg := range strings.Split(p, "/") {
+		if !strings.HasPrefix(seg, ":") {
+			rp.segments = append(rp.segments, patternSegment{glob: seg})
+			continue
+		}
+
+		name, constraint := seg[1:], ""
+		if i := strings.Index(name, "("); i >= 0 {
+			if !strings.HasSuffix(name, ")") {
+				return nil, fmt.Errorf("unterminated constraint in pattern %s", pattern)
+			}
+			name, constraint = name[:i], name[i+1:len(name)-1]
+		}
+		if name == "" {
+			return nil, fmt.Errorf("unnamed parameter in pattern %s", pattern)
+		}
+		ps := patternSegment{name: name}
+		if constraint != "" {
+			re, err := regexp.Compile("^(?:" + constraint + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("bad constraint for %s in pattern %s: %s", name, pattern, err)
+			}
+			ps.re = re
+		}
+		rp.segments = append(rp.segments, ps)
+	}
+	return rp, nil
+}
+
+// match matches a request path, such as "GET /users/123", returning the
+// values of the named parameters.
+func (rp *routePattern) match(pathName string) (map[string]string, bool) {
+	p := pathName
+	if rp.verb != "" {
+		i := strings.Index(pathName, " ")
+		if i < 0 {
+			return nil, false
+		}
+		if ok, _ := path.Match(rp.verb, pathName[:i]); !ok {
+			return nil, false
+		}
+		p = pathName[i+1:]
+	}
+
+	parts := strings.Split(p, "/")
+	if len(parts) < len(rp.segments) || (!rp.subtree && len(parts) > len(rp.segments)) {
+		return nil, false
+	}
+
+	vals := map[string]string{}
+	for i, seg := range rp.segments {
+		part := parts[i]
+		if seg.name == "" {
+			if ok, _ := path.Match(seg.glob, part); !ok {
+				return nil, false
+			}
+			continue
+		}
+		if part == "" || (seg.re != nil && !seg.re.MatchString(part)) {
+			return nil, false
+		}
+		vals[seg.name] = part
+	}
+	return vals, true
+}
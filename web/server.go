@@ -1,12 +1,10 @@
 package web
 
 import (
+	"context"
 	"github.com/Masterminds/cookoo"
 	"net/http"
 	"runtime"
-
-	"os"
-	"os/signal"
 )
 
 // Serve creates a new Cookoo web server.
@@ -23,7 +21,12 @@ import (
 //   * http.Request: A pointer to the http.Request object
 //   * http.ResponseWriter: The response writer.
 //   * server.Address: The server's address and port (NOT ALWAYS PRESENT)
+//   * path.NAME: The value of each named path parameter, such as `path.id`
+//     for the route "GET /users/:id". See URIPathResolver.
 // - The handler includes logic to redirect "not found" errors to a path named "@404" if present.
+// - On SIGINT or SIGTERM, the server shuts down gracefully: it waits for
+//   requests in flight to finish, runs the "@shutdown" route if present, and
+//   then Serve returns. Use a Server for more control.
 //
 // Context Params:
 //
@@ -62,28 +65,16 @@ import (
 // So by declaring the context synchronized here, you
 // are not therefore synchronizing across handlers.
 func Serve(reg *cookoo.Registry, router *cookoo.Router, cxt cookoo.Context) {
-	addr := cxt.Get("server.Address", ":8080").(string)
-
-	handler := NewCookooHandler(reg, router, cxt)
-
 	// MPB: I dont think there's any real point in having a multiplexer in
 	// this particular case. The Cookoo handler is mux enough.
 	//
 	// Note that we can always use Cookoo with the built-in multiplexer. It
 	// just doesn't make sense if Cookoo's the only handler on the app.
 	//http.Handle("/", handler)
+	srv := NewServer(reg, router, cxt)
 
-	server := &http.Server{Addr:addr}
-
-	// Instead of mux, set a single default handler.
-	// What we might be losing:
-	// - Handling of non-conforming paths.
-	server.Handler = handler
-
-	go handleSignals(router, cxt, server)
-	err := server.ListenAndServe()
-	//err := http.ListenAndServe(addr, nil)
-	if err != nil {
+	srv.HandleSignals()
+	if err := srv.ListenAndServe(); err != nil {
 		cxt.Logf("error", "Caught error while serving: %s", err)
 		if router.HasRoute("@crash") {
 			router.HandleRequest("@crash", cxt, false)
@@ -99,14 +90,11 @@ func Serve(reg *cookoo.Registry, router *cookoo.Router, cxt cookoo.Context) {
 // Neither certFile nor keyFile are stored in the context. These values are
 // considered to be security sensitive.
 func ServeTLS(reg *cookoo.Registry, router *cookoo.Router, cxt cookoo.Context, certFile, keyFile string) {
-	addr := cxt.Get("server.Address", ":4433").(string)
+	srv := NewServer(reg, router, cxt)
+	srv.HTTP.Addr = cxt.Get("server.Address", ":4433").(string)
 
-	server := &http.Server{Addr:addr}
-	server.Handler = NewCookooHandler(reg, router, cxt)
-
-	go handleSignals(router, cxt, server)
-	err := server.ListenAndServeTLS(certFile, keyFile)
-	if err != nil {
+	srv.HandleSignals()
+	if err := srv.ListenAndServeTLS(certFile, keyFile); err != nil {
 		cxt.Logf("error", "Caught error while serving: %s", err)
 		if router.HasRoute("@crash") {
 			router.HandleRequest("@crash", cxt, false)
@@ -114,21 +102,6 @@ func ServeTLS(reg *cookoo.Registry, router *cookoo.Router, cxt cookoo.Context, c
 	}
 }
 
-// handleSignals traps kill and interrupt signals and runs shutdown().
-func handleSignals(router *cookoo.Router, cxt cookoo.Context, server *http.Server) {
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Kill, os.Interrupt)
-
-	s := <-sig
-	cxt.Logf("info", "Received signal %s. Shutting down.", s)
-	// Not particularly useful on its own.
-	// server.SetKeepAlivesEnabled(false)
-	// TODO: Implement graceful shutdowns.
-	shutdown(router, cxt)
-	os.Exit(0)
-
-}
-
 // shutdown runs an @shutdown route if it's found in the router.
 func shutdown(router *cookoo.Router, cxt cookoo.Context) {
 	if router.HasRoute("@shutdown") {
@@ -166,6 +139,11 @@ type CookooHandler struct {
 //   * http.Request: A pointer to the http.Request object
 //   * http.ResponseWriter: The response writer.
 //   * server.Address: The server's address and port (NOT ALWAYS PRESENT)
+//   * path.NAME: The value of each named path parameter, such as `path.id`
+//     for the route "GET /users/:id". See URIPathResolver.
+//
+// As with http.ServeMux, this panics if a route has a path pattern that
+// cannot be compiled.
 func NewCookooHandler(reg *cookoo.Registry, router *cookoo.Router, cxt cookoo.Context) *CookooHandler {
 	handler := new(CookooHandler)
 	handler.Registry = reg
@@ -175,6 +153,9 @@ func NewCookooHandler(reg *cookoo.Registry, router *cookoo.Router, cxt cookoo.Co
 	// Use the URI oriented request resolver in this package.
 	resolver := new(URIPathResolver)
 	resolver.Init(reg)
+	if err := resolver.Compile(); err != nil {
+		panic("web: " + err.Error())
+	}
 	router.SetRequestResolver(resolver)
 
 	return handler
@@ -230,6 +211,9 @@ func (h *CookooHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	cxt.Put("http.Request", req)
 	cxt.Put("http.ResponseWriter", res)
 
+	// Stop the route if the client goes away.
+	cxt.SetGoContext(req.Context())
+
 	// Next, we add the datasources for URL and Query params.
 	h.addDatasources(cxt, req)
 
@@ -240,6 +224,10 @@ func (h *CookooHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 
 	// If a route matches, run it.
 	err := h.Router.HandleRequest(path, cxt, true)
+	if err == context.Canceled {
+		cxt.Logf("info", "Request for %s was cancelled.", path)
+		return
+	}
 	if err != nil {
 		switch err.(type) {
 
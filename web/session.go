@@ -0,0 +1,492 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// SessionStore stores session data.
+//
+// Any KeyValueDatasource that can also set and delete keys can be used, such
+// as the Redis datasource. Session data is stored as a JSON string, one key
+// per session.
+type SessionStore interface {
+	cookoo.KeyValueDatasource
+	Set(key string, value interface{}, ttl time.Duration) error
+	Delete(keys ...string) error
+}
+
+// SessionManager manages sessions for web routes.
+//
+// The session ID is kept in a cookie, and the session data in a SessionStore.
+// A session is started with the StartSession command, and its changes are
+// saved by the manager's Commit hook once the route has finished:
+//
+// 	sessions := web.NewSessionManager(web.NewMemorySessionStore())
+// 	sessions.Secure = true
+// 	router.After("", sessions.Commit)
+//
+// 	reg.Route("@session", "Start a session").
+// 		Does(web.StartSession, "session").
+// 			Using("manager").WithDefault(sessions)
+//
+// 	reg.Route("GET /cart", "Show the cart").
+// 		Includes("@session").
+// 		Does(ShowCart, "cart").
+// 			Using("items").From("session:cart")
+//
+// Fields should be set before the manager is used.
+type SessionManager struct {
+	// Store holds the session data.
+	Store SessionStore
+	// Prefix is added to the session ID to make the store key. Default:
+	// "session:"
+	Prefix string
+	// CookieName is the name of the session cookie. Default: "session"
+	CookieName string
+	// Path and Domain set the scope of the cookie. The default Path is "/".
+	Path   string
+	Domain string
+	// Secure sends the cookie over HTTPS only.
+	Secure bool
+	// HTTPOnly hides the cookie from JavaScript. Default: true
+	HTTPOnly bool
+	// SameSite restricts cross-site requests. Default: http.SameSiteLaxMode
+	SameSite http.SameSite
+	// TTL is how long a session lasts after its last request. Both the
+	// cookie and the stored data expire after this long. If it is zero, the
+	// cookie lasts until the browser is closed, and the data does not expire.
+	// Default: 24 hours
+	TTL time.Duration
+}
+
+// NewSessionManager creates a new session manager with the default settings.
+func NewSessionManager(store SessionStore) *SessionManager {
+	return &SessionManager{
+		Store:      store,
+		Prefix:     "session:",
+		CookieName: "session",
+		Path:       "/",
+		HTTPOnly:   true,
+		SameSite:   http.SameSiteLaxMode,
+		TTL:        24 * time.Hour,
+	}
+}
+
+// Session is the session of one client.
+//
+// A Session is a KeyValueDatasource, so session values can be read with
+// From("session:NAME"). Values are stored as JSON, which means that when a
+// session is loaded, numbers come back as float64, and structs as maps. Use
+// cookoo.GetAs to convert them.
+//
+// A Session is safe for concurrent use.
+type Session struct {
+	// ID is the session ID.
+	ID string
+	// IsNew is true if the session was created by this request.
+	IsNew bool
+
+	mu        sync.Mutex
+	manager   *SessionManager
+	out       http.ResponseWriter
+	values    map[string]interface{}
+	flashes   []string
+	changed   bool
+	destroyed bool
+	// stale holds IDs replaced by Regenerate, to be deleted from the store.
+	stale []string
+}
+
+// sessionData is the stored form of a session.
+type sessionData struct {
+	Values  map[string]interface{} `json:"values"`
+	Flashes []string               `json:"flashes,omitempty"`
+}
+
+// Value returns a session value, or nil if it is not set.
+func (s *Session) Value(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Get returns a session value, or the default value if it is not set.
+func (s *Session) Get(key string, defaultVal interface{}) interface{} {
+	return cookoo.DatasourceGet(s, key, defaultVal)
+}
+
+// Has returns a session value, and whether it is set.
+func (s *Session) Has(key string) (interface{}, bool) {
+	return cookoo.DatasourceHas(s, key)
+}
+
+// Set sets a session value. The value must be encodable as JSON.
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.changed = true
+}
+
+// Delete removes a session value.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.changed = true
+	}
+}
+
+// Keys returns the names of the session values, in sorted order.
+func (s *Session) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AddFlash adds a flash message.
+//
+// Flash messages are kept until they are read with Flashes, usually on the
+// next request. This is the usual way to show a message after a redirect.
+func (s *Session) AddFlash(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flashes = append(s.flashes, msg)
+	s.changed = true
+}
+
+// Flashes returns the flash messages, and removes them from the session.
+func (s *Session) Flashes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.flashes
+	if len(f) > 0 {
+		s.flashes = nil
+		s.changed = true
+	}
+	return f
+}
+
+// Regenerate gives the session a new ID, keeping its values.
+//
+// This should be done when a user logs in, so that an ID that was known
+// before the login cannot be used to take over the session. The new cookie
+// is sent right away, so this must be called before the response is written.
+func (s *Session) Regenerate() error {
+	id, err := newSessionID()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.IsNew {
+		s.stale = append(s.stale, s.ID)
+	}
+	s.ID = id
+	s.changed = true
+	if s.out != nil {
+		s.manager.setCookie(s.out, s.manager.cookie(id))
+	}
+	return nil
+}
+
+// Destroy ends the session.
+//
+// Its values are removed, its data is deleted from the store when the session
+// is committed, and the cookie is expired right away. So this must be called
+// before the response is written.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = map[string]interface{}{}
+	s.flashes = nil
+	s.destroyed = true
+	if s.out != nil {
+		c := s.manager.cookie("")
+		c.MaxAge = -1
+		s.manager.setCookie(s.out, c)
+	}
+}
+
+// Save writes the session to the store.
+//
+// The Commit hook does this, so Save is only needed to save a session before
+// the route has finished. A session that was only just created and has no
+// data is not saved.
+func (s *Session) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.manager
+
+	keys := make([]string, 0, len(s.stale)+1)
+	for _, id := range s.stale {
+		keys = append(keys, m.Prefix+id)
+	}
+	if s.destroyed {
+		keys = append(keys, m.Prefix+s.ID)
+	}
+	if len(keys) > 0 {
+		if err := m.Store.Delete(keys...); err != nil {
+			return err
+		}
+		s.stale = nil
+	}
+	if s.destroyed || (s.IsNew && !s.changed) {
+		return nil
+	}
+
+	data, err := json.Marshal(sessionData{Values: s.values, Flashes: s.flashes})
+	if err != nil {
+		return err
+	}
+	if err := m.Store.Set(m.Prefix+s.ID, string(data), m.TTL); err != nil {
+		return err
+	}
+	s.changed = false
+	return nil
+}
+
+// GetSession returns the session started by StartSession, or nil if no
+// session has been started.
+func GetSession(cxt cookoo.Context) *Session {
+	if s, ok := cxt.Get("session.Session", nil).(*Session); ok {
+		return s
+	}
+	return nil
+}
+
+// StartSession starts a session for the request.
+//
+// The session is loaded from the store if the request has a session cookie
+// for a session that still exists. Otherwise, a new session with a random ID
+// is created. Either way, the cookie is sent with the response, so that the
+// session lasts for the manager's TTL after each request.
+//
+// The session is put into the context as `session.Session`. Its values are
+// available through the `session` datasource, e.g. `From("session:user")`.
+// Use GetSession to get the session in a command.
+//
+// Starting a session again in the same request returns the same session.
+//
+// Params:
+// 	- manager (*SessionManager): The session manager. This is required.
+// 	- writer: A ResponseWriter. This will use the HTTP response if no writer
+// 	  is specified.
+// 	- request: A request. This will use the HTTP request if no request
+// 	  is specified.
+//
+// Returns:
+// 	- The *Session.
+func StartSession(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	if s := GetSession(cxt); s != nil {
+		return s, nil
+	}
+	writer, ok := params.Has("writer")
+	if !ok {
+		writer, ok = cxt.Has("http.ResponseWriter")
+		if !ok {
+			return nil, &cookoo.FatalError{Message: "No http.ResponseWriter found."}
+		}
+	}
+	req, ok := params.Has("request")
+	if !ok {
+		req, ok = cxt.Has("http.Request")
+		if !ok {
+			return nil, &cookoo.FatalError{Message: "No http.Request found."}
+		}
+	}
+	m, ok := params.Get("manager", nil).(*SessionManager)
+	if !ok {
+		return nil, &cookoo.FatalError{Message: "Expected a 'manager'"}
+	}
+
+	s, err := m.load(req.(*http.Request))
+	if err != nil {
+		return nil, &cookoo.FatalError{Message: fmt.Sprintf("Could not load session: %s", err)}
+	}
+	s.out = writer.(http.ResponseWriter)
+	m.setCookie(s.out, m.cookie(s.ID))
+
+	cxt.Put("session.Session", s)
+	cxt.AddDatasource("session", s)
+	return s, nil
+}
+
+// Commit saves the session started by StartSession, if there is one.
+//
+// It is a cookoo.Hook, to be run after routes:
+//
+// 	router.After("", sessions.Commit)
+//
+// Since After hooks only run when a route succeeds, changes made by a route
+// that fails are not saved.
+func (m *SessionManager) Commit(cxt cookoo.Context, route string) cookoo.Interrupt {
+	s := GetSession(cxt)
+	if s == nil || s.manager != m {
+		return nil
+	}
+	if err := s.Save(); err != nil {
+		return &cookoo.FatalError{Message: fmt.Sprintf("Could not save session: %s", err)}
+	}
+	return nil
+}
+
+// load loads the request's session, or creates a new one.
+func (m *SessionManager) load(req *http.Request) (*Session, error) {
+	if c, err := req.Cookie(m.CookieName); err == nil && c.Value != "" {
+		var raw []byte
+		switch v := m.Store.Value(m.Prefix + c.Value).(type) {
+		case string:
+			raw = []byte(v)
+		case []byte:
+			raw = v
+		}
+		if raw != nil {
+			data := sessionData{}
+			if err := json.Unmarshal(raw, &data); err != nil {
+				return nil, err
+			}
+			if data.Values == nil {
+				data.Values = map[string]interface{}{}
+			}
+			return &Session{ID: c.Value, manager: m, values: data.Values, flashes: data.Flashes}, nil
+		}
+	}
+
+	// An unknown ID is never reused, so that a client cannot choose its own.
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	return &Session{ID: id, IsNew: true, manager: m, values: map[string]interface{}{}}, nil
+}
+
+// cookie builds the session cookie for an ID.
+func (m *SessionManager) cookie(id string) *http.Cookie {
+	c := &http.Cookie{
+		Name:     m.CookieName,
+		Value:    id,
+		Path:     m.Path,
+		Domain:   m.Domain,
+		Secure:   m.Secure,
+		HttpOnly: m.HTTPOnly,
+		SameSite: m.SameSite,
+	}
+	if m.TTL > 0 {
+		c.MaxAge = int(m.TTL / time.Second)
+		c.Expires = time.Now().Add(m.TTL)
+	}
+	return c
+}
+
+// setCookie sends a session cookie, replacing one that was already set by
+// this response.
+func (m *SessionManager) setCookie(w http.ResponseWriter, c *http.Cookie) {
+	h := w.Header()
+	kept := h["Set-Cookie"][:0]
+	for _, v := range h["Set-Cookie"] {
+		if !strings.HasPrefix(v, m.CookieName+"=") {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == 0 {
+		h.Del("Set-Cookie")
+	} else {
+		h["Set-Cookie"] = kept
+	}
+	http.SetCookie(w, c)
+}
+
+// newSessionID generates a random session ID.
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// MemorySessionStore is a SessionStore that keeps sessions in memory.
+//
+// It is useful for development and tests, and for apps that run on a single
+// server. Sessions are lost when the process exits.
+type MemorySessionStore struct {
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+// NewMemorySessionStore creates a new, empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{values: map[string]string{}, expires: map[string]time.Time{}}
+}
+
+// Value returns the value of a key, or nil if it is not set or has expired.
+func (m *MemorySessionStore) Value(key string) interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	if !ok {
+		return nil
+	}
+	if t, ok := m.expires[key]; ok && !time.Now().Before(t) {
+		delete(m.values, key)
+		delete(m.expires, key)
+		return nil
+	}
+	return v
+}
+
+// Set sets the value of a key. If ttl is greater than zero, the key expires
+// after that long.
+//
+// As with the Redis datasource, strings and byte slices are stored as they
+// are, and other values are formatted with fmt.Sprint.
+func (m *MemorySessionStore) Set(key string, value interface{}, ttl time.Duration) error {
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		str = fmt.Sprint(v)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = str
+	if ttl > 0 {
+		m.expires[key] = time.Now().Add(ttl)
+	} else {
+		delete(m.expires, key)
+	}
+	return nil
+}
+
+// Delete removes keys.
+func (m *MemorySessionStore) Delete(keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range keys {
+		delete(m.values, k)
+		delete(m.expires, k)
+	}
+	return nil
+}
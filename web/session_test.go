@@ -0,0 +1,151 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+func TestSessions(t *testing.T) {
+	store := NewMemorySessionStore()
+	sessions := NewSessionManager(store)
+	sessions.Secure = true
+	sessions.SameSite = http.SameSiteStrictMode
+
+	reg, router, cxt := cookoo.Cookoo()
+	router.After("", sessions.Commit)
+	reg.Route("@session", "Start a session").
+		Does(StartSession, "session").
+		Using("manager").WithDefault(sessions)
+	reg.Route("GET /login", "Log in").
+		Includes("@session").
+		Does(cookoo.Command(func(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			s := GetSession(cxt)
+			s.Set("user", "matt")
+			s.AddFlash("Welcome")
+			return nil, nil
+		}), "login")
+	reg.Route("GET /show", "Show the session").
+		Includes("@session").
+		Does(cookoo.Command(func(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			w := cxt.Get("http.ResponseWriter", nil).(http.ResponseWriter)
+			w.Write([]byte(params.Get("user", "nobody").(string) + ":" + strings.Join(GetSession(cxt).Flashes(), ",")))
+			return nil, nil
+		}), "show").
+		Using("user").From("session:user")
+	reg.Route("GET /fail", "Change the session, then fail").
+		Includes("@session").
+		Does(cookoo.Command(func(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			GetSession(cxt).Set("user", "mallory")
+			return nil, &cookoo.FatalError{Message: "Failed"}
+		}), "fail")
+	reg.Route("GET /logout", "Log out").
+		Includes("@session").
+		Does(cookoo.Command(func(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+			GetSession(cxt).Destroy()
+			return nil, nil
+		}), "logout")
+	handler := NewCookooHandler(reg, router, cxt)
+
+	get := func(path string, c *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if c != nil {
+			req.AddCookie(c)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+	cookieOf := func(res *httptest.ResponseRecorder) *http.Cookie {
+		for _, c := range res.Result().Cookies() {
+			if c.Name == "session" {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// A new session with no data is not stored.
+	res := get("/show", nil)
+	if res.Body.String() != "nobody:" || len(store.values) != 0 {
+		t.Errorf("! Unexpected new session: %q, %d stored", res.Body.String(), len(store.values))
+	}
+
+	res = get("/login", nil)
+	c := cookieOf(res)
+	if c == nil {
+		t.Fatal("! Expected a session cookie.")
+	}
+	if !c.Secure || !c.HttpOnly || c.SameSite != http.SameSiteStrictMode || c.MaxAge != 86400 || c.Path != "/" {
+		t.Errorf("! Unexpected cookie attributes: %v", c)
+	}
+
+	if res = get("/show", c); res.Body.String() != "matt:Welcome" {
+		t.Errorf("! Expected the user and the flash, got %q", res.Body.String())
+	}
+	if res = get("/show", c); res.Body.String() != "matt:" {
+		t.Errorf("! Expected the flash to be gone, got %q", res.Body.String())
+	}
+
+	// Changes from a failed route are not saved.
+	get("/fail", c)
+	if res = get("/show", c); res.Body.String() != "matt:" {
+		t.Errorf("! Expected the failed change to be discarded, got %q", res.Body.String())
+	}
+
+	res = get("/logout", c)
+	if expired := cookieOf(res); expired == nil || expired.MaxAge >= 0 {
+		t.Errorf("! Expected the cookie to be expired, got %v", expired)
+	}
+	if res = get("/show", c); res.Body.String() != "nobody:" {
+		t.Errorf("! Expected the session to be gone, got %q", res.Body.String())
+	}
+
+	// An unknown ID is replaced.
+	res = get("/login", &http.Cookie{Name: "session", Value: "chosen"})
+	if c = cookieOf(res); c == nil || c.Value == "chosen" {
+		t.Errorf("! Expected a new session ID, got %v", c)
+	}
+}
+
+func TestSessionRegenerate(t *testing.T) {
+	store := NewMemorySessionStore()
+	m := NewSessionManager(store)
+	s := &Session{ID: "old", manager: m, values: map[string]interface{}{"n": 1}}
+	s.Save()
+	if store.Value("session:old") == nil {
+		t.Fatal("! Expected the session to be saved.")
+	}
+
+	if err := s.Regenerate(); err != nil {
+		t.Fatal(err)
+	}
+	s.Save()
+	if store.Value("session:old") != nil {
+		t.Error("! Expected the old session to be deleted.")
+	}
+	if v, ok := store.Value("session:" + s.ID).(string); !ok || !strings.Contains(v, `"n":1`) {
+		t.Errorf("! Expected the session under its new ID, got %v", v)
+	}
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	store := NewMemorySessionStore()
+	store.Set("a", 1, 0)
+	store.Set("b", "x", time.Millisecond)
+	if store.Value("a") != "1" {
+		t.Errorf("! Expected '1', got %v", store.Value("a"))
+	}
+	time.Sleep(5 * time.Millisecond)
+	if store.Value("b") != nil {
+		t.Error("! Expected b to expire.")
+	}
+	store.Delete("a")
+	if store.Value("a") != nil {
+		t.Error("! Expected a to be deleted.")
+	}
+}
@@ -0,0 +1,322 @@
+package web
+
+import (
+	"compress/gzip"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// ServeStatic serves files from a directory, with the caching and
+// compression that browsers expect.
+//
+// This is a more complete ServeFiles:
+// 	- Responses have an ETag and a Last-Modified header, and conditional
+// 	  requests (If-None-Match, If-Modified-Since) get 304 Not Modified.
+// 	- Range requests are supported, for resuming downloads and seeking in
+// 	  media.
+// 	- If the client accepts it, a precompressed `.br` or `.gz` file next to
+// 	  the requested file is served in its place. Otherwise, files over 1KB of
+// 	  compressible types (text, JSON, JavaScript, XML, and SVG) are gzipped
+// 	  on the fly.
+// 	- Directories are served by their index file, and may be listed.
+// 	- Paths cannot escape the directory, and hidden files (names starting
+// 	  with a dot) are not served by default.
+//
+// Example:
+//
+// 	registry.Route("GET /assets/**", "Serve assets").
+// 		Does(web.ServeStatic, "file").
+// 			Using("directory").WithDefault("static").
+// 			Using("removePrefix").WithDefault("/assets").
+// 			Using("maxAge").WithDefault(24 * time.Hour)
+//
+// If the file is not found, this reroutes to "@404", as ServeFiles does.
+//
+// Params:
+// 	- directory (string): The directory to serve files from. This is required.
+// 	- removePrefix (string): A prefix to remove from the URL path before looking
+// 	  for the file.
+// 	- index (string): The file to serve for a directory. Default: "index.html".
+// 	  Set to "" to disable.
+// 	- listDirectories (bool): List directories that have no index. Default: false
+// 	- compress (bool): Gzip compressible files on the fly. Default: true
+// 	- precompressed (bool): Look for `.br` and `.gz` files. Default: true
+// 	- hidden (bool): Serve hidden files. Default: false
+// 	- maxAge (time.Duration): If set, a Cache-Control header with this
+// 	  max-age is sent.
+// 	- writer: A ResponseWriter. This will use the HTTP response if no writer
+// 	  is specified.
+// 	- request: A request. This will use the HTTP request if no request
+// 	  is specified.
+//
+// Returns:
+// 	- The path of the file that was served, as a string.
+func ServeStatic(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	writer, ok := params.Has("writer")
+	if !ok {
+		writer, ok = cxt.Has("http.ResponseWriter")
+		if !ok {
+			return nil, &cookoo.FatalError{Message: "No http.ResponseWriter found."}
+		}
+	}
+	req, ok := params.Has("request")
+	if !ok {
+		req, ok = cxt.Has("http.Request")
+		if !ok {
+			return nil, &cookoo.FatalError{Message: "No http.Request found."}
+		}
+	}
+	out := writer.(http.ResponseWriter)
+	in := req.(*http.Request)
+
+	directory, ok := cookoo.HasString("directory", params)
+	if !ok {
+		return nil, &cookoo.FatalError{Message: "Expected a 'directory'"}
+	}
+
+	urlPath := strings.TrimPrefix(in.URL.Path, cookoo.GetString("removePrefix", "", params))
+	if strings.Contains(urlPath, "\x00") {
+		return nil, cookoo.NewReroute("@404")
+	}
+	// As with http.Dir, a path with a backslash could escape directory on
+	// Windows, since path.Clean does not treat it as a separator.
+	if filepath.Separator != '/' && strings.ContainsRune(urlPath, filepath.Separator) {
+		return nil, cookoo.NewReroute("@404")
+	}
+	// Cleaning a rooted path removes any "..", so it stays inside directory.
+	clean := path.Clean("/" + urlPath)
+	if !cookoo.GetBool("hidden", false, params) && hasHiddenSegment(clean) {
+		return nil, cookoo.NewReroute("@404")
+	}
+	name := filepath.Join(directory, filepath.FromSlash(clean))
+	if !within(directory, name) {
+		return nil, cookoo.NewReroute("@404")
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, cookoo.NewReroute("@404")
+	}
+
+	if info.IsDir() {
+		index := cookoo.GetString("index", "index.html", params)
+		listing := cookoo.GetBool("listDirectories", false, params)
+
+		var indexInfo os.FileInfo
+		if index != "" {
+			if fi, err := os.Stat(filepath.Join(name, index)); err == nil && !fi.IsDir() {
+				indexInfo = fi
+			}
+		}
+		if indexInfo == nil && !listing {
+			return nil, cookoo.NewReroute("@404")
+		}
+
+		// Relative links in the page only work with a trailing slash.
+		if !strings.HasSuffix(in.URL.Path, "/") {
+			target := path.Base(in.URL.Path) + "/"
+			if in.URL.RawQuery != "" {
+				target += "?" + in.URL.RawQuery
+			}
+			http.Redirect(out, in, target, http.StatusMovedPermanently)
+			return name, nil
+		}
+		if indexInfo == nil {
+			if err := listDirectory(out, name, cookoo.GetBool("hidden", false, params)); err != nil {
+				return nil, &cookoo.FatalError{Message: err.Error()}
+			}
+			return name, nil
+		}
+		name, info = filepath.Join(name, index), indexInfo
+	}
+
+	header := out.Header()
+	if maxAge := cookoo.GetDuration("maxAge", 0, params); maxAge > 0 {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge/time.Second)))
+	}
+	ctype := mime.TypeByExtension(filepath.Ext(name))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	header.Set("Content-Type", ctype)
+	header.Add("Vary", "Accept-Encoding")
+
+	// Prefer a precompressed file.
+	served, sinfo, encoding := name, info, ""
+	if cookoo.GetBool("precompressed", true, params) {
+		for _, pc := range []struct{ enc, ext string }{{"br", ".br"}, {"gzip", ".gz"}} {
+			if !acceptsEncoding(in, pc.enc) {
+				continue
+			}
+			if fi, err := os.Stat(name + pc.ext); err == nil && !fi.IsDir() {
+				served, sinfo, encoding = name+pc.ext, fi, pc.enc
+				break
+			}
+		}
+	}
+
+	f, err := os.Open(served)
+	if err != nil {
+		return nil, cookoo.NewReroute("@404")
+	}
+	defer f.Close()
+
+	etag := fmt.Sprintf(`"%x-%x"`, sinfo.ModTime().UnixNano(), sinfo.Size())
+	switch {
+	case encoding != "":
+		header.Set("Content-Encoding", encoding)
+		header.Set("ETag", etag[:len(etag)-1]+"-"+encoding+`"`)
+	case cookoo.GetBool("compress", true, params) && compressible(ctype) && sinfo.Size() > 1024 &&
+		in.Method != "HEAD" && in.Header.Get("Range") == "" && acceptsEncoding(in, "gzip"):
+		// Ranges refer to the compressed bytes, so they are not combined with
+		// on-the-fly compression. The gzipped body is not byte-identical to
+		// the file, so its ETag is weak.
+		header.Set("Content-Encoding", "gzip")
+		header.Set("ETag", "W/"+etag)
+		gz := &gzipResponseWriter{ResponseWriter: out}
+		defer gz.Close()
+		out = gz
+	default:
+		header.Set("ETag", etag)
+	}
+
+	http.ServeContent(out, in, filepath.Base(name), info.ModTime(), f)
+	return served, nil
+}
+
+// within checks whether name is inside of directory.
+func within(directory, name string) bool {
+	rel, err := filepath.Rel(directory, name)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// hasHiddenSegment returns true if any part of a path starts with a dot.
+func hasHiddenSegment(p string) bool {
+	for _, seg := range strings.Split(p, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEncoding checks the Accept-Encoding header for an encoding that
+// has not been refused with q=0.
+func acceptsEncoding(req *http.Request, enc string) bool {
+	for _, part := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		token, q, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(token), enc) && strings.TrimSpace(token) != "*" {
+			continue
+		}
+		q = strings.TrimPrefix(strings.TrimSpace(q), "q=")
+		if n, err := strconv.ParseFloat(q, 64); err == nil && n == 0 {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// compressible returns true for content types that are worth compressing.
+func compressible(ctype string) bool {
+	mt, _, _ := strings.Cut(ctype, ";")
+	mt = strings.TrimSpace(mt)
+	switch {
+	case strings.HasPrefix(mt, "text/"),
+		strings.HasSuffix(mt, "+json"), strings.HasSuffix(mt, "+xml"):
+		return true
+	}
+	switch mt {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter compresses a successful response body.
+//
+// Other responses, such as 304 Not Modified, are passed through unchanged,
+// because they have no body to compress.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	passThrough bool
+	wroteHeader bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	if code != http.StatusOK {
+		g.passThrough = true
+		g.Header().Del("Content-Encoding")
+	}
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.passThrough {
+		return g.ResponseWriter.Write(b)
+	}
+	if g.gz == nil {
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+	return g.gz.Write(b)
+}
+
+// Close finishes the gzip stream, if one was started.
+func (g *gzipResponseWriter) Close() error {
+	if g.gz == nil {
+		return nil
+	}
+	return g.gz.Close()
+}
+
+// listDirectory writes a simple HTML listing of a directory.
+func listDirectory(out http.ResponseWriter, dir string, hidden bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !hidden && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		n := e.Name()
+		if e.IsDir() {
+			n += "/"
+		}
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	out.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(out, "<!DOCTYPE html>\n<ul>\n")
+	for _, n := range names {
+		link := url.URL{Path: n}
+		fmt.Fprintf(out, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(link.String()), html.EscapeString(n))
+	}
+	fmt.Fprint(out, "</ul>\n")
+	return nil
+}
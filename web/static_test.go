@@ -0,0 +1,127 @@
+package web
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/cookoo"
+)
+
+func TestServeStatic(t *testing.T) {
+	dir := t.TempDir()
+	big := strings.Repeat("body { color: red; }\n", 100)
+	os.WriteFile(filepath.Join(dir, "site.css"), []byte(big), 0644)
+	os.WriteFile(filepath.Join(dir, "app.js"), []byte("plain"), 0644)
+	os.WriteFile(filepath.Join(dir, "app.js.br"), []byte("brotli"), 0644)
+	os.WriteFile(filepath.Join(dir, ".secret"), []byte("hidden"), 0644)
+	os.Mkdir(filepath.Join(dir, "docs"), 0755)
+	os.WriteFile(filepath.Join(dir, "docs", "index.html"), []byte("<h1>Docs</h1>"), 0644)
+	os.Mkdir(filepath.Join(dir, "files"), 0755)
+	os.WriteFile(filepath.Join(dir, "files", "a.txt"), []byte("a"), 0644)
+
+	reg, router, cxt := cookoo.Cookoo()
+	reg.Route("GET /static/**", "Serve files").
+		Does(ServeStatic, "file").
+		Using("directory").WithDefault(dir).
+		Using("removePrefix").WithDefault("/static")
+	reg.Route("GET /list/**", "List files").
+		Does(ServeStatic, "file").
+		Using("directory").WithDefault(dir).
+		Using("removePrefix").WithDefault("/list").
+		Using("listDirectories").WithDefault(true).
+		Using("index").WithDefault("")
+	reg.Route("@404", "Not found").
+		Does(Flush, "out").
+		Using("content").WithDefault("missing").
+		Using("responseCode").WithDefault(http.StatusNotFound)
+	handler := NewCookooHandler(reg, router, cxt)
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	res := get("/static/site.css", nil)
+	etag := res.Header().Get("ETag")
+	if res.Code != http.StatusOK || res.Body.String() != big || etag == "" || res.Header().Get("Last-Modified") == "" {
+		t.Fatalf("! Unexpected response: %d %v", res.Code, res.Header())
+	}
+	if res = get("/static/site.css", map[string]string{"If-None-Match": etag}); res.Code != http.StatusNotModified {
+		t.Errorf("! Expected 304, got %d", res.Code)
+	}
+	if res = get("/static/site.css", map[string]string{"Range": "bytes=0-3"}); res.Code != http.StatusPartialContent || res.Body.String() != "body" {
+		t.Errorf("! Expected a partial response, got %d %q", res.Code, res.Body.String())
+	}
+
+	res = get("/static/site.css", map[string]string{"Accept-Encoding": "gzip"})
+	if res.Header().Get("Content-Encoding") != "gzip" || !strings.HasPrefix(res.Header().Get("ETag"), "W/") {
+		t.Fatalf("! Expected a gzipped response, got %v", res.Header())
+	}
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != big {
+		t.Error("! Expected the gzipped body to match the file.")
+	}
+	if res = get("/static/site.css", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": "W/" + etag}); res.Code != http.StatusNotModified || res.Body.Len() != 0 {
+		t.Errorf("! Expected an empty 304 for a gzipped file, got %d %q", res.Code, res.Body.String())
+	}
+
+	res = get("/static/app.js", map[string]string{"Accept-Encoding": "gzip;q=0, br"})
+	if res.Header().Get("Content-Encoding") != "br" || res.Body.String() != "brotli" {
+		t.Errorf("! Expected the precompressed file, got %v %q", res.Header(), res.Body.String())
+	}
+	if ct := res.Header().Get("Content-Type"); !strings.Contains(ct, "javascript") {
+		t.Errorf("! Expected the original content type, got %s", ct)
+	}
+	if res = get("/static/app.js", nil); res.Body.String() != "plain" {
+		t.Errorf("! Expected the plain file, got %q", res.Body.String())
+	}
+
+	if res = get("/static/docs", nil); res.Code != http.StatusMovedPermanently || res.Header().Get("Location") != "/static/docs/" {
+		t.Errorf("! Expected a redirect, got %d %v", res.Code, res.Header())
+	}
+	if res = get("/static/docs/", nil); res.Body.String() != "<h1>Docs</h1>" {
+		t.Errorf("! Expected the index, got %q", res.Body.String())
+	}
+	if res = get("/static/files/", nil); res.Code != http.StatusNotFound {
+		t.Errorf("! Expected no listing, got %d", res.Code)
+	}
+	if res = get("/list/files/", nil); !strings.Contains(res.Body.String(), `<a href="a.txt">a.txt</a>`) {
+		t.Errorf("! Expected a listing, got %q", res.Body.String())
+	}
+
+	for _, p := range []string{"/static/.secret", "/static/../static_test.go", "/static/%2e%2e/x", "/static/nope"} {
+		if res = get(p, nil); res.Code != http.StatusNotFound {
+			t.Errorf("! Expected %s to be not found, got %d", p, res.Code)
+		}
+	}
+}
+
+func TestWithin(t *testing.T) {
+	dir := filepath.Join("srv", "static")
+	for name, want := range map[string]bool{
+		filepath.Join(dir, "a.txt"):              true,
+		dir:                                      true,
+		filepath.Join(dir, "..", "secret"):       false,
+		filepath.Join(dir, "..", "..", "etc"):    false,
+		filepath.Join(dir, "..", "static2", "x"): false,
+		filepath.Join(dir, "..a"):                true,
+	} {
+		if got := within(dir, name); got != want {
+			t.Errorf("! Expected within(%q, %q) to be %v", dir, name, want)
+		}
+	}
+}
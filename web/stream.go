@@ -0,0 +1,186 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// Event is a Server-Sent Event with optional fields. See ServeSSE.
+type Event struct {
+	// ID sets the client's last event ID, which it sends back when it
+	// reconnects.
+	ID string
+	// Event is the event's name. Clients receive unnamed events as "message".
+	Event string
+	// Data is the event's payload. It is formatted as values are for Flush.
+	Data interface{}
+	// Retry tells the client how long to wait before reconnecting.
+	Retry time.Duration
+}
+
+// newlines removes line breaks from the fields of an event, since a line
+// break would start a new field.
+var newlines = strings.NewReplacer("\r", "", "\n", "")
+
+// writeEvent writes a single Server-Sent Event.
+func writeEvent(out io.Writer, ev interface{}) {
+	if e, ok := ev.(*Event); ok {
+		ev = *e
+	}
+	if e, ok := ev.(Event); ok {
+		if e.ID != "" {
+			fmt.Fprintf(out, "id: %s\n", newlines.Replace(e.ID))
+		}
+		if e.Event != "" {
+			fmt.Fprintf(out, "event: %s\n", newlines.Replace(e.Event))
+		}
+		if e.Retry > 0 {
+			fmt.Fprintf(out, "retry: %d\n", e.Retry/time.Millisecond)
+		}
+		ev = e.Data
+	}
+
+	var data string
+	if b, ok := ev.([]byte); ok {
+		data = string(b)
+	} else {
+		data = fmt.Sprintf("%v", ev)
+	}
+	// Clients also end lines at a bare carriage return.
+	data = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(out, "data: %s\n", line)
+	}
+	io.WriteString(out, "\n")
+}
+
+// Stream streams a response body to the client in chunks.
+//
+// Unlike Flush, the body never has to be held in memory all at once. Each
+// chunk is flushed to the client as soon as it is written, so this works
+// for large files, as well as for output that is produced slowly. When the
+// response has no Content-Length, Go's server sends it with chunked
+// transfer encoding.
+//
+// The body comes from either a reader or a channel:
+//
+// 	reg.Route("GET /export", "Download an export").
+// 		Does(OpenExport, "export").
+// 		Does(web.Stream, "sent").
+// 			Using("reader").From("cxt:export").
+// 			Using("contentType").WithDefault("text/csv")
+//
+// Params:
+// 	- reader (io.Reader): The body. If it is also an io.Closer, it is closed
+// 	  when the stream ends.
+// 	- chunks: A `<-chan interface{}` (or `chan interface{}`) of chunks, used
+// 	  if there is no reader. Chunks are formatted as in Flush. The stream ends
+// 	  when the channel is closed.
+// 	- chunkSize (int): The most bytes to read from the reader for each chunk.
+// 	  Default: 32768
+// 	- contentType: The content type header. Default is
+// 	  "application/octet-stream".
+// 	- responseCode (int): The HTTP response code. Default is `http.StatusOK`.
+// 	- writer: A ResponseWriter. This will use the HTTP response if no writer
+// 	  is specified.
+// 	- request: A request. This will use the HTTP request if no request
+// 	  is specified, to stop streaming when the client goes away.
+//
+// Returns:
+// 	- The number of bytes written, as an int64.
+func Stream(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	writer, ok := params.Has("writer")
+	if !ok {
+		writer, ok = cxt.Has("http.ResponseWriter")
+		if !ok {
+			return int64(0), &cookoo.FatalError{Message: "No http.ResponseWriter found."}
+		}
+	}
+	out := writer.(http.ResponseWriter)
+	flusher, _ := out.(http.Flusher)
+
+	var done <-chan struct{}
+	req, ok := params.Has("request")
+	if !ok {
+		req, ok = cxt.Has("http.Request")
+	}
+	if ok {
+		done = req.(*http.Request).Context().Done()
+	}
+
+	var next func() ([]byte, error)
+	if r, ok := params.Get("reader", nil).(io.Reader); ok {
+		if c, ok := r.(io.Closer); ok {
+			defer c.Close()
+		}
+		size := cookoo.GetAs("chunkSize", 32768, params)
+		if size <= 0 {
+			return int64(0), &cookoo.FatalError{Message: fmt.Sprintf("Expected a positive chunkSize, got %d", size)}
+		}
+		buf := make([]byte, size)
+		next = func() ([]byte, error) {
+			n, err := r.Read(buf)
+			return buf[:n], err
+		}
+	} else {
+		var chunks <-chan interface{}
+		switch ch := params.Get("chunks", nil).(type) {
+		case <-chan interface{}:
+			chunks = ch
+		case chan interface{}:
+			chunks = ch
+		default:
+			return int64(0), &cookoo.FatalError{Message: "Expected a 'reader' or 'chunks'"}
+		}
+		next = func() ([]byte, error) {
+			select {
+			case <-done:
+				return nil, io.EOF
+			case c, ok := <-chunks:
+				if !ok {
+					return nil, io.EOF
+				}
+				if b, ok := c.([]byte); ok {
+					return b, nil
+				}
+				return []byte(fmt.Sprintf("%v", c)), nil
+			}
+		}
+	}
+
+	out.Header().Set("Content-Type", params.Get("contentType", "application/octet-stream").(string))
+	out.WriteHeader(params.Get("responseCode", http.StatusOK).(int))
+
+	var written int64
+	for {
+		select {
+		case <-done:
+			cxt.Logf("info", "Client closed the stream after %d bytes.", written)
+			return written, nil
+		default:
+		}
+
+		chunk, err := next()
+		if len(chunk) > 0 {
+			n, werr := out.Write(chunk)
+			written += int64(n)
+			if werr != nil {
+				return written, &cookoo.FatalError{Message: "Stream write failed: " + werr.Error()}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, &cookoo.FatalError{Message: "Stream read failed: " + err.Error()}
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/Masterminds/cookoo"
 	"path"
 	"strings"
+	"sync"
 )
 
 // Resolver for transforming a URI path into a route.
@@ -53,8 +54,31 @@ import (
 // The behavior for rules that contain `/**` anywhere other than the end
 // have undefined behavior.
 //
+// Path Parameters:
+// ================
+//
+// A path segment that starts with `:` is a named parameter. It matches any
+// non-empty segment, and the value is put into the context as
+// `path.NAME`. A parameter may be followed by a regular expression in
+// parentheses, which the whole segment must match.
+//
+// Examples:
+// - URI path "GET /users/123" matches "GET /users/:id", and sets `path.id`
+//   to "123". Commands can use it with `From("cxt:path.id")`.
+// - URI path "GET /users/123/posts/hello" matches
+//   "GET /users/:id([0-9]+)/posts/:slug", but "GET /users/matt/posts/hello"
+//   does not.
+// - URI path "GET /files/7/a/b.txt" matches "GET /files/:id/**".
+//
+// Parameters can be mixed with wildcards in other segments. Since the
+// pattern is split on slashes, a constraint cannot match a slash. Values are
+// not URL-decoded beyond what net/url already does for the path.
 type URIPathResolver struct {
 	registry *cookoo.Registry
+
+	mu       sync.Mutex
+	patterns map[string]*routePattern
+	errs     map[string]error
 }
 
 // Creates a new URIPathResolver.
@@ -64,8 +88,33 @@ func NewURIPathResolver(reg *cookoo.Registry) *URIPathResolver {
 	return res
 }
 
+// Init initializes the resolver, compiling the patterns of the routes that
+// are already in the registry. See Compile.
 func (r *URIPathResolver) Init(registry *cookoo.Registry) {
 	r.registry = registry
+	r.patterns = map[string]*routePattern{}
+	r.errs = map[string]error{}
+	r.Compile()
+}
+
+// Compile compiles the pattern of every route with path parameters, and
+// returns the first error.
+//
+// Patterns are compiled only once. A pattern that does not compile keeps
+// failing with the same error, so call this once the routes have been
+// added to find bad patterns at startup, rather than when they are
+// requested. Routes added later are compiled when they are first used.
+func (r *URIPathResolver) Compile() error {
+	var first error
+	for _, pattern := range r.registry.RouteNames() {
+		if !hasPathParams(pattern) {
+			continue
+		}
+		if _, err := r.compiled(pattern); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
 }
 
 // Resolve a path name based using path patterns.
@@ -77,6 +126,20 @@ func (r *URIPathResolver) Resolve(pathName string, cxt cookoo.Context) (string,
 	// illegal in URI paths. So presently we do no special handling for verbs. Yay for simplicity.
 	for _, pattern := range r.registry.RouteNames() {
 
+		if hasPathParams(pattern) {
+			rp, err := r.compiled(pattern)
+			if err != nil {
+				return pathName, err
+			}
+			if vals, ok := rp.match(pathName); ok {
+				for name, val := range vals {
+					cxt.Put("path."+name, val)
+				}
+				return pattern, nil
+			}
+			continue
+		}
+
 		if strings.HasSuffix(pattern, "**") {
 			ok := r.subtreeMatch(cxt, pathName, pattern)
 			if ok {
@@ -94,6 +157,29 @@ func (r *URIPathResolver) Resolve(pathName string, cxt cookoo.Context) (string,
 	return pathName, &cookoo.RouteError{"Could not resolve route " + pathName}
 }
 
+// compiled gets the compiled form of a pattern with path parameters.
+func (r *URIPathResolver) compiled(pattern string) (*routePattern, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rp, ok := r.patterns[pattern]; ok {
+		return rp, nil
+	}
+	if err, ok := r.errs[pattern]; ok {
+		return nil, err
+	}
+	if r.patterns == nil {
+		r.patterns = map[string]*routePattern{}
+		r.errs = map[string]error{}
+	}
+	rp, err := compilePattern(pattern)
+	if err != nil {
+		r.errs[pattern] = err
+		return nil, err
+	}
+	r.patterns[pattern] = rp
+	return rp, nil
+}
+
 func (r *URIPathResolver) subtreeMatch(c cookoo.Context, pathName, pattern string) bool {
 
 	if pattern == "**" {
@@ -3,6 +3,7 @@ package web
 import (
 	"fmt"
 	"github.com/Masterminds/cookoo"
+	"strings"
 	"testing"
 )
 
@@ -69,3 +70,64 @@ func TestUriPathResolver(t *testing.T) {
 		}
 	}
 }
+
+func TestUriPathResolverParams(t *testing.T) {
+	reg, router, _ := cookoo.Cookoo()
+	router.SetRequestResolver(NewURIPathResolver(reg))
+
+	reg.Route("GET /users/:id([0-9]+)", "A user by ID")
+	reg.Route("GET /users/:name", "A user by name")
+	reg.Route("GET /users/:id/posts/:slug", "A post")
+	reg.Route("* /files/:id/**", "A file")
+	reg.Route("GET /[a-z]*/:x", "Mixed")
+
+	tests := []struct {
+		path, route string
+		params     map[string]string
+	}{
+		{"GET /users/123", "GET /users/:id([0-9]+)", map[string]string{"id": "123"}},
+		{"GET /users/matt", "GET /users/:name", map[string]string{"name": "matt"}},
+		{"GET /users/7/posts/hello", "GET /users/:id/posts/:slug", map[string]string{"id": "7", "slug": "hello"}},
+		{"PUT /files/9/a/b.txt", "* /files/:id/**", map[string]string{"id": "9"}},
+		{"GET /things/1", "GET /[a-z]*/:x", map[string]string{"x": "1"}},
+	}
+	for _, tt := range tests {
+		cxt := cookoo.NewContext()
+		resolved, err := router.ResolveRequest(tt.path, cxt)
+		if err != nil || resolved != tt.route {
+			t.Errorf("! Expected `%s` to match `%s`; got `%s` (%v)", tt.path, tt.route, resolved, err)
+			continue
+		}
+		for name, val := range tt.params {
+			if v := cxt.Get("path."+name, nil); v != val {
+				t.Errorf("! Expected path.%s to be %s for %s, got %v", name, val, tt.path, v)
+			}
+		}
+	}
+
+	for _, p := range []string{"GET /users/", "POST /users/1", "GET /users/1/posts", "GET /Things/1"} {
+		if _, err := router.ResolveRequest(p, cookoo.NewContext()); err == nil {
+			t.Errorf("! Expected %s not to match.", p)
+		}
+	}
+
+	reg.Route("GET /bad/x/:id([0-9]", "Bad constraint")
+	if _, err := router.ResolveRequest("GET /bad/x/1", cookoo.NewContext()); err == nil {
+		t.Error("! Expected a bad constraint to fail.")
+	}
+
+	// Bad patterns are found once, up front.
+	resolver := NewURIPathResolver(reg)
+	if _, ok := resolver.errs["GET /bad/x/:id([0-9]"]; !ok {
+		t.Error("! Expected Init to record the bad pattern.")
+	}
+	if err := resolver.Compile(); err == nil || !strings.Contains(err.Error(), "GET /bad/x/:id([0-9]") {
+		t.Errorf("! Expected Compile to report the bad pattern, got %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("! Expected NewCookooHandler to panic on a bad pattern.")
+		}
+	}()
+	NewCookooHandler(reg, router, cookoo.NewContext())
+}
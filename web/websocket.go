@@ -0,0 +1,450 @@
+package web
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// WebSocket message types, as defined by RFC 6455.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	closeMessage  = 8
+	pingMessage   = 9
+	pongMessage   = 10
+)
+
+// DefaultReadLimit is the largest message a WebSocket accepts by default.
+const DefaultReadLimit = 1 << 20
+
+// websocketGUID is used to compute the Sec-WebSocket-Accept header.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrMessageTooLarge is returned when a client sends a message larger than
+// the WebSocket's ReadLimit.
+var ErrMessageTooLarge = errors.New("websocket: message too large")
+
+// WebSocket is the server side of a WebSocket connection.
+//
+// It is created by UpgradeWebSocket. Reads must be done from one goroutine
+// at a time. Writes may be done from several.
+type WebSocket struct {
+	// ReadLimit is the largest message that will be read, in bytes.
+	ReadLimit int64
+
+	conn net.Conn
+	r    *bufio.Reader
+	wmu  sync.Mutex
+}
+
+// ReadMessage reads the next text or binary message.
+//
+// Pings are answered, and fragmented messages are put back together. When
+// the client closes the connection, the close is acknowledged and io.EOF is
+// returned.
+func (ws *WebSocket) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		fin, op, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case pingMessage:
+			if err := ws.writeFrame(pongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case pongMessage:
+			continue
+		case closeMessage:
+			// Echo the status code back, as the protocol asks.
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			ws.writeFrame(closeMessage, payload)
+			ws.conn.Close()
+			return 0, nil, io.EOF
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, errors.New("websocket: expected a continuation frame")
+			}
+			messageType = op
+		case 0:
+			if messageType == 0 {
+				return 0, nil, errors.New("websocket: unexpected continuation frame")
+			}
+		default:
+			return 0, nil, fmt.Errorf("websocket: unknown opcode %d", op)
+		}
+
+		if int64(len(data)+len(payload)) > ws.ReadLimit {
+			ws.CloseWithStatus(1009, "message too large")
+			return 0, nil, ErrMessageTooLarge
+		}
+		data = append(data, payload...)
+		if fin {
+			return messageType, data, nil
+		}
+	}
+}
+
+// WriteMessage sends a text or binary message.
+func (ws *WebSocket) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: cannot write message type %d", messageType)
+	}
+	return ws.writeFrame(messageType, data)
+}
+
+// Close sends a normal close message and closes the connection.
+func (ws *WebSocket) Close() error {
+	return ws.CloseWithStatus(1000, "")
+}
+
+// CloseWithStatus sends a close message with a status code and reason, and
+// closes the connection.
+func (ws *WebSocket) CloseWithStatus(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	ws.writeFrame(closeMessage, payload)
+	return ws.conn.Close()
+}
+
+// readFrame reads a single frame from the client.
+func (ws *WebSocket) readFrame() (fin bool, op int, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(ws.r, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	op = int(head[0] & 0x0f)
+	if head[1]&0x80 == 0 {
+		err = errors.New("websocket: client frames must be masked")
+		return
+	}
+
+	size := int64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(ws.r, ext[:]); err != nil {
+			return
+		}
+		size = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(ws.r, ext[:]); err != nil {
+			return
+		}
+		size = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if size < 0 || size > ws.ReadLimit {
+		ws.CloseWithStatus(1009, "message too large")
+		err = ErrMessageTooLarge
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.r, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, size)
+	if _, err = io.ReadFull(ws.r, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// writeFrame writes a single, unfragmented frame to the client.
+func (ws *WebSocket) writeFrame(op int, payload []byte) error {
+	buf := make([]byte, 0, len(payload)+10)
+	buf = append(buf, 0x80|byte(op))
+	switch n := len(payload); {
+	case n < 126:
+		buf = append(buf, byte(n))
+	case n <= 0xffff:
+		buf = append(buf, 126, byte(n>>8), byte(n))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		buf = append(append(buf, 127), ext[:]...)
+	}
+	buf = append(buf, payload...)
+
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	_, err := ws.conn.Write(buf)
+	return err
+}
+
+// UpgradeWebSocket upgrades the HTTP request to a WebSocket connection.
+//
+// The connection is put into the context as `websocket.Conn`, and is also
+// returned. After the upgrade, the HTTP response can no longer be written
+// to. Use WebSocketSend to write to the client, and WebSocketMessages to
+// read from it.
+//
+// Routes that use a WebSocket run once per connection, and should close it
+// when they are done. To run a route for every message, use
+// WebSocketMessages, which closes the connection when it returns:
+//
+// 	reg.Route("GET /chat", "Chat with the server").
+// 		Does(web.UpgradeWebSocket, "ws").
+// 		Does(web.WebSocketMessages, "messages").
+// 			Using("router").WithDefault(router).
+// 			Using("route").WithDefault("@chat.message")
+//
+// 	reg.Route("@chat.message", "Echo a message").
+// 		Does(web.WebSocketSend, "echo").
+// 			Using("content").From("cxt:websocket.Message")
+//
+// By default, browsers may only connect from pages with the same host. The
+// `origins` param allows other origins.
+//
+// Params:
+// 	- origins ([]string): Hosts (such as "example.com:8080") that may connect,
+// 	  in addition to the request's own host. "*" allows any origin.
+// 	- readLimit (int64): The largest message to accept. Default: DefaultReadLimit
+// 	- writer: A ResponseWriter. This will use the HTTP response if no writer
+// 	  is specified. It must implement http.Hijacker.
+// 	- request: A request. This will use the HTTP request if no request
+// 	  is specified.
+//
+// Returns:
+// 	- A *WebSocket.
+//
+// If the request is not a valid WebSocket handshake, a 400 Bad Request (or
+// 403 Forbidden, for a bad origin) is sent, and a FatalError is returned.
+func UpgradeWebSocket(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	writer, ok := params.Has("writer")
+	if !ok {
+		writer, ok = cxt.Has("http.ResponseWriter")
+		if !ok {
+			return nil, &cookoo.FatalError{Message: "No http.ResponseWriter found."}
+		}
+	}
+	req, ok := params.Has("request")
+	if !ok {
+		req, ok = cxt.Has("http.Request")
+		if !ok {
+			return nil, &cookoo.FatalError{Message: "No http.Request found."}
+		}
+	}
+	out := writer.(http.ResponseWriter)
+	in := req.(*http.Request)
+
+	fail := func(code int, msg string) (interface{}, cookoo.Interrupt) {
+		http.Error(out, http.StatusText(code), code)
+		return nil, &cookoo.FatalError{Message: "WebSocket upgrade failed: " + msg}
+	}
+	if !headerContains(in.Header, "Connection", "upgrade") || !headerContains(in.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "not an upgrade request")
+	}
+	if in.Header.Get("Sec-WebSocket-Version") != "13" {
+		return fail(http.StatusBadRequest, "unsupported version")
+	}
+	key := in.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return fail(http.StatusBadRequest, "missing Sec-WebSocket-Key")
+	}
+	origins, _ := params.Get("origins", []string{}).([]string)
+	if !originAllowed(in, origins) {
+		return fail(http.StatusForbidden, "origin not allowed")
+	}
+
+	hijacker, ok := out.(http.Hijacker)
+	if !ok {
+		return fail(http.StatusInternalServerError, "the ResponseWriter cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, err.Error())
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	accept := base64.StdEncoding.EncodeToString(sum[:])
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", accept)
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, &cookoo.FatalError{Message: "WebSocket upgrade failed: " + err.Error()}
+	}
+
+	limit := cookoo.GetAs[int64]("readLimit", DefaultReadLimit, params)
+	ws := &WebSocket{ReadLimit: limit, conn: conn, r: rw.Reader}
+	cxt.Put("websocket.Conn", ws)
+	return ws, nil
+}
+
+// WebSocketMessages runs a route for every message from a WebSocket.
+//
+// Each route runs in a child context (see cookoo.Context.NewChild) with
+// these values:
+// 	- websocket.Message: The message. Text messages are strings, and binary
+// 	  messages are []byte.
+// 	- websocket.MessageType: TextMessage or BinaryMessage.
+//
+// If the route fails, the error is logged and the next message is read.
+// This command blocks until the client closes the connection, or the
+// request's Go context is cancelled.
+//
+// Params:
+// 	- router (*cookoo.Router): The router to run the route with. This is required.
+// 	- route (string): The route to run for each message. This is required.
+// 	- conn (*WebSocket): The connection. Default: the `websocket.Conn` in the context.
+//
+// Returns:
+// 	- The number of messages handled, as an int.
+func WebSocketMessages(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	router, ok := params.Get("router", nil).(*cookoo.Router)
+	if !ok {
+		return 0, &cookoo.FatalError{Message: "Expected a 'router'"}
+	}
+	route, ok := cookoo.HasString("route", params)
+	if !ok {
+		return 0, &cookoo.FatalError{Message: "Expected a 'route'"}
+	}
+	ws, irq := websocketParam(cxt, params)
+	if irq != nil {
+		return 0, irq
+	}
+
+	// Closing the connection unblocks ReadMessage when the request ends.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-cxt.GoContext().Done():
+			ws.conn.Close()
+		case <-stop:
+		}
+	}()
+
+	handled := 0
+	for {
+		mt, data, err := ws.ReadMessage()
+		if err != nil {
+			if err != io.EOF && cxt.GoContext().Err() == nil {
+				cxt.Logf("warn", "WebSocket read failed: %s", err)
+			}
+			ws.conn.Close()
+			return handled, nil
+		}
+
+		msg := cxt.NewChild()
+		if mt == TextMessage {
+			msg.Put("websocket.Message", string(data))
+		} else {
+			msg.Put("websocket.Message", data)
+		}
+		msg.Put("websocket.MessageType", mt)
+		if err := router.HandleRequest(route, msg, false); err != nil {
+			cxt.Logf("warn", "WebSocket route %s failed: %s", route, err)
+		}
+		handled++
+	}
+}
+
+// WebSocketSend sends a message to a WebSocket.
+//
+// Params:
+// 	- content: The message. A []byte is sent as a binary message unless
+// 	  `type` says otherwise. Anything else is formatted with fmt's `%v` and
+// 	  sent as text.
+// 	- type (int): TextMessage or BinaryMessage. The default depends on content.
+// 	- conn (*WebSocket): The connection. Default: the `websocket.Conn` in the context.
+//
+// Returns:
+// 	- boolean true
+func WebSocketSend(cxt cookoo.Context, params *cookoo.Params) (interface{}, cookoo.Interrupt) {
+	ws, irq := websocketParam(cxt, params)
+	if irq != nil {
+		return nil, irq
+	}
+
+	var data []byte
+	mt := TextMessage
+	switch c := params.Get("content", "").(type) {
+	case []byte:
+		data, mt = c, BinaryMessage
+	case string:
+		data = []byte(c)
+	default:
+		data = []byte(fmt.Sprintf("%v", c))
+	}
+	if t, ok := params.Has("type"); ok && t != nil {
+		if mt, ok = cookoo.HasAs[int]("type", params); !ok {
+			return nil, &cookoo.FatalError{Message: fmt.Sprintf("Expected an int message type, got %T", t)}
+		}
+	}
+
+	if err := ws.WriteMessage(mt, data); err != nil {
+		return nil, &cookoo.FatalError{Message: "WebSocket write failed: " + err.Error()}
+	}
+	return true, nil
+}
+
+// websocketParam gets the connection from the `conn` param or the context.
+func websocketParam(cxt cookoo.Context, params *cookoo.Params) (*WebSocket, cookoo.Interrupt) {
+	c, ok := params.Has("conn")
+	if !ok {
+		c, ok = cxt.Has("websocket.Conn")
+	}
+	ws, isWS := c.(*WebSocket)
+	if !ok || !isWS {
+		return nil, &cookoo.FatalError{Message: "No WebSocket found."}
+	}
+	return ws, nil
+}
+
+// headerContains checks for a comma-separated token in a header,
+// ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// originAllowed checks the Origin header against the request's host and the
+// allowed origins. Requests without an Origin, which do not come from
+// browsers, are allowed.
+func originAllowed(req *http.Request, origins []string) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, req.Host) {
+		return true
+	}
+	for _, o := range origins {
+		if o == "*" || strings.EqualFold(o, u.Host) {
+			return true
+		}
+	}
+	return false
+}
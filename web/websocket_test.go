@@ -0,0 +1,121 @@
+package web
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/cookoo"
+)
+
+// writeClientFrame writes a masked frame, as a browser would.
+func writeClientFrame(w io.Writer, op byte, payload string) {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | op, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i := 0; i < len(payload); i++ {
+		frame = append(frame, payload[i]^mask[i%4])
+	}
+	w.Write(frame)
+}
+
+// readServerFrame reads an unmasked frame with a short payload.
+func readServerFrame(r *bufio.Reader) (byte, string, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, "", err
+	}
+	payload := make([]byte, head[1]&0x7f)
+	_, err := io.ReadFull(r, payload)
+	return head[0] & 0x0f, string(payload), err
+}
+
+func TestWebSocket(t *testing.T) {
+	reg, router, cxt := cookoo.Cookoo()
+	reg.Route("GET /ws", "Echo messages").
+		Does(UpgradeWebSocket, "ws").
+		Does(WebSocketSend, "hello").
+		Using("content").WithDefault("welcome").
+		Does(WebSocketMessages, "messages").
+		Using("router").WithDefault(router).
+		Using("route").WithDefault("@message")
+	reg.Route("@message", "Echo one message").
+		Does(WebSocketSend, "echo").
+		Using("content").From("cxt:websocket.Message")
+
+	srv := httptest.NewServer(NewCookooHandler(reg, router, cxt))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("! Expected a plain GET to be rejected, got %d", res.StatusCode)
+	}
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+
+	r := bufio.NewReader(conn)
+	hres, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hres.StatusCode != http.StatusSwitchingProtocols || hres.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("! Unexpected handshake: %d %v", hres.StatusCode, hres.Header)
+	}
+
+	expect := func(op byte, payload string) {
+		gotOp, got, err := readServerFrame(r)
+		if err != nil || gotOp != op || got != payload {
+			t.Errorf("! Expected frame %d %q, got %d %q (%v)", op, payload, gotOp, got, err)
+		}
+	}
+	expect(TextMessage, "welcome")
+
+	writeClientFrame(conn, TextMessage, "hello")
+	expect(TextMessage, "hello")
+	writeClientFrame(conn, pingMessage, "ping")
+	expect(pongMessage, "ping")
+
+	// A fragmented message.
+	conn.Write([]byte{TextMessage, 0x80 | 2, 0, 0, 0, 0, 'a', 'b'})
+	writeClientFrame(conn, 0, "cd")
+	expect(TextMessage, "abcd")
+
+	writeClientFrame(conn, closeMessage, "\x03\xe8")
+	expect(closeMessage, "\x03\xe8")
+}
+
+func TestWebSocketSendType(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	ws := &WebSocket{conn: server, r: bufio.NewReader(server)}
+
+	cxt := cookoo.NewContext()
+	params := cookoo.NewParamsWithValues(map[string]interface{}{"conn": ws, "content": "hi", "type": "text"})
+	if _, irq := WebSocketSend(cxt, params); irq == nil {
+		t.Error("! Expected a string type to fail.")
+	}
+
+	go func() {
+		params := cookoo.NewParamsWithValues(map[string]interface{}{"conn": ws, "content": "hi", "type": int64(BinaryMessage)})
+		WebSocketSend(cxt, params)
+	}()
+	op, payload, err := readServerFrame(bufio.NewReader(client))
+	if err != nil || op != BinaryMessage || payload != "hi" {
+		t.Errorf("! Expected an int64 type to be used, got %d %q (%v)", op, payload, err)
+	}
+}